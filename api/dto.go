@@ -0,0 +1,461 @@
+// Package api exposes the App operations used by the frontend as a
+// service that can also be served over HTTP.
+package api
+
+import (
+	"fmt"
+	"glog/db"
+	"glog/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BlockDto struct {
+	Id      string `json:"id"`
+	Content string `json:"content"`
+	Indent  int    `json:"indent"`
+}
+
+type DocumentDto struct {
+	Id        string     `json:"id"`
+	Title     string     `json:"title"`
+	Blocks    []BlockDto `json:"blocks"`
+	Date      string     `json:"date"`       // RFC 3339 format
+	IsJournal bool       `json:"is_journal"` // Indicates if this document is a journal entry
+	Revision  uint64     `json:"revision"`   // Base revision for ApplyOps
+}
+
+func ToDocumentDto(doc *domain.Document) DocumentDto {
+	blocks := make([]BlockDto, len(doc.Blocks))
+	for i, b := range doc.Blocks {
+		blocks[i] = BlockDto{
+			Id:      b.ID.String(),
+			Content: b.Content,
+			Indent:  b.Indent,
+		}
+	}
+
+	return DocumentDto{
+		Id:        doc.ID.String(),
+		Title:     doc.Title,
+		Date:      doc.Date.Format(time.RFC3339),
+		IsJournal: doc.IsJournal,
+		Blocks:    blocks,
+		Revision:  doc.Revision,
+	}
+}
+
+func (d DocumentDto) ToDomain() (*domain.Document, error) {
+	docID, err := uuid.Parse(d.Id)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing document id: %s", err)
+	}
+
+	t, err := time.Parse(time.RFC3339, d.Date)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing date: %s", err)
+	}
+
+	doc := &domain.Document{
+		ID:        domain.DocumentID(docID),
+		Title:     d.Title,
+		Date:      t,
+		IsJournal: d.IsJournal,
+		Blocks:    make([]*domain.Block, len(d.Blocks)),
+	}
+
+	for i, b := range d.Blocks {
+		blockId, err := uuid.Parse(d.Blocks[i].Id)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing block id: %s", err)
+		}
+
+		doc.Blocks[i] = &domain.Block{
+			ID:      domain.BlockID(blockId),
+			Content: b.Content,
+			Indent:  b.Indent,
+		}
+	}
+
+	return doc, nil
+}
+
+// BlockOpDto is a db.BlockOp; block ids are empty for none
+type BlockOpDto struct {
+	Type    string `json:"type"` // insert, update, delete, move or indent
+	BlockId string `json:"block_id"`
+	After   string `json:"after,omitempty"`
+	Content string `json:"content,omitempty"`
+	Indent  int    `json:"indent,omitempty"`
+}
+
+func (o BlockOpDto) ToDomain() (db.BlockOp, error) {
+	op := db.BlockOp{
+		Type:    db.OpType(o.Type),
+		Content: o.Content,
+		Indent:  o.Indent,
+	}
+
+	if o.BlockId != "" {
+		id, err := uuid.Parse(o.BlockId)
+		if err != nil {
+			return db.BlockOp{}, fmt.Errorf("error parsing block id: %s", err)
+		}
+		op.BlockID = domain.BlockID(id)
+	}
+
+	if o.After != "" {
+		id, err := uuid.Parse(o.After)
+		if err != nil {
+			return db.BlockOp{}, fmt.Errorf("error parsing after block id: %s", err)
+		}
+		op.After = domain.BlockID(id)
+	}
+
+	return op, nil
+}
+
+// MoveBlocksDto is the result of moving or copying blocks between documents
+type MoveBlocksDto struct {
+	Source      DocumentDto `json:"source"`
+	Destination DocumentDto `json:"destination"`
+}
+
+// MergePlanDto is a db.MergePlan: what merging Merge into Keep changes
+type MergePlanDto struct {
+	Keep      DocumentSummaryDto   `json:"keep"`
+	Merge     DocumentSummaryDto   `json:"merge"`
+	Blocks    int                  `json:"blocks"`
+	Relinked  []DocumentSummaryDto `json:"relinked"`
+	Documents []DocumentSummaryDto `json:"documents"` // every touched document, Keep first
+	DryRun    bool                 `json:"dry_run"`
+}
+
+func ToMergePlanDto(plan *db.MergePlan, dryRun bool) MergePlanDto {
+	dto := MergePlanDto{
+		Keep:      ToDocumentSummaryDto(plan.Keep),
+		Merge:     ToDocumentSummaryDto(plan.Merge),
+		Blocks:    plan.Blocks,
+		Relinked:  make([]DocumentSummaryDto, len(plan.Relinked)),
+		Documents: make([]DocumentSummaryDto, 0, len(plan.Relinked)+2),
+		DryRun:    dryRun,
+	}
+	for i, doc := range plan.Relinked {
+		dto.Relinked[i] = ToDocumentSummaryDto(doc)
+	}
+	for _, doc := range plan.Documents() {
+		dto.Documents = append(dto.Documents, ToDocumentSummaryDto(doc))
+	}
+	return dto
+}
+
+type DocumentSummaryDto struct {
+	Id    string `json:"id"`
+	Title string `json:"title"`
+	Date  string `json:"date"` // RFC 3339 format
+}
+
+func ToDocumentSummaryDto(summary db.DocumentSummary) DocumentSummaryDto {
+	return DocumentSummaryDto{
+		Id:    summary.ID.String(),
+		Title: summary.Title,
+		Date:  summary.Date.Format(time.RFC3339),
+	}
+}
+
+// SearchResultsDto is a page of documents matching a search
+type SearchResultsDto struct {
+	Total  int                  `json:"total"` // matches in the search index
+	Offset int                  `json:"offset"`
+	Hits   []DocumentSummaryDto `json:"hits"`
+}
+
+func ToSearchResultsDto(results *db.SearchResults, offset int) SearchResultsDto {
+	dto := SearchResultsDto{
+		Total:  results.Total,
+		Offset: offset,
+		Hits:   make([]DocumentSummaryDto, len(results.Hits)),
+	}
+	for i, hit := range results.Hits {
+		dto.Hits[i] = ToDocumentSummaryDto(hit)
+	}
+	return dto
+}
+
+// TitleSuggestionDto is a page title suggested for a [[link]]
+type TitleSuggestionDto struct {
+	Id        string `json:"id"`
+	Title     string `json:"title"`
+	Backlinks int    `json:"backlinks"`
+	Fuzzy     bool   `json:"fuzzy"` // not a prefix match
+}
+
+func ToTitleSuggestionDto(suggestion db.TitleSuggestion) TitleSuggestionDto {
+	return TitleSuggestionDto{
+		Id:        suggestion.ID.String(),
+		Title:     suggestion.Title,
+		Backlinks: suggestion.Backlinks,
+		Fuzzy:     suggestion.Fuzzy,
+	}
+}
+
+// BlockSearchHitDto is a block matching a search, with the ancestors of
+// the block and the matching fragments of its content
+type BlockSearchHitDto struct {
+	DocId      string   `json:"doc_id"`
+	Title      string   `json:"title"`
+	BlockId    string   `json:"block_id"`
+	Content    string   `json:"content"`
+	Breadcrumb []string `json:"breadcrumb"` // first lines of the ancestors, outermost first
+	Fragments  []string `json:"fragments"`  // HTML-escaped, with matches in <mark> tags
+	Score      float64  `json:"score"`
+}
+
+// UnlinkedReferenceDto is a block mentioning a page in plain text, in a
+// document that does not link the page
+type UnlinkedReferenceDto struct {
+	DocId      string   `json:"doc_id"`
+	Title      string   `json:"title"`
+	BlockId    string   `json:"block_id"`
+	Content    string   `json:"content"`
+	Breadcrumb []string `json:"breadcrumb"` // first lines of the ancestors, outermost first
+	Mention    string   `json:"mention"`    // the title or alias as written in the block
+	Fragments  []string `json:"fragments"`  // HTML-escaped, with matches in <mark> tags
+}
+
+func ToUnlinkedReferenceDto(ref db.UnlinkedReference) UnlinkedReferenceDto {
+	dto := UnlinkedReferenceDto{
+		DocId:      ref.DocID.String(),
+		Title:      ref.Title,
+		BlockId:    ref.BlockID.String(),
+		Content:    ref.Content,
+		Breadcrumb: ref.Breadcrumb,
+		Mention:    ref.Mention,
+		Fragments:  ref.Fragments,
+	}
+	if dto.Breadcrumb == nil {
+		dto.Breadcrumb = []string{}
+	}
+	if dto.Fragments == nil {
+		dto.Fragments = []string{}
+	}
+	return dto
+}
+
+// RelatedDocumentDto is a document suggested as related, with the signals
+// of its score
+type RelatedDocumentDto struct {
+	Id          string   `json:"id"`
+	Title       string   `json:"title"`
+	Date        string   `json:"date"` // RFC 3339 format
+	Score       float64  `json:"score"`
+	Reason      string   `json:"reason"` // e.g. "3 shared links, similar text"
+	SharedLinks []string `json:"shared_links"`
+	SharedTags  []string `json:"shared_tags"`
+	Similarity  float64  `json:"similarity"` // text similarity from 0 to 1
+}
+
+func ToRelatedDocumentDto(related db.RelatedDocument) RelatedDocumentDto {
+	dto := RelatedDocumentDto{
+		Id:          related.ID.String(),
+		Title:       related.Title,
+		Date:        related.Date.Format(time.RFC3339),
+		Score:       related.Score,
+		Reason:      related.Reason(),
+		SharedLinks: related.SharedLinks,
+		SharedTags:  related.SharedTags,
+		Similarity:  related.Similarity,
+	}
+	if dto.SharedLinks == nil {
+		dto.SharedLinks = []string{}
+	}
+	if dto.SharedTags == nil {
+		dto.SharedTags = []string{}
+	}
+	return dto
+}
+
+// SavedSearchDto is a named search query, opened like a page
+type SavedSearchDto struct {
+	Id      string `json:"id"` // empty to create a saved search
+	Name    string `json:"name"`
+	Query   string `json:"query"`
+	Notify  bool   `json:"notify"`            // record SearchMatched events for new matches
+	Created string `json:"created,omitempty"` // RFC 3339 format
+}
+
+func ToSavedSearchDto(search db.SavedSearch) SavedSearchDto {
+	return SavedSearchDto{
+		Id:      search.ID.String(),
+		Name:    search.Name,
+		Query:   search.Query,
+		Notify:  search.Notify,
+		Created: search.Created.Format(time.RFC3339),
+	}
+}
+
+func (dto SavedSearchDto) ToDomain() (db.SavedSearch, error) {
+	search := db.SavedSearch{
+		Name:   dto.Name,
+		Query:  dto.Query,
+		Notify: dto.Notify,
+	}
+	if dto.Id != "" {
+		id, err := uuid.Parse(dto.Id)
+		if err != nil {
+			return db.SavedSearch{}, fmt.Errorf("error parsing saved search id: %s", err)
+		}
+		search.ID = id
+	}
+	return search, nil
+}
+
+func ToBlockSearchHitDto(hit db.BlockSearchHit) BlockSearchHitDto {
+	dto := BlockSearchHitDto{
+		DocId:      hit.DocID.String(),
+		Title:      hit.Title,
+		BlockId:    hit.BlockID.String(),
+		Content:    hit.Content,
+		Breadcrumb: hit.Breadcrumb,
+		Fragments:  hit.Fragments,
+		Score:      hit.Score,
+	}
+	if dto.Breadcrumb == nil {
+		dto.Breadcrumb = []string{}
+	}
+	if dto.Fragments == nil {
+		dto.Fragments = []string{}
+	}
+	return dto
+}
+
+type ScheduledTaskDto struct {
+	Id          string `json:"id"`
+	Description string `json:"description"`
+	DueDate     string `json:"due_date"` // RFC 3339 format
+	Title       string `json:"title"`
+	BlockId     string `json:"block_id"`
+	DocId       string `json:"doc_id"`
+}
+
+type DocumentReferenceDto struct {
+	Id     string
+	Title  string
+	Blocks []BlockReferenceDto
+}
+
+type BlockReferenceDto struct {
+	Id      string
+	Content string
+	Indent  int
+}
+
+// IndexHealthDto represents the health status of the search index for the UI
+type IndexHealthDto struct {
+	IsHealthy          bool   `json:"isHealthy"`
+	FailedDocuments    int    `json:"failedDocuments"`
+	LastHealthCheck    string `json:"lastHealthCheck"`
+	RequiresReindex    bool   `json:"requiresReindex"`
+	HealthCheckMessage string `json:"healthCheckMessage"`
+	QueueDepth         int    `json:"queueDepth"` // documents waiting to be indexed
+	QueueLagMs         int64  `json:"queueLagMs"` // how long the oldest of them has waited
+}
+
+// IndexVerificationDto is a db.IndexVerification: the documents whose
+// search entries were missing, stale or orphaned, by ID
+type IndexVerificationDto struct {
+	Documents int      `json:"documents"`
+	Entries   int      `json:"entries"`
+	Missing   []string `json:"missing"`
+	Stale     []string `json:"stale"`
+	Orphaned  []string `json:"orphaned"`
+	Repaired  int      `json:"repaired"`
+}
+
+func ToIndexVerificationDto(result *db.IndexVerification) IndexVerificationDto {
+	ids := func(ids []domain.DocumentID) []string {
+		result := make([]string, len(ids))
+		for i, id := range ids {
+			result[i] = uuid.UUID(id).String()
+		}
+		return result
+	}
+	return IndexVerificationDto{
+		Documents: result.Documents,
+		Entries:   result.Entries,
+		Missing:   ids(result.Missing),
+		Stale:     ids(result.Stale),
+		Orphaned:  ids(result.Orphaned),
+		Repaired:  result.Repaired,
+	}
+}
+
+// SearchAnalysisDto configures how the search index analyzes text
+type SearchAnalysisDto struct {
+	Languages   []string `json:"languages"` // stemming languages, e.g. "en", "es"
+	FoldAccents bool     `json:"foldAccents"`
+	CJK         bool     `json:"cjk"` // CJK text as bigrams
+}
+
+func ToSearchAnalysisDto(analysis db.SearchAnalysis) SearchAnalysisDto {
+	dto := SearchAnalysisDto{
+		Languages:   analysis.Languages,
+		FoldAccents: analysis.FoldAccents,
+		CJK:         analysis.CJK,
+	}
+	if dto.Languages == nil {
+		dto.Languages = []string{}
+	}
+	return dto
+}
+
+func (dto SearchAnalysisDto) ToDomain() db.SearchAnalysis {
+	return db.SearchAnalysis{
+		Languages:   dto.Languages,
+		FoldAccents: dto.FoldAccents,
+		CJK:         dto.CJK,
+	}
+}
+
+// APIServerDto describes the HTTP API served by the app
+type APIServerDto struct {
+	Url   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// ChangeEventDto is a db.Event as sent to the frontend
+type ChangeEventDto struct {
+	Seq       uint64 `json:"seq"`
+	Type      string `json:"type"`
+	Time      string `json:"time"` // RFC 3339 format
+	DocId     string `json:"doc_id"`
+	Title     string `json:"title"`
+	IsJournal bool   `json:"is_journal"`
+	BlockId   string `json:"block_id,omitempty"`  // TaskScheduled
+	Date      string `json:"date,omitempty"`      // TaskScheduled, RFC 3339 format
+	Error     string `json:"error,omitempty"`     // IndexFailed
+	SearchId  string `json:"search_id,omitempty"` // SearchMatched
+	Search    string `json:"search,omitempty"`    // SearchMatched, the saved search name
+}
+
+func ToChangeEventDto(ev db.Event) ChangeEventDto {
+	dto := ChangeEventDto{
+		Seq:       ev.Seq,
+		Type:      string(ev.Type),
+		Time:      ev.Time.Format(time.RFC3339),
+		DocId:     ev.DocID.String(),
+		Title:     ev.Title,
+		IsJournal: ev.IsJournal,
+		Error:     ev.Error,
+	}
+	if ev.Type == db.EventTaskScheduled {
+		dto.BlockId = ev.BlockID.String()
+		dto.Date = ev.Date.Format(time.RFC3339)
+	}
+	if ev.Type == db.EventSearchMatched {
+		dto.SearchId = ev.SearchID.String()
+		dto.Search = ev.Search
+	}
+	return dto
+}
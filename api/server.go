@@ -0,0 +1,458 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"glog/db"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+// maxRequestBody bounds request bodies; pasted images are the largest.
+const maxRequestBody = 32 << 20
+
+var ErrNotLoopback = errors.New("the API only listens on loopback addresses")
+
+// NewToken returns a random API token.
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NewHandler serves the Service operations as a REST/JSON API described
+// by openapi.json. Every request except GET /api/openapi.json must carry
+// "Authorization: Bearer <token>".
+func NewHandler(s *Service, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openAPISpec)
+	})
+
+	mux.Handle("GET /api/documents", authorized(token, func(r *http.Request) (interface{}, error) {
+		title := r.URL.Query().Get("title")
+		if title == "" {
+			return nil, badRequest("query parameter 'title' is required")
+		}
+		return s.GetDocumentByTitle(title)
+	}))
+	mux.Handle("POST /api/documents", authorized(token, func(r *http.Request) (interface{}, error) {
+		var req openRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, badRequest("invalid request: " + err.Error())
+		}
+		if strings.TrimSpace(req.Title) == "" {
+			return nil, badRequest("'title' is required")
+		}
+		return s.OpenDocumentByTitle(req.Title)
+	}))
+	mux.Handle("GET /api/documents/{id}", authorized(token, func(r *http.Request) (interface{}, error) {
+		if _, err := uuid.Parse(r.PathValue("id")); err != nil {
+			return nil, badRequest("invalid document id")
+		}
+		return s.OpenDocument(r.PathValue("id"))
+	}))
+	mux.Handle("GET /api/blocks/{id}/document", authorized(token, func(r *http.Request) (interface{}, error) {
+		if _, err := uuid.Parse(r.PathValue("id")); err != nil {
+			return nil, badRequest("invalid block id")
+		}
+		return s.OpenBlockDocument(r.PathValue("id"))
+	}))
+	mux.Handle("PUT /api/documents/{id}", authorized(token, func(r *http.Request) (interface{}, error) {
+		var doc DocumentDto
+		if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+			return nil, badRequest("invalid document: " + err.Error())
+		}
+		if doc.Id != r.PathValue("id") {
+			return nil, badRequest("document id does not match the URL")
+		}
+		if _, err := doc.ToDomain(); err != nil {
+			return nil, badRequest(err.Error())
+		}
+		return s.SaveDocument(doc)
+	}))
+	mux.Handle("PATCH /api/documents/{id}", authorized(token, func(r *http.Request) (interface{}, error) {
+		if _, err := uuid.Parse(r.PathValue("id")); err != nil {
+			return nil, badRequest("invalid document id")
+		}
+		var req opsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, badRequest("invalid operations: " + err.Error())
+		}
+		for _, op := range req.Ops {
+			if _, err := op.ToDomain(); err != nil {
+				return nil, badRequest(err.Error())
+			}
+		}
+		return s.ApplyOps(r.PathValue("id"), req.BaseRevision, req.Ops)
+	}))
+	for _, action := range []string{"move", "copy"} {
+		copyBlocks := action == "copy"
+		mux.Handle("POST /api/documents/{id}/blocks/{block}/"+action, authorized(token, func(r *http.Request) (interface{}, error) {
+			var req moveRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				return nil, badRequest("invalid request: " + err.Error())
+			}
+			for _, id := range []string{r.PathValue("id"), r.PathValue("block"), req.To} {
+				if _, err := uuid.Parse(id); err != nil {
+					return nil, badRequest("invalid id: " + id)
+				}
+			}
+			return s.MoveBlocks(r.PathValue("id"), r.PathValue("block"), req.To, req.Position, req.Indent, req.LeaveReference, copyBlocks)
+		}))
+	}
+	mux.Handle("POST /api/documents/{id}/blocks/{block}/extract", authorized(token, func(r *http.Request) (interface{}, error) {
+		var req extractRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, badRequest("invalid request: " + err.Error())
+		}
+		for _, id := range []string{r.PathValue("id"), r.PathValue("block")} {
+			if _, err := uuid.Parse(id); err != nil {
+				return nil, badRequest("invalid id: " + id)
+			}
+		}
+		return s.ExtractToPage(r.PathValue("id"), r.PathValue("block"), req.Title)
+	}))
+	mux.Handle("POST /api/documents/{id}/blocks/{block}/link", authorized(token, func(r *http.Request) (interface{}, error) {
+		var req linkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, badRequest("invalid request: " + err.Error())
+		}
+		for _, id := range []string{r.PathValue("id"), r.PathValue("block")} {
+			if _, err := uuid.Parse(id); err != nil {
+				return nil, badRequest("invalid id: " + id)
+			}
+		}
+		return s.LinkReference(r.PathValue("id"), r.PathValue("block"), req.Title)
+	}))
+	mux.Handle("POST /api/documents/{id}/merge", authorized(token, func(r *http.Request) (interface{}, error) {
+		var req mergeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, badRequest("invalid request: " + err.Error())
+		}
+		for _, id := range []string{r.PathValue("id"), req.Merge} {
+			if _, err := uuid.Parse(id); err != nil {
+				return nil, badRequest("invalid id: " + id)
+			}
+		}
+		return s.MergeDocuments(r.PathValue("id"), req.Merge, req.DryRun)
+	}))
+	mux.Handle("GET /api/documents/{id}/related", authorized(token, func(r *http.Request) (interface{}, error) {
+		if _, err := uuid.Parse(r.PathValue("id")); err != nil {
+			return nil, badRequest("invalid id: " + r.PathValue("id"))
+		}
+		limit, err := intParam(r, "limit")
+		if err != nil {
+			return nil, err
+		}
+		return nonNil(s.GetRelatedDocuments(r.PathValue("id"), limit))
+	}))
+	mux.Handle("GET /api/search", authorized(token, func(r *http.Request) (interface{}, error) {
+		return nonNil(s.SearchDocuments(r.URL.Query().Get("q")))
+	}))
+	mux.Handle("GET /api/search/documents", authorized(token, func(r *http.Request) (interface{}, error) {
+		offset, err := intParam(r, "offset")
+		if err != nil {
+			return nil, err
+		}
+		limit, err := intParam(r, "limit")
+		if err != nil {
+			return nil, err
+		}
+		return s.Search(r.URL.Query().Get("q"), offset, limit, r.URL.Query().Get("sort"))
+	}))
+	mux.Handle("GET /api/search/blocks", authorized(token, func(r *http.Request) (interface{}, error) {
+		limit, err := intParam(r, "limit")
+		if err != nil {
+			return nil, err
+		}
+		return nonNil(s.SearchBlocks(r.URL.Query().Get("q"), limit))
+	}))
+	mux.Handle("GET /api/searches", authorized(token, func(r *http.Request) (interface{}, error) {
+		return nonNil(s.ListSavedSearches())
+	}))
+	mux.Handle("POST /api/searches", authorized(token, func(r *http.Request) (interface{}, error) {
+		var search SavedSearchDto
+		if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+			return nil, badRequest("invalid saved search: " + err.Error())
+		}
+		search.Id = ""
+		return s.SaveSearch(search)
+	}))
+	mux.Handle("GET /api/searches/{id}", authorized(token, func(r *http.Request) (interface{}, error) {
+		if _, err := uuid.Parse(r.PathValue("id")); err != nil {
+			return nil, badRequest("invalid saved search id")
+		}
+		return s.GetSavedSearch(r.PathValue("id"))
+	}))
+	mux.Handle("PUT /api/searches/{id}", authorized(token, func(r *http.Request) (interface{}, error) {
+		if _, err := uuid.Parse(r.PathValue("id")); err != nil {
+			return nil, badRequest("invalid saved search id")
+		}
+		var search SavedSearchDto
+		if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+			return nil, badRequest("invalid saved search: " + err.Error())
+		}
+		if search.Id != "" && search.Id != r.PathValue("id") {
+			return nil, badRequest("saved search id does not match the URL")
+		}
+		search.Id = r.PathValue("id")
+		return s.SaveSearch(search)
+	}))
+	mux.Handle("DELETE /api/searches/{id}", authorized(token, func(r *http.Request) (interface{}, error) {
+		if _, err := uuid.Parse(r.PathValue("id")); err != nil {
+			return nil, badRequest("invalid saved search id")
+		}
+		return nil, s.DeleteSavedSearch(r.PathValue("id"))
+	}))
+	mux.Handle("GET /api/searches/{id}/results", authorized(token, func(r *http.Request) (interface{}, error) {
+		if _, err := uuid.Parse(r.PathValue("id")); err != nil {
+			return nil, badRequest("invalid saved search id")
+		}
+		offset, err := intParam(r, "offset")
+		if err != nil {
+			return nil, err
+		}
+		limit, err := intParam(r, "limit")
+		if err != nil {
+			return nil, err
+		}
+		return s.RunSavedSearch(r.PathValue("id"), offset, limit, r.URL.Query().Get("sort"))
+	}))
+	mux.Handle("GET /api/titles", authorized(token, func(r *http.Request) (interface{}, error) {
+		limit, err := intParam(r, "limit")
+		if err != nil {
+			return nil, err
+		}
+		return nonNil(s.SuggestTitles(r.URL.Query().Get("prefix"), limit))
+	}))
+	mux.Handle("GET /api/references", authorized(token, func(r *http.Request) (interface{}, error) {
+		title := r.URL.Query().Get("title")
+		if title == "" {
+			return nil, badRequest("query parameter 'title' is required")
+		}
+		return nonNil(s.GetReferences(title))
+	}))
+	mux.Handle("GET /api/references/unlinked", authorized(token, func(r *http.Request) (interface{}, error) {
+		title := r.URL.Query().Get("title")
+		if title == "" {
+			return nil, badRequest("query parameter 'title' is required")
+		}
+		return nonNil(s.GetUnlinkedReferences(title))
+	}))
+	mux.Handle("GET /api/tasks", authorized(token, func(r *http.Request) (interface{}, error) {
+		return nonNil(s.GetScheduledTasks())
+	}))
+	mux.Handle("GET /api/journals", authorized(token, func(r *http.Request) (interface{}, error) {
+		q := r.URL.Query()
+		for _, key := range []string{"from", "to"} {
+			if _, err := time.Parse(time.RFC3339, q.Get(key)); err != nil {
+				return nil, badRequest(fmt.Sprintf("query parameter '%s' must be an RFC 3339 date", key))
+			}
+		}
+		return nonNil(s.LoadJournals(q.Get("from"), q.Get("to")))
+	}))
+	mux.Handle("POST /api/assets", authorized(token, func(r *http.Request) (interface{}, error) {
+		var req assetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, badRequest("invalid asset: " + err.Error())
+		}
+		path, err := s.SaveAsset(req.Data)
+		if err != nil {
+			return nil, err
+		}
+		return assetResponse{Path: path}, nil
+	}))
+
+	return mux
+}
+
+type openRequest struct {
+	Title string `json:"title"` // created as an empty page when it does not exist
+}
+
+type opsRequest struct {
+	BaseRevision uint64       `json:"base_revision"`
+	Ops          []BlockOpDto `json:"ops"`
+}
+
+type moveRequest struct {
+	To             string `json:"to"`
+	Position       int    `json:"position"` // negative for the end
+	Indent         int    `json:"indent"`
+	LeaveReference bool   `json:"leave_reference"`
+}
+
+type extractRequest struct {
+	Title string `json:"title"` // empty for the block's first line
+}
+
+type linkRequest struct {
+	Title string `json:"title"` // the page linked, mentioned by title or alias
+}
+
+type mergeRequest struct {
+	Merge  string `json:"merge"` // the document merged into the one in the URL
+	DryRun bool   `json:"dry_run"`
+}
+
+type assetRequest struct {
+	Data string `json:"data"` // base64, optionally as a data URL
+}
+
+type assetResponse struct {
+	Path string `json:"path"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// badRequest is an error caused by the client's input.
+type badRequest string
+
+func (e badRequest) Error() string {
+	return string(e)
+}
+
+// nonNil makes empty results encode as [] rather than null.
+func nonNil[T any](items []T, err error) ([]T, error) {
+	if items == nil && err == nil {
+		items = []T{}
+	}
+	return items, err
+}
+
+func authorized(token string, handle func(r *http.Request) (interface{}, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		given, ok := strings.CutPrefix(auth, "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing or invalid API token"})
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+		result, err := handle(r)
+		if err != nil {
+			writeJSON(w, statusOf(err), errorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	})
+}
+
+// intParam returns the non-negative integer query parameter name, or 0
+// when it is missing.
+func intParam(r *http.Request, name string) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, badRequest("invalid " + name + ": " + v)
+	}
+	return n, nil
+}
+
+func statusOf(err error) int {
+	var bad badRequest
+	switch {
+	case errors.As(err, &bad):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrDocumentNotFound), errors.Is(err, db.ErrSavedSearchNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrInvalidOp), errors.Is(err, db.ErrInvalidQuery):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrDuplicateTitle), errors.Is(err, db.ErrRevisionConflict),
+		errors.Is(err, db.ErrDuplicateSearchName):
+		return http.StatusConflict
+	case errors.Is(err, db.ErrReadOnly):
+		return http.StatusForbidden
+	}
+
+	var corrupt base64.CorruptInputError
+	if errors.As(err, &corrupt) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warnf("api: failed to write response: %v", err)
+	}
+}
+
+// Server is a running HTTP API.
+type Server struct {
+	http     *http.Server
+	listener net.Listener
+}
+
+// Listen starts serving the API of s on addr, which must be a loopback
+// address such as 127.0.0.1:8765.
+func Listen(s *Service, addr string, token string) (*Server, error) {
+	if token == "" {
+		return nil, errors.New("an API token is required")
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("%w: %s", ErrNotLoopback, addr)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{
+		http: &http.Server{
+			Handler:           NewHandler(s, token),
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		listener: listener,
+	}
+	go func() {
+		if err := server.http.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("api: server stopped: %v", err)
+		}
+	}()
+	return server, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	return s.http.Close()
+}
@@ -0,0 +1,305 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"glog/db"
+
+	"github.com/google/uuid"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	store, err := db.NewDocumentStore("./testapi.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	assets := t.TempDir()
+	t.Cleanup(func() {
+		err := store.Close()
+		if err != nil {
+			t.Errorf("Failed to close DocumentStore: %v", err)
+		}
+
+		_ = os.Remove("./testapi.db")
+		_ = os.RemoveAll("./testapi.db.bleve")
+	})
+	return NewService(store, assets)
+}
+
+func request(t *testing.T, h http.Handler, method, target, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, target, &buf)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(newTestService(t), "secret")
+
+	if rec := request(t, h, "GET", "/api/tasks", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", rec.Code)
+	}
+	if rec := request(t, h, "GET", "/api/tasks", "wrong", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong token, got %d", rec.Code)
+	}
+	if rec := request(t, h, "GET", "/api/openapi.json", "", nil); rec.Code != http.StatusOK || !json.Valid(rec.Body.Bytes()) {
+		t.Errorf("Expected the OpenAPI description without token, got %d", rec.Code)
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	journal := DocumentDto{
+		Id:        uuid.NewString(),
+		Title:     "Journal",
+		Date:      today.Format(time.RFC3339),
+		IsJournal: true,
+		Blocks: []BlockDto{
+			{Id: uuid.NewString(), Content: "Met with [[Project]] team /scheduled " + today.Format("2006-01-02")},
+		},
+	}
+	rec := request(t, h, "PUT", "/api/documents/"+journal.Id, "secret", journal)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Save failed with %d: %s", rec.Code, rec.Body)
+	}
+	if rec := request(t, h, "PUT", "/api/documents/"+uuid.NewString(), "secret", journal); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for mismatched ids, got %d", rec.Code)
+	}
+
+	var doc DocumentDto
+	rec = request(t, h, "GET", "/api/documents/"+journal.Id, "secret", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil || doc.Title != "Journal" {
+		t.Errorf("Unexpected document %s (err %v)", rec.Body, err)
+	}
+
+	ops := opsRequest{
+		BaseRevision: doc.Revision,
+		Ops:          []BlockOpDto{{Type: "insert", BlockId: uuid.NewString(), After: journal.Blocks[0].Id, Content: "Follow up"}},
+	}
+	rec = request(t, h, "PATCH", "/api/documents/"+journal.Id, "secret", ops)
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil || len(doc.Blocks) != 2 || doc.Revision != ops.BaseRevision+1 {
+		t.Errorf("Expected the inserted block, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := request(t, h, "PATCH", "/api/documents/"+journal.Id, "secret", ops); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a stale revision, got %d", rec.Code)
+	}
+	ops = opsRequest{BaseRevision: doc.Revision, Ops: []BlockOpDto{{Type: "delete", BlockId: uuid.NewString()}}}
+	if rec := request(t, h, "PATCH", "/api/documents/"+journal.Id, "secret", ops); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown block, got %d", rec.Code)
+	}
+
+	page := DocumentDto{Id: uuid.NewString(), Title: "Inbox", Date: today.Format(time.RFC3339), Blocks: []BlockDto{}}
+	if rec := request(t, h, "PUT", "/api/documents/"+page.Id, "secret", page); rec.Code != http.StatusOK {
+		t.Fatalf("Save failed with %d: %s", rec.Code, rec.Body)
+	}
+	var moved MoveBlocksDto
+	move := moveRequest{To: page.Id, Position: -1}
+	rec = request(t, h, "POST", "/api/documents/"+journal.Id+"/blocks/"+doc.Blocks[1].Id+"/move", "secret", move)
+	if err := json.Unmarshal(rec.Body.Bytes(), &moved); err != nil || len(moved.Source.Blocks) != 1 || len(moved.Destination.Blocks) != 1 {
+		t.Errorf("Expected the block to move to the inbox, got %d: %s", rec.Code, rec.Body)
+	}
+	var holder DocumentDto
+	rec = request(t, h, "GET", "/api/blocks/"+doc.Blocks[1].Id+"/document", "secret", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &holder); err != nil || holder.Id != page.Id {
+		t.Errorf("Expected the moved block to resolve to the inbox, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := request(t, h, "GET", "/api/blocks/"+uuid.NewString()+"/document", "secret", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown block, got %d", rec.Code)
+	}
+	var plan MergePlanDto
+	rec = request(t, h, "POST", "/api/documents/"+page.Id+"/merge", "secret", mergeRequest{Merge: journal.Id, DryRun: true})
+	if err := json.Unmarshal(rec.Body.Bytes(), &plan); err != nil || !plan.DryRun || plan.Merge.Id != journal.Id || len(plan.Documents) < 2 {
+		t.Errorf("Expected a merge preview, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := request(t, h, "GET", "/api/documents/"+journal.Id, "secret", nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected the preview to keep the journal, got %d", rec.Code)
+	}
+
+	move.To = "not-a-uuid"
+	if rec := request(t, h, "POST", "/api/documents/"+journal.Id+"/blocks/"+doc.Blocks[0].Id+"/copy", "secret", move); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a malformed destination, got %d", rec.Code)
+	}
+
+	if rec := request(t, h, "GET", "/api/documents/"+uuid.NewString(), "secret", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown document, got %d", rec.Code)
+	}
+	if rec := request(t, h, "GET", "/api/documents/not-a-uuid", "secret", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a malformed id, got %d", rec.Code)
+	}
+
+	// Reading by title never creates the page; POST does, like in the app
+	if rec := request(t, h, "GET", "/api/documents?title="+url.QueryEscape("Project"), "secret", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing title, got %d", rec.Code)
+	}
+	rec = request(t, h, "POST", "/api/documents", "secret", openRequest{Title: "Project"})
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil || doc.Title != "Project" {
+		t.Errorf("Expected the Project page, got %s", rec.Body)
+	}
+	rec = request(t, h, "GET", "/api/documents?title="+url.QueryEscape("project"), "secret", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil || doc.Title != "Project" {
+		t.Errorf("Expected the created Project page, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := request(t, h, "POST", "/api/documents", "secret", openRequest{}); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a title, got %d", rec.Code)
+	}
+
+	duplicate := DocumentDto{Id: uuid.NewString(), Title: "project", Date: today.Format(time.RFC3339), Blocks: []BlockDto{}}
+	if rec := request(t, h, "PUT", "/api/documents/"+duplicate.Id, "secret", duplicate); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a duplicate title, got %d", rec.Code)
+	}
+
+	var summaries []DocumentSummaryDto
+	rec = request(t, h, "GET", "/api/search?q=team", "secret", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &summaries); err != nil || len(summaries) != 1 {
+		t.Errorf("Expected one search hit, got %s", rec.Body)
+	}
+	if rec := request(t, h, "GET", "/api/search?q=nothingmatches", "secret", nil); strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("Expected an empty list, got %s", rec.Body)
+	}
+	if rec := request(t, h, "GET", "/api/search?q=is:draft", "secret", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid query, got %d", rec.Code)
+	}
+
+	var results SearchResultsDto
+	rec = request(t, h, "GET", "/api/search/documents?q=team&limit=1&sort=date", "secret", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil || results.Total != 1 || len(results.Hits) != 1 || results.Hits[0].Id != journal.Id {
+		t.Errorf("Expected a page with one hit, got %s", rec.Body)
+	}
+	if rec := request(t, h, "GET", "/api/search/documents?q=team&sort=size", "secret", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown sort, got %d", rec.Code)
+	}
+
+	var suggestions []TitleSuggestionDto
+	rec = request(t, h, "GET", "/api/titles?prefix=proj&limit=5", "secret", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &suggestions); err != nil || len(suggestions) != 1 || suggestions[0].Title != "Project" || suggestions[0].Backlinks != 1 {
+		t.Errorf("Expected the Project page with its backlink, got %s", rec.Body)
+	}
+
+	var hits []BlockSearchHitDto
+	rec = request(t, h, "GET", "/api/search/blocks?q=team&limit=5", "secret", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &hits); err != nil || len(hits) != 1 || hits[0].DocId != journal.Id || !strings.Contains(hits[0].Fragments[0], "<mark>") {
+		t.Errorf("Expected one highlighted block hit, got %s", rec.Body)
+	}
+	if rec := request(t, h, "GET", "/api/search/blocks?q=team&limit=x", "secret", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid limit, got %d", rec.Code)
+	}
+
+	var refs []DocumentReferenceDto
+	rec = request(t, h, "GET", "/api/references?title=Project", "secret", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &refs); err != nil || len(refs) != 1 || refs[0].Id != journal.Id {
+		t.Errorf("Expected the journal to reference Project, got %s", rec.Body)
+	}
+
+	var unlinked []UnlinkedReferenceDto
+	rec = request(t, h, "GET", "/api/references/unlinked?title=Team", "secret", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &unlinked); err != nil || len(unlinked) != 1 || unlinked[0].BlockId != journal.Blocks[0].Id || unlinked[0].Mention != "team" {
+		t.Fatalf("Expected the journal to mention Team, got %s", rec.Body)
+	}
+	rec = request(t, h, "POST", "/api/documents/"+journal.Id+"/blocks/"+journal.Blocks[0].Id+"/link", "secret", linkRequest{Title: "Team"})
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil || !strings.HasPrefix(doc.Blocks[0].Content, "Met with [[Project]] [[Team]] /scheduled") {
+		t.Errorf("Expected the mention to be linked, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := request(t, h, "POST", "/api/documents/"+journal.Id+"/blocks/"+journal.Blocks[0].Id+"/link", "secret", linkRequest{Title: "Team"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a plain mention, got %d", rec.Code)
+	}
+
+	standup := DocumentDto{Id: uuid.NewString(), Title: "Standup", Date: today.Format(time.RFC3339), Blocks: []BlockDto{{Id: uuid.NewString(), Content: "Status of [[Project]]"}}}
+	if rec := request(t, h, "PUT", "/api/documents/"+standup.Id, "secret", standup); rec.Code != http.StatusOK {
+		t.Fatalf("Save failed with %d: %s", rec.Code, rec.Body)
+	}
+	var related []RelatedDocumentDto
+	rec = request(t, h, "GET", "/api/documents/"+journal.Id+"/related?limit=5", "secret", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &related); err != nil || len(related) != 1 || related[0].Id != standup.Id || !strings.HasPrefix(related[0].Reason, "1 shared link") {
+		t.Errorf("Expected Standup to share the Project link, got %s", rec.Body)
+	}
+	if rec := request(t, h, "GET", "/api/documents/"+uuid.NewString()+"/related", "secret", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown document, got %d", rec.Code)
+	}
+
+	var saved SavedSearchDto
+	rec = request(t, h, "POST", "/api/searches", "secret", SavedSearchDto{Name: "Project notes", Query: "links:[[Project]]"})
+	if err := json.Unmarshal(rec.Body.Bytes(), &saved); err != nil || saved.Id == "" || saved.Name != "Project notes" {
+		t.Fatalf("Expected a saved search, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := request(t, h, "POST", "/api/searches", "secret", SavedSearchDto{Name: "project NOTES", Query: "team"}); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a duplicate name, got %d", rec.Code)
+	}
+	if rec := request(t, h, "POST", "/api/searches", "secret", SavedSearchDto{Name: "Drafts", Query: "is:draft"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid query, got %d", rec.Code)
+	}
+	rec = request(t, h, "GET", "/api/searches/"+saved.Id+"/results?sort=title", "secret", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil || results.Total != 2 || results.Hits[1].Id != standup.Id {
+		t.Errorf("Expected the two documents linking Project, got %s", rec.Body)
+	}
+	saved.Query = "links:[[Team]]"
+	if rec := request(t, h, "PUT", "/api/searches/"+saved.Id, "secret", saved); rec.Code != http.StatusOK {
+		t.Errorf("Expected the saved search to be updated, got %d: %s", rec.Code, rec.Body)
+	}
+	var searches []SavedSearchDto
+	rec = request(t, h, "GET", "/api/searches", "secret", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &searches); err != nil || len(searches) != 1 || searches[0].Query != "links:[[Team]]" {
+		t.Errorf("Expected the updated saved search, got %s", rec.Body)
+	}
+	if rec := request(t, h, "DELETE", "/api/searches/"+saved.Id, "secret", nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected the saved search to be deleted, got %d", rec.Code)
+	}
+	if rec := request(t, h, "GET", "/api/searches/"+saved.Id, "secret", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a deleted saved search, got %d", rec.Code)
+	}
+
+	var tasks []ScheduledTaskDto
+	rec = request(t, h, "GET", "/api/tasks", "secret", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &tasks); err != nil || len(tasks) != 1 {
+		t.Errorf("Expected one scheduled task, got %s", rec.Body)
+	}
+
+	var journals []DocumentDto
+	q := url.Values{"from": {today.Format(time.RFC3339)}, "to": {today.Format(time.RFC3339)}}
+	rec = request(t, h, "GET", "/api/journals?"+q.Encode(), "secret", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &journals); err != nil || len(journals) != 1 {
+		t.Errorf("Expected one journal, got %s", rec.Body)
+	}
+	if rec := request(t, h, "GET", "/api/journals?from=yesterday", "secret", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for malformed dates, got %d", rec.Code)
+	}
+
+	var asset assetResponse
+	data := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("png"))
+	rec = request(t, h, "POST", "/api/assets", "secret", assetRequest{Data: data})
+	if err := json.Unmarshal(rec.Body.Bytes(), &asset); err != nil || !strings.HasPrefix(asset.Path, "./assets/") {
+		t.Errorf("Expected an asset path, got %s", rec.Body)
+	}
+	if rec := request(t, h, "POST", "/api/assets", "secret", assetRequest{Data: "%%%"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid base64, got %d", rec.Code)
+	}
+}
+
+func TestListenRequiresLoopback(t *testing.T) {
+	if _, err := Listen(nil, "0.0.0.0:0", "secret"); err == nil {
+		t.Errorf("Expected an error for a non-loopback address")
+	}
+
+	server, err := Listen(nil, "127.0.0.1:0", "secret")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	_ = server.Close()
+}
@@ -0,0 +1,548 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"glog/db"
+	"glog/domain"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Service implements the document operations shared by the desktop app
+// and the HTTP API.
+type Service struct {
+	store     *db.DocumentStore
+	assetsDir string
+}
+
+// NewService creates a Service on store. Pasted images are written to
+// assetsDir, which is served as ./assets/ by the app.
+func NewService(store *db.DocumentStore, assetsDir string) *Service {
+	return &Service{
+		store:     store,
+		assetsDir: assetsDir,
+	}
+}
+
+// SaveDocument saves doc whole and returns it with its new revision.
+func (s *Service) SaveDocument(doc DocumentDto) (DocumentDto, error) {
+	domainDoc, err := doc.ToDomain()
+	if err != nil {
+		return DocumentDto{}, err
+	}
+
+	err = s.store.Save(domainDoc)
+	if err != nil {
+		return DocumentDto{}, err
+	}
+
+	return ToDocumentDto(domainDoc), nil
+}
+
+// ApplyOps applies block operations to a document saved at baseRevision
+// and returns the updated document, see db.DocumentStore.ApplyOps.
+func (s *Service) ApplyOps(docId string, baseRevision uint64, ops []BlockOpDto) (DocumentDto, error) {
+	id, err := uuid.Parse(docId)
+	if err != nil {
+		return DocumentDto{}, err
+	}
+
+	blockOps := make([]db.BlockOp, len(ops))
+	for i, op := range ops {
+		blockOps[i], err = op.ToDomain()
+		if err != nil {
+			return DocumentDto{}, err
+		}
+	}
+
+	doc, err := s.store.ApplyOps(domain.DocumentID(id), baseRevision, blockOps)
+	if err != nil {
+		return DocumentDto{}, err
+	}
+	return ToDocumentDto(doc), nil
+}
+
+// MoveBlocks moves a block and its children to another document, see
+// db.DocumentStore.MoveBlocks. With copyBlocks the blocks are copied
+// instead and leaveReference is ignored.
+func (s *Service) MoveBlocks(srcId string, blockId string, dstId string, position int, indent int, leaveReference bool, copyBlocks bool) (MoveBlocksDto, error) {
+	src, err := uuid.Parse(srcId)
+	if err != nil {
+		return MoveBlocksDto{}, err
+	}
+	block, err := uuid.Parse(blockId)
+	if err != nil {
+		return MoveBlocksDto{}, err
+	}
+	dst, err := uuid.Parse(dstId)
+	if err != nil {
+		return MoveBlocksDto{}, err
+	}
+
+	transfer := s.store.MoveBlocks
+	if copyBlocks {
+		transfer = s.store.CopyBlocks
+	}
+	srcDoc, dstDoc, err := transfer(domain.DocumentID(src), domain.BlockID(block), domain.DocumentID(dst), position,
+		db.MoveOptions{Indent: indent, LeaveReference: leaveReference})
+	if err != nil {
+		return MoveBlocksDto{}, err
+	}
+
+	return MoveBlocksDto{
+		Source:      ToDocumentDto(srcDoc),
+		Destination: ToDocumentDto(dstDoc),
+	}, nil
+}
+
+// ExtractToPage turns a block's children into a new page and links to it
+// in place of the block, see db.DocumentStore.ExtractToPage. The new page
+// is the Destination of the result.
+func (s *Service) ExtractToPage(docId string, blockId string, title string) (MoveBlocksDto, error) {
+	id, err := uuid.Parse(docId)
+	if err != nil {
+		return MoveBlocksDto{}, err
+	}
+	block, err := uuid.Parse(blockId)
+	if err != nil {
+		return MoveBlocksDto{}, err
+	}
+
+	doc, page, err := s.store.ExtractToPage(domain.DocumentID(id), domain.BlockID(block), title)
+	if err != nil {
+		return MoveBlocksDto{}, err
+	}
+
+	return MoveBlocksDto{
+		Source:      ToDocumentDto(doc),
+		Destination: ToDocumentDto(page),
+	}, nil
+}
+
+// MergeDocuments merges document mergeId into keepId, or with dryRun only
+// reports what it would change, see db.DocumentStore.MergeDocuments.
+func (s *Service) MergeDocuments(keepId string, mergeId string, dryRun bool) (MergePlanDto, error) {
+	keep, err := uuid.Parse(keepId)
+	if err != nil {
+		return MergePlanDto{}, err
+	}
+	merge, err := uuid.Parse(mergeId)
+	if err != nil {
+		return MergePlanDto{}, err
+	}
+
+	run := s.store.MergeDocuments
+	if dryRun {
+		run = s.store.PreviewMerge
+	}
+	plan, err := run(domain.DocumentID(keep), domain.DocumentID(merge))
+	if err != nil {
+		return MergePlanDto{}, err
+	}
+	return ToMergePlanDto(plan, dryRun), nil
+}
+
+func (s *Service) LoadJournals(from string, to string) ([]DocumentDto, error) {
+	fromTime, err := time.Parse(time.RFC3339, from)
+	if err != nil {
+		return nil, err
+	}
+
+	toTime, err := time.Parse(time.RFC3339, to)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := s.store.LoadJournals(fromTime, toTime)
+	if err != nil {
+		return nil, err
+	}
+
+	docDtos := make([]DocumentDto, len(docs))
+	for i, doc := range docs {
+		docDtos[i] = ToDocumentDto(doc)
+	}
+
+	return docDtos, nil
+}
+
+func (s *Service) createNewDocument(title string) (DocumentDto, error) {
+	doc := domain.Document{
+		ID:    domain.DocumentID(uuid.New()),
+		Title: title,
+		Date:  time.Now(), // Use local time
+		Blocks: []*domain.Block{
+			{
+				ID:      domain.BlockID(uuid.New()),
+				Content: "",
+				Indent:  0,
+			},
+		},
+	}
+
+	err := s.store.Save(&doc)
+	if err != nil {
+		return DocumentDto{}, err
+	}
+
+	return ToDocumentDto(&doc), nil
+}
+
+func (s *Service) OpenDocument(docId string) (DocumentDto, error) {
+	id, err := uuid.Parse(docId)
+	if err != nil {
+		return DocumentDto{}, err
+	}
+
+	domainDoc, err := s.store.LoadDocument(domain.DocumentID(id))
+	if err != nil {
+		return DocumentDto{}, err
+	}
+
+	doc := ToDocumentDto(domainDoc)
+	return doc, nil
+}
+
+// OpenBlockDocument opens the document holding a block, to follow
+// ((block-id)) references.
+func (s *Service) OpenBlockDocument(blockId string) (DocumentDto, error) {
+	id, err := uuid.Parse(blockId)
+	if err != nil {
+		return DocumentDto{}, err
+	}
+
+	docID, err := s.store.GetBlockDocument(domain.BlockID(id))
+	if err != nil {
+		return DocumentDto{}, err
+	}
+	domainDoc, err := s.store.LoadDocument(docID)
+	if err != nil {
+		return DocumentDto{}, err
+	}
+	return ToDocumentDto(domainDoc), nil
+}
+
+// GetDocumentByTitle opens the document with the given title or alias,
+// without creating it.
+func (s *Service) GetDocumentByTitle(title string) (DocumentDto, error) {
+	domainDoc, err := s.store.LoadDocumentByTitle(title)
+	if err != nil {
+		return DocumentDto{}, err
+	}
+	return ToDocumentDto(domainDoc), nil
+}
+
+// OpenDocumentByTitle opens the document with the given title, creating
+// an empty page when there is none.
+func (s *Service) OpenDocumentByTitle(title string) (DocumentDto, error) {
+	domainDoc, err := s.store.LoadDocumentByTitle(title)
+	if err != nil {
+		if errors.Is(err, db.ErrDocumentNotFound) {
+			return s.createNewDocument(title)
+		}
+		if errors.Is(err, db.ErrDuplicateTitle) {
+			// Another request created the document concurrently.
+			// Load again by title and return the winner.
+			domainDoc, loadErr := s.store.LoadDocumentByTitle(title)
+			if loadErr != nil {
+				return DocumentDto{}, loadErr
+			}
+			return ToDocumentDto(domainDoc), nil
+		}
+		return DocumentDto{}, err
+	}
+
+	doc := ToDocumentDto(domainDoc)
+	return doc, nil
+}
+
+func (s *Service) SearchDocuments(search string) ([]DocumentSummaryDto, error) {
+	results, err := s.Search(search, 0, 10000, string(db.SortRelevance))
+	if err != nil {
+		return nil, err
+	}
+	return results.Hits, nil
+}
+
+// Search returns up to limit documents matching search from offset, sorted
+// by relevance, date or title, with the total number of matches.
+func (s *Service) Search(search string, offset int, limit int, sort string) (SearchResultsDto, error) {
+	results, err := s.store.SearchPage(search, offset, limit, db.SearchSort(sort))
+	if err != nil {
+		return SearchResultsDto{}, err
+	}
+	return ToSearchResultsDto(results, max(offset, 0)), nil
+}
+
+// ListSavedSearches returns the saved searches ordered by name.
+func (s *Service) ListSavedSearches() ([]SavedSearchDto, error) {
+	searches, err := s.store.ListSavedSearches()
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]SavedSearchDto, len(searches))
+	for i, search := range searches {
+		dtos[i] = ToSavedSearchDto(search)
+	}
+	return dtos, nil
+}
+
+func (s *Service) GetSavedSearch(searchId string) (SavedSearchDto, error) {
+	id, err := uuid.Parse(searchId)
+	if err != nil {
+		return SavedSearchDto{}, err
+	}
+	search, err := s.store.GetSavedSearch(id)
+	if err != nil {
+		return SavedSearchDto{}, err
+	}
+	return ToSavedSearchDto(*search), nil
+}
+
+// SaveSearch creates a saved search, or updates it when search has an id.
+func (s *Service) SaveSearch(search SavedSearchDto) (SavedSearchDto, error) {
+	domainSearch, err := search.ToDomain()
+	if err != nil {
+		return SavedSearchDto{}, err
+	}
+	saved, err := s.store.SaveSearch(domainSearch)
+	if err != nil {
+		return SavedSearchDto{}, err
+	}
+	return ToSavedSearchDto(*saved), nil
+}
+
+func (s *Service) DeleteSavedSearch(searchId string) error {
+	id, err := uuid.Parse(searchId)
+	if err != nil {
+		return err
+	}
+	return s.store.DeleteSavedSearch(id)
+}
+
+// RunSavedSearch returns a page of the current results of a saved search,
+// see Search.
+func (s *Service) RunSavedSearch(searchId string, offset int, limit int, sort string) (SearchResultsDto, error) {
+	id, err := uuid.Parse(searchId)
+	if err != nil {
+		return SearchResultsDto{}, err
+	}
+	_, results, err := s.store.RunSavedSearch(id, offset, limit, db.SearchSort(sort))
+	if err != nil {
+		return SearchResultsDto{}, err
+	}
+	return ToSearchResultsDto(results, max(offset, 0)), nil
+}
+
+// SearchBlocks returns up to limit blocks matching search, best first.
+func (s *Service) SearchBlocks(search string, limit int) ([]BlockSearchHitDto, error) {
+	hits, err := s.store.SearchBlocks(search, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]BlockSearchHitDto, len(hits))
+	for i, hit := range hits {
+		dtos[i] = ToBlockSearchHitDto(hit)
+	}
+	return dtos, nil
+}
+
+// SuggestTitles returns up to limit page titles starting with prefix, or
+// close to it, for link autocomplete.
+func (s *Service) SuggestTitles(prefix string, limit int) ([]TitleSuggestionDto, error) {
+	suggestions, err := s.store.SuggestTitles(prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]TitleSuggestionDto, len(suggestions))
+	for i, suggestion := range suggestions {
+		dtos[i] = ToTitleSuggestionDto(suggestion)
+	}
+	return dtos, nil
+}
+
+// GetUnlinkedReferences returns the blocks mentioning the page title, or
+// one of its aliases, in documents that do not link it.
+func (s *Service) GetUnlinkedReferences(title string) ([]UnlinkedReferenceDto, error) {
+	refs, err := s.store.GetUnlinkedReferences(title)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]UnlinkedReferenceDto, len(refs))
+	for i, ref := range refs {
+		dtos[i] = ToUnlinkedReferenceDto(ref)
+	}
+	return dtos, nil
+}
+
+// GetRelatedDocuments suggests up to limit documents related to a document
+// that are not linked with it, best first.
+func (s *Service) GetRelatedDocuments(docId string, limit int) ([]RelatedDocumentDto, error) {
+	id, err := uuid.Parse(docId)
+	if err != nil {
+		return nil, err
+	}
+
+	related, err := s.store.GetRelatedDocuments(domain.DocumentID(id), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]RelatedDocumentDto, len(related))
+	for i, r := range related {
+		dtos[i] = ToRelatedDocumentDto(r)
+	}
+	return dtos, nil
+}
+
+// LinkReference turns the first plain mention of title in a block into a
+// [[title]] link, see db.DocumentStore.LinkReference.
+func (s *Service) LinkReference(docId string, blockId string, title string) (DocumentDto, error) {
+	doc, err := uuid.Parse(docId)
+	if err != nil {
+		return DocumentDto{}, err
+	}
+	block, err := uuid.Parse(blockId)
+	if err != nil {
+		return DocumentDto{}, err
+	}
+
+	linked, err := s.store.LinkReference(domain.DocumentID(doc), domain.BlockID(block), title)
+	if err != nil {
+		return DocumentDto{}, err
+	}
+	return ToDocumentDto(linked), nil
+}
+
+func (s *Service) GetReferences(title string) ([]DocumentReferenceDto, error) {
+	titleLower := strings.ToLower(title)
+	docIDs, err := s.store.GetReferences(title)
+	if err != nil {
+		return nil, err
+	}
+
+	var references []DocumentReferenceDto
+	for _, id := range docIDs {
+		domainDoc, err := s.store.LoadDocument(id)
+		if err != nil {
+			return nil, err
+		}
+
+		parents := db.ParentIndexes(domainDoc.Blocks)
+		include := make([]bool, len(domainDoc.Blocks))
+
+		needle := "[[" + titleLower + "]]"
+		for i, block := range domainDoc.Blocks {
+			contentLower := strings.ToLower(block.Content)
+			if !strings.Contains(contentLower, needle) {
+				continue
+			}
+
+			include[i] = true
+			for parent := parents[i]; parent != -1; parent = parents[parent] {
+				include[parent] = true
+			}
+		}
+
+		blocks := make([]BlockReferenceDto, 0)
+		for i, block := range domainDoc.Blocks {
+			if !include[i] {
+				continue
+			}
+			blocks = append(blocks, BlockReferenceDto{
+				Id:      block.ID.String(),
+				Content: block.Content,
+				Indent:  block.Indent,
+			})
+		}
+
+		references = append(references, DocumentReferenceDto{
+			Id:     domainDoc.ID.String(),
+			Title:  domainDoc.Title,
+			Blocks: blocks,
+		})
+	}
+
+	return references, nil
+}
+
+func (s *Service) GetScheduledTasks() ([]ScheduledTaskDto, error) {
+	scheduleTasks, err := s.store.GetScheduledTasks(time.Now(), 5)
+	if err != nil {
+		return nil, err
+	}
+
+	var scheduledTaskDtos []ScheduledTaskDto
+	for _, task := range scheduleTasks {
+		doc, err := s.store.LoadDocument(task.DocID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Get the block content
+		var blockContent string
+		for _, block := range doc.Blocks {
+			if block.ID == task.BlockID {
+				blockContent = block.Content
+				break
+			}
+		}
+
+		// Skip tasks marked as done
+		if db.IsDone(blockContent) {
+			continue
+		}
+
+		scheduledTaskDtos = append(scheduledTaskDtos, ScheduledTaskDto{
+			Id:          task.ID.String(),
+			Title:       doc.Title,
+			DocId:       task.DocID.String(),
+			BlockId:     task.BlockID.String(),
+			Description: blockContent,
+			DueDate:     task.Time.Format(time.RFC3339),
+		})
+	}
+
+	return scheduledTaskDtos, nil
+}
+
+// SaveAsset saves a base64-encoded image to the assets directory and returns the relative path.
+// The image is saved with a UUID-based filename to avoid collisions.
+func (s *Service) SaveAsset(base64Data string) (string, error) {
+	// Remove data URL prefix if present (e.g., "data:image/png;base64,")
+	if idx := strings.Index(base64Data, ","); idx != -1 {
+		base64Data = base64Data[idx+1:]
+	}
+
+	// Decode the base64 data
+	data, err := base64.StdEncoding.DecodeString(base64Data)
+	if err != nil {
+		return "", err
+	}
+
+	// Generate a UUID-based filename
+	filename := uuid.NewString() + ".png"
+
+	assetsDir := s.assetsDir
+	if err := os.MkdirAll(assetsDir, 0755); err != nil {
+		return "", err
+	}
+
+	// Write the file
+	filePath := filepath.Join(assetsDir, filename)
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return "", err
+	}
+
+	// Return the relative path for use in markdown (use forward slashes for URL compatibility)
+	return "./assets/" + filename, nil
+}
@@ -2,28 +2,40 @@ package main
 
 import (
 	"context"
-	"encoding/base64"
 	"errors"
+	"glog/api"
 	"glog/db"
 	"glog/domain"
-	"os"
-	"path/filepath"
+	"glog/hooks"
+	"glog/import/common"
+	"glog/import/opml"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App struct
 type App struct {
 	ctx context.Context
 	db  *db.DocumentStore
+	api *api.Service
+
+	events *db.Subscription
+	hooks  *hooks.Runner
+
+	apiServer    *api.Server
+	apiServerDto APIServerDto
+	apiServerMu  sync.Mutex
 }
 
 // NewApp creates a new App application struct
 func NewApp(db *db.DocumentStore) *App {
 	return &App{
-		db: db,
+		db:  db,
+		api: api.NewService(db, "assets"),
 	}
 }
 
@@ -31,20 +43,111 @@ func NewApp(db *db.DocumentStore) *App {
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+
+	// Forward store events to the frontend as "store:<EventType>", e.g.
+	// "store:DocumentSaved", with a ChangeEventDto payload
+	a.events = a.db.Subscribe(256)
+	go func(events <-chan db.Event) {
+		for ev := range events {
+			runtime.EventsEmit(ctx, "store:"+string(ev.Type), api.ToChangeEventDto(ev))
+		}
+	}(a.events.C)
 }
 
-func (a *App) SaveDocument(doc DocumentDto) error {
-	domainDoc, err := doc.ToDomain()
-	if err != nil {
-		return err
+// shutdown is called when the app is closing
+func (a *App) shutdown(ctx context.Context) {
+	if a.events != nil {
+		a.db.Unsubscribe(a.events)
+	}
+	if a.hooks != nil {
+		a.hooks.Stop()
+	}
+}
+
+// GetHookFailures returns the most recent failed hook runs, newest first.
+func (a *App) GetHookFailures() []HookFailureDto {
+	if a.hooks == nil {
+		return []HookFailureDto{}
 	}
 
-	err = a.db.Save(domainDoc)
+	failures := a.hooks.Failures()
+	dtos := make([]HookFailureDto, len(failures))
+	for i, f := range failures {
+		dtos[i] = HookFailureDto{
+			Hook:     f.Hook,
+			Event:    f.Event,
+			DocId:    f.DocID.String(),
+			Title:    f.Title,
+			Time:     f.Time.Format(time.RFC3339),
+			Attempts: f.Attempts,
+			Error:    f.Error,
+		}
+	}
+	return dtos
+}
+
+// GetChanges returns up to limit events of the change log after the
+// sequence number after, so the frontend can catch up on missed events.
+// Only the newest events are kept, see db.DocumentStore.Changes.
+func (a *App) GetChanges(after int, limit int) ([]ChangeEventDto, error) {
+	if after < 0 {
+		after = 0
+	}
+	events, err := a.db.Changes(uint64(after), limit)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	changes := make([]ChangeEventDto, len(events))
+	for i, ev := range events {
+		changes[i] = api.ToChangeEventDto(ev)
+	}
+	return changes, nil
+}
+
+// SaveDocument saves a document whole and returns it with its new
+// revision, see ApplyOps.
+func (a *App) SaveDocument(doc DocumentDto) (DocumentDto, error) {
+	return a.api.SaveDocument(doc)
+}
+
+// ApplyOps applies block operations to a document instead of saving it
+// whole. baseRevision is the revision of the DocumentDto the operations
+// were made on; it fails when the document changed since.
+func (a *App) ApplyOps(docId string, baseRevision uint64, ops []BlockOpDto) (DocumentDto, error) {
+	return a.api.ApplyOps(docId, baseRevision, ops)
+}
+
+// MoveBlocks moves a block and its children to another document, before
+// the block at position (negative for the end) and with the given indent.
+// With leaveReference a ((block-id)) reference stays in its place.
+func (a *App) MoveBlocks(srcDocId string, blockId string, dstDocId string, position int, indent int, leaveReference bool) (MoveBlocksDto, error) {
+	return a.api.MoveBlocks(srcDocId, blockId, dstDocId, position, indent, leaveReference, false)
+}
+
+// CopyBlocks copies a block and its children to another document like
+// MoveBlocks; the copies get new block IDs.
+func (a *App) CopyBlocks(srcDocId string, blockId string, dstDocId string, position int, indent int) (MoveBlocksDto, error) {
+	return a.api.MoveBlocks(srcDocId, blockId, dstDocId, position, indent, false, true)
+}
+
+// ExtractToPage creates a page titled title from the children of a block
+// and replaces them with a [[title]] link. An empty title uses the
+// block's text. The new page is the Destination of the result.
+func (a *App) ExtractToPage(docId string, blockId string, title string) (MoveBlocksDto, error) {
+	return a.api.ExtractToPage(docId, blockId, title)
+}
+
+// PreviewMerge lists what MergeDocuments would change: the appended
+// blocks and every document whose links would be rewritten.
+func (a *App) PreviewMerge(keepId string, mergeId string) (MergePlanDto, error) {
+	return a.api.MergeDocuments(keepId, mergeId, true)
+}
+
+// MergeDocuments appends the blocks of document mergeId to keepId, points
+// links to the merged title at the kept one and deletes the merged document.
+func (a *App) MergeDocuments(keepId string, mergeId string) (MergePlanDto, error) {
+	return a.api.MergeDocuments(keepId, mergeId, false)
 }
 
 // DeleteDocument removes a document and all its index entries from the store.
@@ -57,80 +160,23 @@ func (a *App) DeleteDocument(id string) error {
 }
 
 func (a *App) LoadJournalToday() (DocumentDto, error) {
-	// Get current time in UTC and normalize to start of day
-	// Using UTC ensures consistency with how journal index keys are stored
-	now := time.Now().UTC()
-	t := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	// Journals are keyed by their UTC day, see db.JournalDay
+	now := time.Now()
+	t := db.JournalDay(now)
 
 	from := t
 	to := t.Add(24 * time.Hour)
 	docs, err := a.db.LoadJournals(from, to)
 	if err == nil && docs != nil && len(docs) > 0 {
-		return ToDocumentDto(docs[0]), nil
+		return api.ToDocumentDto(docs[0]), nil
 	}
 
-	// Create title based on local date for user-friendly display
-	localNow := time.Now()
-	title := localNow.Format("Monday, January 2, 2006")
-	return DocumentDto{
-		Id:        uuid.NewString(),
-		Title:     title,
-		Date:      t.Format(time.RFC3339),
-		IsJournal: true,
-		Blocks: []BlockDto{
-			{
-				Id:      uuid.NewString(),
-				Content: "",
-				Indent:  0,
-			},
-		},
-	}, nil
+	// Not saved until the user edits it
+	return api.ToDocumentDto(db.NewJournal(now)), nil
 }
 
 func (a *App) LoadJournals(from string, to string) ([]DocumentDto, error) {
-	fromTime, err := time.Parse(time.RFC3339, from)
-	if err != nil {
-		return nil, err
-	}
-
-	toTime, err := time.Parse(time.RFC3339, to)
-	if err != nil {
-		return nil, err
-	}
-
-	docs, err := a.db.LoadJournals(fromTime, toTime)
-	if err != nil {
-		return nil, err
-	}
-
-	docDtos := make([]DocumentDto, len(docs))
-	for i, doc := range docs {
-		docDtos[i] = ToDocumentDto(doc)
-	}
-
-	return docDtos, nil
-}
-
-func (a *App) createNewDocument(title string) (DocumentDto, error) {
-	doc := domain.Document{
-		ID:    domain.DocumentID(uuid.New()),
-		Title: title,
-		Date:  time.Now(), // Use local time
-		Blocks: []*domain.Block{
-			{
-				ID:      domain.BlockID(uuid.New()),
-				Content: "",
-				Indent:  0,
-			},
-		},
-	}
-
-	err := a.db.Save(&doc)
-	if err != nil {
-		return DocumentDto{}, err
-	}
-
-	return ToDocumentDto(&doc), nil
+	return a.api.LoadJournals(from, to)
 }
 
 func (a *App) GetDocumentList() ([]DocumentSummaryDto, error) {
@@ -181,201 +227,93 @@ func (a *App) GetRecentDocuments(limit int) ([]DocumentSummaryDto, error) {
 }
 
 func (a *App) OpenDocument(docId string) (DocumentDto, error) {
-	id, err := uuid.Parse(docId)
-	if err != nil {
-		return DocumentDto{}, err
-	}
-
-	domainDoc, err := a.db.LoadDocument(domain.DocumentID(id))
-	if err != nil {
-		return DocumentDto{}, err
-	}
+	return a.api.OpenDocument(docId)
+}
 
-	doc := ToDocumentDto(domainDoc)
-	return doc, nil
+// OpenBlockDocument opens the document holding a block, to follow
+// ((block-id)) references.
+func (a *App) OpenBlockDocument(blockId string) (DocumentDto, error) {
+	return a.api.OpenBlockDocument(blockId)
 }
 
 func (a *App) OpenDocumentByTitle(title string) (DocumentDto, error) {
-	domainDoc, err := a.db.LoadDocumentByTitle(title)
-	if err != nil {
-		if errors.Is(err, db.ErrDocumentNotFound) {
-			return a.createNewDocument(title)
-		}
-		if errors.Is(err, db.ErrDuplicateTitle) {
-			// Another request created the document concurrently.
-			// Load again by title and return the winner.
-			domainDoc, loadErr := a.db.LoadDocumentByTitle(title)
-			if loadErr != nil {
-				return DocumentDto{}, loadErr
-			}
-			return ToDocumentDto(domainDoc), nil
-		}
-		return DocumentDto{}, err
-	}
-
-	doc := ToDocumentDto(domainDoc)
-	return doc, nil
+	return a.api.OpenDocumentByTitle(title)
 }
 
 func (a *App) SearchDocuments(search string) ([]DocumentSummaryDto, error) {
-	docIDs, err := a.db.Search(search)
-	if err != nil {
-		return nil, err
-	}
-
-	summaries := make([]DocumentSummaryDto, 0, len(docIDs))
-	for _, id := range docIDs {
-		domainDoc, err := a.db.LoadDocument(domain.DocumentID(id))
-		if err != nil {
-			return nil, err
-		}
-
-		summaries = append(summaries, DocumentSummaryDto{
-			Id:    domainDoc.ID.String(),
-			Title: domainDoc.Title,
-			Date:  domainDoc.Date.Format(time.RFC3339),
-		})
-	}
+	return a.api.SearchDocuments(search)
+}
 
-	return summaries, nil
+// Search returns a page of the documents matching search, sorted by
+// "relevance", "date" (newest first) or "title", with the total count
+func (a *App) Search(search string, offset int, limit int, sort string) (SearchResultsDto, error) {
+	return a.api.Search(search, offset, limit, sort)
 }
 
-type DocumentReferenceDto struct {
-	Id     string
-	Title  string
-	Blocks []BlockReferenceDto
+// ListSavedSearches returns the saved searches ordered by name, for the
+// document list
+func (a *App) ListSavedSearches() ([]SavedSearchDto, error) {
+	return a.api.ListSavedSearches()
 }
 
-type BlockReferenceDto struct {
-	Id      string
-	Content string
-	Indent  int
+func (a *App) GetSavedSearch(searchId string) (SavedSearchDto, error) {
+	return a.api.GetSavedSearch(searchId)
 }
 
-func computeParentIndexes(blocks []*domain.Block) []int {
-	parents := make([]int, len(blocks))
-	stack := make([]int, 0, 16)
+// SaveSearch creates a saved search, or updates it when search has an id.
+// With notify, documents that start matching raise a store:SearchMatched
+// event
+func (a *App) SaveSearch(search SavedSearchDto) (SavedSearchDto, error) {
+	return a.api.SaveSearch(search)
+}
 
-	for i, block := range blocks {
-		for len(stack) > 0 {
-			top := stack[len(stack)-1]
-			if blocks[top].Indent < block.Indent {
-				break
-			}
-			stack = stack[:len(stack)-1]
-		}
+func (a *App) DeleteSavedSearch(searchId string) error {
+	return a.api.DeleteSavedSearch(searchId)
+}
 
-		if len(stack) == 0 {
-			parents[i] = -1
-		} else {
-			parents[i] = stack[len(stack)-1]
-		}
+// RunSavedSearch returns a page of the current results of a saved search,
+// see Search
+func (a *App) RunSavedSearch(searchId string, offset int, limit int, sort string) (SearchResultsDto, error) {
+	return a.api.RunSavedSearch(searchId, offset, limit, sort)
+}
 
-		stack = append(stack, i)
-	}
+// SearchBlocks returns up to limit blocks matching search, best first,
+// with highlighted fragments, so a hit can open its document at the block
+func (a *App) SearchBlocks(search string, limit int) ([]BlockSearchHitDto, error) {
+	return a.api.SearchBlocks(search, limit)
+}
 
-	return parents
+// SuggestTitles returns up to limit page titles for [[link]] autocomplete,
+// recently opened and most linked first
+func (a *App) SuggestTitles(prefix string, limit int) ([]TitleSuggestionDto, error) {
+	return a.api.SuggestTitles(prefix, limit)
 }
 
 func (a *App) GetReferences(title string) ([]DocumentReferenceDto, error) {
-	titleLower := strings.ToLower(title)
-	docIDs, err := a.db.GetReferences(title)
-	if err != nil {
-		return nil, err
-	}
-
-	var references []DocumentReferenceDto
-	for _, id := range docIDs {
-		domainDoc, err := a.db.LoadDocument(id)
-		if err != nil {
-			return nil, err
-		}
-
-		parents := computeParentIndexes(domainDoc.Blocks)
-		include := make([]bool, len(domainDoc.Blocks))
-
-		needle := "[[" + titleLower + "]]"
-		for i, block := range domainDoc.Blocks {
-			contentLower := strings.ToLower(block.Content)
-			if !strings.Contains(contentLower, needle) {
-				continue
-			}
-
-			include[i] = true
-			for parent := parents[i]; parent != -1; parent = parents[parent] {
-				include[parent] = true
-			}
-		}
-
-		blocks := make([]BlockReferenceDto, 0)
-		for i, block := range domainDoc.Blocks {
-			if !include[i] {
-				continue
-			}
-			blocks = append(blocks, BlockReferenceDto{
-				Id:      block.ID.String(),
-				Content: block.Content,
-				Indent:  block.Indent,
-			})
-		}
-
-		references = append(references, DocumentReferenceDto{
-			Id:     domainDoc.ID.String(),
-			Title:  domainDoc.Title,
-			Blocks: blocks,
-		})
-	}
-
-	return references, nil
+	return a.api.GetReferences(title)
 }
 
-func (a *App) GetScheduledTasks() ([]ScheduledTaskDto, error) {
-	scheduleTasks, err := a.db.GetScheduledTasks(time.Now(), 5)
-	if err != nil {
-		return nil, err
-	}
-
-	var scheduledTaskDtos []ScheduledTaskDto
-	for _, task := range scheduleTasks {
-		doc, err := a.db.LoadDocument(task.DocID)
-		if err != nil {
-			return nil, err
-		}
-
-		// Get the block content
-		var blockContent string
-		for _, block := range doc.Blocks {
-			if block.ID == task.BlockID {
-				blockContent = block.Content
-				break
-			}
-		}
-
-		// Skip tasks marked as done
-		if db.IsDone(blockContent) {
-			continue
-		}
+// GetUnlinkedReferences returns the blocks mentioning the page title, or
+// one of its aliases, in plain text in documents that do not link it
+func (a *App) GetUnlinkedReferences(title string) ([]UnlinkedReferenceDto, error) {
+	return a.api.GetUnlinkedReferences(title)
+}
 
-		scheduledTaskDtos = append(scheduledTaskDtos, ScheduledTaskDto{
-			Id:          task.ID.String(),
-			Title:       doc.Title,
-			DocId:       task.DocID.String(),
-			BlockId:     task.BlockID.String(),
-			Description: blockContent,
-			DueDate:     task.Time.Format(time.RFC3339),
-		})
-	}
+// LinkReference turns the first plain mention of title in a block into a
+// [[title]] link and returns the saved document
+func (a *App) LinkReference(docId string, blockId string, title string) (DocumentDto, error) {
+	return a.api.LinkReference(docId, blockId, title)
+}
 
-	return scheduledTaskDtos, nil
+// GetRelatedDocuments suggests up to limit documents related to a document
+// by shared links, shared tags and similar text, leaving out the documents
+// already linked with it
+func (a *App) GetRelatedDocuments(docId string, limit int) ([]RelatedDocumentDto, error) {
+	return a.api.GetRelatedDocuments(docId, limit)
 }
 
-// IndexHealthDto represents the health status of the search index for the UI
-type IndexHealthDto struct {
-	IsHealthy          bool   `json:"isHealthy"`
-	FailedDocuments    int    `json:"failedDocuments"`
-	LastHealthCheck    string `json:"lastHealthCheck"`
-	RequiresReindex    bool   `json:"requiresReindex"`
-	HealthCheckMessage string `json:"healthCheckMessage"`
+func (a *App) GetScheduledTasks() ([]ScheduledTaskDto, error) {
+	return a.api.GetScheduledTasks()
 }
 
 // GetIndexHealth returns the current health status of the search index
@@ -387,6 +325,8 @@ func (a *App) GetIndexHealth() IndexHealthDto {
 		LastHealthCheck:    health.LastHealthCheck.Format(time.RFC3339),
 		RequiresReindex:    health.RequiresReindex,
 		HealthCheckMessage: health.HealthCheckMessage,
+		QueueDepth:         health.QueueDepth,
+		QueueLagMs:         health.QueueLag.Milliseconds(),
 	}
 }
 
@@ -395,40 +335,137 @@ func (a *App) ReindexSearch() error {
 	return a.db.ReindexSearch()
 }
 
+// VerifySearchIndex checks the search index against the database and
+// reindexes only the documents that differ, without blocking searches
+func (a *App) VerifySearchIndex() (IndexVerificationDto, error) {
+	result, err := a.db.VerifySearchIndex()
+	if err != nil {
+		return IndexVerificationDto{}, err
+	}
+	return api.ToIndexVerificationDto(result), nil
+}
+
+// GetSearchAnalysis returns how the search index analyzes text
+func (a *App) GetSearchAnalysis() (SearchAnalysisDto, error) {
+	analysis, err := a.db.GetSearchAnalysis()
+	if err != nil {
+		return SearchAnalysisDto{}, err
+	}
+	return api.ToSearchAnalysisDto(analysis), nil
+}
+
+// SetSearchAnalysis changes how the search index analyzes text; the index
+// is rebuilt in the background when the analysis changes
+func (a *App) SetSearchAnalysis(analysis SearchAnalysisDto) error {
+	return a.db.SetSearchAnalysis(analysis.ToDomain())
+}
+
+// GetSearchLanguages returns the languages the search index can stem
+func (a *App) GetSearchLanguages() []string {
+	return db.SearchLanguages()
+}
+
 // RetryFailedIndexing attempts to reindex documents that previously failed
 func (a *App) RetryFailedIndexing() (int, error) {
 	return a.db.RetryFailedIndexing()
 }
 
-// SaveAsset saves a base64-encoded image to the assets directory and returns the relative path.
-// The image is saved with a UUID-based filename to avoid collisions.
-func (a *App) SaveAsset(base64Data string) (string, error) {
-	// Remove data URL prefix if present (e.g., "data:image/png;base64,")
-	if idx := strings.Index(base64Data, ","); idx != -1 {
-		base64Data = base64Data[idx+1:]
+// ExportOPML renders a document as an OPML outline so it can be opened in
+// other outliners.
+func (a *App) ExportOPML(docId string) (string, error) {
+	id, err := uuid.Parse(docId)
+	if err != nil {
+		return "", err
 	}
 
-	// Decode the base64 data
-	data, err := base64.StdEncoding.DecodeString(base64Data)
+	domainDoc, err := a.db.LoadDocument(domain.DocumentID(id))
 	if err != nil {
 		return "", err
 	}
 
-	// Generate a UUID-based filename
-	filename := uuid.NewString() + ".png"
-
-	// Determine assets directory (same directory as glog.db)
-	assetsDir := "assets"
-	if err := os.MkdirAll(assetsDir, 0755); err != nil {
+	data, err := opml.Export(domainDoc)
+	if err != nil {
 		return "", err
 	}
 
-	// Write the file
-	filePath := filepath.Join(assetsDir, filename)
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return "", err
+	return string(data), nil
+}
+
+// ImportOPML creates a new document from an OPML outline. If the title is
+// already taken, a suffix is added the same way the importers do.
+func (a *App) ImportOPML(data string) (DocumentDto, error) {
+	domainDoc, err := opml.Parse(strings.NewReader(data))
+	if err != nil {
+		return DocumentDto{}, err
+	}
+
+	if domainDoc.Title == "" {
+		domainDoc.Title = "Imported outline"
+	}
+	domainDoc.Title = common.FindUniqueTitle(a.db, domainDoc.Title)
+
+	if err := a.db.Save(domainDoc); err != nil {
+		return DocumentDto{}, err
+	}
+
+	return api.ToDocumentDto(domainDoc), nil
+}
+
+// SaveAsset saves a base64-encoded image to the assets directory and returns the relative path.
+func (a *App) SaveAsset(base64Data string) (string, error) {
+	return a.api.SaveAsset(base64Data)
+}
+
+// StartAPIServer serves the HTTP API in-process on a loopback address,
+// e.g. 127.0.0.1:8765. An empty token generates a new one. The returned
+// URL and token are what scripts and plugins need to connect.
+func (a *App) StartAPIServer(addr string, token string) (APIServerDto, error) {
+	a.apiServerMu.Lock()
+	defer a.apiServerMu.Unlock()
+
+	if a.apiServer != nil {
+		return APIServerDto{}, errors.New("the API server is already running")
+	}
+
+	if token == "" {
+		t, err := api.NewToken()
+		if err != nil {
+			return APIServerDto{}, err
+		}
+		token = t
+	}
+
+	server, err := api.Listen(a.api, addr, token)
+	if err != nil {
+		return APIServerDto{}, err
+	}
+
+	a.apiServer = server
+	a.apiServerDto = APIServerDto{
+		Url:   "http://" + server.Addr() + "/api/",
+		Token: token,
 	}
+	return a.apiServerDto, nil
+}
+
+// StopAPIServer stops the in-process HTTP API, if it is running.
+func (a *App) StopAPIServer() error {
+	a.apiServerMu.Lock()
+	defer a.apiServerMu.Unlock()
+
+	if a.apiServer == nil {
+		return nil
+	}
+	err := a.apiServer.Close()
+	a.apiServer = nil
+	a.apiServerDto = APIServerDto{}
+	return err
+}
 
-	// Return the relative path for use in markdown (use forward slashes for URL compatibility)
-	return "./assets/" + filename, nil
+// GetAPIServer returns the URL and token of the running HTTP API, or an
+// empty URL when it is stopped.
+func (a *App) GetAPIServer() APIServerDto {
+	a.apiServerMu.Lock()
+	defer a.apiServerMu.Unlock()
+	return a.apiServerDto
 }
@@ -0,0 +1,170 @@
+// Package capture appends quick notes to a journal, as used by
+// 'glog capture'.
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"glog/db"
+	"glog/domain"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Options controls where captured blocks are placed.
+type Options struct {
+	// Indent is added to the indent of every captured block.
+	Indent int
+	// Under is the text of a heading block the captured blocks are nested
+	// under, e.g. "Meetings" matches "## Meetings". The heading is created
+	// at the end of the journal when no block matches.
+	Under string
+	// Scheduled adds '/scheduled YYYY-MM-DD' to the top-level captured
+	// blocks unless it is the zero time.
+	Scheduled time.Time
+}
+
+// ParseBlocks turns text into blocks, one per non-empty line. Leading
+// "- " and "* " bullets are removed and the indentation of the lines
+// (a tab or two spaces per level) is kept relative to the least indented line.
+func ParseBlocks(text string) []*domain.Block {
+	var blocks []*domain.Block
+	minIndent := -1
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		indent := 0
+		spaces := 0
+		for len(line) > 0 && (line[0] == ' ' || line[0] == '\t') {
+			if line[0] == '\t' {
+				indent++
+			} else {
+				spaces++
+			}
+			line = line[1:]
+		}
+		indent += spaces / 2
+
+		for _, bullet := range []string{"- ", "* "} {
+			if strings.HasPrefix(line, bullet) {
+				line = line[len(bullet):]
+				break
+			}
+		}
+
+		if minIndent < 0 || indent < minIndent {
+			minIndent = indent
+		}
+		blocks = append(blocks, &domain.Block{
+			ID:      domain.BlockID(uuid.New()),
+			Content: strings.TrimRight(line, " \t"),
+			Indent:  indent,
+		})
+	}
+
+	for _, block := range blocks {
+		block.Indent -= minIndent
+	}
+	return blocks
+}
+
+// Apply inserts blocks, as returned by ParseBlocks, into doc.
+func Apply(doc *domain.Document, blocks []*domain.Block, opts Options) {
+	if len(blocks) == 0 {
+		return
+	}
+
+	// A new journal starts with a single empty block to type into
+	if len(doc.Blocks) == 1 && strings.TrimSpace(doc.Blocks[0].Content) == "" {
+		doc.Blocks = nil
+	}
+
+	if !opts.Scheduled.IsZero() {
+		marker := fmt.Sprintf("/scheduled %s", opts.Scheduled.Format("2006-01-02"))
+		for _, block := range blocks {
+			if block.Indent == 0 {
+				block.Content = strings.TrimSpace(block.Content + " " + marker)
+			}
+		}
+	}
+
+	pos := len(doc.Blocks)
+	base := opts.Indent
+	if opts.Under != "" {
+		heading := findHeading(doc, opts.Under)
+		if heading < 0 {
+			doc.Blocks = append(doc.Blocks, &domain.Block{
+				ID:      domain.BlockID(uuid.New()),
+				Content: opts.Under,
+				Indent:  0,
+			})
+			heading = len(doc.Blocks) - 1
+		}
+
+		// Append after the last descendant of the heading
+		parent := doc.Blocks[heading]
+		pos = heading + 1
+		for pos < len(doc.Blocks) && doc.Blocks[pos].Indent > parent.Indent {
+			pos++
+		}
+		base += parent.Indent + 1
+	}
+
+	// Blocks can be at most one level deeper than the block before them
+	maxIndent := 0
+	if pos > 0 {
+		maxIndent = doc.Blocks[pos-1].Indent + 1
+	}
+	if base > maxIndent {
+		base = maxIndent
+	}
+
+	for _, block := range blocks {
+		block.Indent += base
+	}
+
+	inserted := make([]*domain.Block, 0, len(doc.Blocks)+len(blocks))
+	inserted = append(inserted, doc.Blocks[:pos]...)
+	inserted = append(inserted, blocks...)
+	inserted = append(inserted, doc.Blocks[pos:]...)
+	doc.Blocks = inserted
+}
+
+// findHeading returns the index of the first block whose text, without
+// markdown heading markers, equals text (case-insensitive), or -1.
+func findHeading(doc *domain.Document, text string) int {
+	want := headingText(text)
+	for i, block := range doc.Blocks {
+		if headingText(block.Content) == want {
+			return i
+		}
+	}
+	return -1
+}
+
+func headingText(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimLeft(s, "#")
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// ErrNothingToCapture is returned by Journal when text has no content.
+var ErrNothingToCapture = errors.New("nothing to capture")
+
+// Journal appends text to the journal of the day of now, creating the
+// journal if needed, in a single transaction.
+func Journal(store *db.DocumentStore, now time.Time, text string, opts Options) (*domain.Document, error) {
+	blocks := ParseBlocks(text)
+	if len(blocks) == 0 {
+		return nil, ErrNothingToCapture
+	}
+
+	return store.UpdateJournal(now, func(doc *domain.Document) error {
+		Apply(doc, blocks, opts)
+		return nil
+	})
+}
@@ -0,0 +1,114 @@
+package capture
+
+import (
+	"glog/domain"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func block(content string, indent int) *domain.Block {
+	return &domain.Block{ID: domain.BlockID(uuid.New()), Content: content, Indent: indent}
+}
+
+type wantBlock struct {
+	content string
+	indent  int
+}
+
+func checkBlocks(t *testing.T, got []*domain.Block, want []wantBlock) {
+	t.Helper()
+	if len(got) != len(want) {
+		for _, b := range got {
+			t.Logf("  %d %q", b.Indent, b.Content)
+		}
+		t.Fatalf("got %d blocks, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Content != w.content || got[i].Indent != w.indent {
+			t.Errorf("block %d = (%q, %d), want (%q, %d)", i, got[i].Content, got[i].Indent, w.content, w.indent)
+		}
+	}
+}
+
+func TestParseBlocks(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []wantBlock
+	}{
+		{"single line", "Call Alice", []wantBlock{{"Call Alice", 0}}},
+		{"skips blank lines", "one\n\n  \ntwo\n", []wantBlock{{"one", 0}, {"two", 0}}},
+		{"bullets and nesting", "- parent\n  - child\n\t\t- grandchild", []wantBlock{{"parent", 0}, {"child", 1}, {"grandchild", 2}}},
+		{"relative to least indented", "    - a\n      - b", []wantBlock{{"a", 0}, {"b", 1}}},
+		{"crlf", "a\r\nb\r\n", []wantBlock{{"a", 0}, {"b", 0}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkBlocks(t, ParseBlocks(tt.text), tt.want)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	due := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		blocks []*domain.Block
+		text   string
+		opts   Options
+		want   []wantBlock
+	}{
+		{
+			name:   "replaces empty block of a new journal",
+			blocks: []*domain.Block{block("", 0)},
+			text:   "idea",
+			want:   []wantBlock{{"idea", 0}},
+		},
+		{
+			name:   "appends at the end",
+			blocks: []*domain.Block{block("first", 0)},
+			text:   "- a\n  - b",
+			want:   []wantBlock{{"first", 0}, {"a", 0}, {"b", 1}},
+		},
+		{
+			name:   "indent is clamped to one level below the previous block",
+			blocks: []*domain.Block{block("first", 0)},
+			text:   "deep",
+			opts:   Options{Indent: 3},
+			want:   []wantBlock{{"first", 0}, {"deep", 1}},
+		},
+		{
+			name:   "nests after the children of a heading",
+			blocks: []*domain.Block{block("## Meetings", 0), block("standup", 1), block("notes", 2), block("## Ideas", 0)},
+			text:   "retro",
+			opts:   Options{Under: "meetings"},
+			want:   []wantBlock{{"## Meetings", 0}, {"standup", 1}, {"notes", 2}, {"retro", 1}, {"## Ideas", 0}},
+		},
+		{
+			name:   "creates a missing heading",
+			blocks: []*domain.Block{block("first", 0)},
+			text:   "buy milk",
+			opts:   Options{Under: "Errands"},
+			want:   []wantBlock{{"first", 0}, {"Errands", 0}, {"buy milk", 1}},
+		},
+		{
+			name:   "schedules top-level blocks",
+			blocks: []*domain.Block{block("", 0)},
+			text:   "- pay rent\n  - by transfer",
+			opts:   Options{Scheduled: due},
+			want:   []wantBlock{{"pay rent /scheduled 2026-10-20", 0}, {"by transfer", 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &domain.Document{Blocks: tt.blocks}
+			Apply(doc, ParseBlocks(tt.text), tt.opts)
+			checkBlocks(t, doc.Blocks, tt.want)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"glog/capture"
+	"glog/db"
+	"glog/domain"
+	"glog/ipc"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const captureUsage = `Usage: glog capture [flags] [text]
+
+Appends blocks to today's journal, creating the journal if needed. The
+text is read from stdin when no argument (or "-") is given; every
+non-empty line becomes a block and "- " bullets with leading indentation
+become nested blocks.
+
+Examples:
+  glog capture "Call Alice about the offer"
+  glog capture --under Meetings --scheduled 2026-10-20 "Prepare retro"
+  pbpaste | glog capture --indent 1
+
+Flags:
+`
+
+func runCapture(args []string) error {
+	fs, dbPath := newFlagSet("capture", captureUsage)
+	asJSON := jsonFlag(fs)
+	indent := fs.Int("indent", 0, "Indent level of the captured blocks")
+	under := fs.String("under", "", "Nest under the block with this heading text (created if missing)")
+	scheduled := fs.String("scheduled", "", "Schedule the captured blocks for a date (YYYY-MM-DD)")
+	parseArgs(fs, args)
+
+	if *indent < 0 {
+		return errors.New("--indent cannot be negative")
+	}
+
+	opts := capture.Options{Indent: *indent, Under: strings.TrimSpace(*under)}
+	if *scheduled != "" {
+		date, err := time.Parse("2006-01-02", *scheduled)
+		if err != nil {
+			return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", *scheduled)
+		}
+		opts.Scheduled = date
+	}
+
+	text := strings.Join(fs.Args(), " ")
+	if fs.NArg() == 0 || text == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+		text = string(data)
+	}
+
+	blocks := capture.ParseBlocks(text)
+	if len(blocks) == 0 {
+		fs.Usage()
+		return capture.ErrNothingToCapture
+	}
+
+	store, err := openStore(*dbPath, false)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var doc *domain.Document
+	switch s := store.(type) {
+	case *ipc.Client:
+		doc, err = s.Capture(time.Now(), text, opts)
+	case *db.DocumentStore:
+		doc, err = capture.Journal(s, time.Now(), text, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(toDocumentJSON(doc))
+	}
+	fmt.Printf("Captured %d block(s) in %s\n", len(blocks), doc.Title)
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"glog/export/site"
+	"path/filepath"
+)
+
+const exportHTMLUsage = `Usage: glog export-html [flags]
+
+Writes a static, read-only HTML site: one page per exported document with
+its block outline and backlinks, an index page with client-side search,
+and the assets referenced by the exported pages.
+
+Flags:
+`
+
+func runExportHTML(args []string) error {
+	fs, dbPath := newFlagSet("export-html", exportHTMLUsage)
+	out := fs.String("out", "./site", "Output directory")
+	assets := fs.String("assets", "", "Assets directory (default: 'assets' next to the database)")
+	title := fs.String("title", "glog", "Site title")
+	public := fs.Bool("public", false, "Only export pages with a 'public:: true' property")
+	tag := fs.String("tag", "", "Only export pages with this tag")
+	journals := fs.Bool("journals", false, "Include journals")
+	_ = fs.Parse(args)
+
+	if *assets == "" {
+		*assets = filepath.Join(filepath.Dir(*dbPath), "assets")
+	}
+
+	store, err := openStore(*dbPath, true)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	result, err := site.Export(store, site.Options{
+		OutDir:     *out,
+		AssetsDir:  *assets,
+		SiteTitle:  *title,
+		PublicOnly: *public,
+		Tag:        *tag,
+		Journals:   *journals,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d pages and %d assets to %s\n", result.Pages, result.Assets, *out)
+	if len(result.Errors) > 0 {
+		fmt.Printf("Errors: %d\n", len(result.Errors))
+		for _, e := range result.Errors {
+			fmt.Printf("  - %v\n", e)
+		}
+		return fmt.Errorf("export finished with %d errors", len(result.Errors))
+	}
+	return nil
+}
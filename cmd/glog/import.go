@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"glog/import/common"
+	"glog/import/logseq"
+	"glog/import/obsidian"
+	"glog/import/opml"
+	"glog/import/roam"
+)
+
+const importUsage = `Usage: glog import <source> [flags] <path>
+
+Imports notes from another tool. Sources:
+  logseq     Logseq graph directory (journals/ and pages/)
+  obsidian   Obsidian vault directory
+  roam       Roam Research JSON export file
+  opml       One or more OPML files, one page each
+
+Documents whose titles already exist get a suffix ("My Page (2)").
+
+Flags:
+`
+
+type importResultJSON struct {
+	JournalsImported int              `json:"journals_imported"`
+	PagesImported    int              `json:"pages_imported"`
+	AssetsCopied     int              `json:"assets_copied"`
+	Skipped          int              `json:"skipped"`
+	Renamed          []renameInfoJSON `json:"renamed"`
+	Errors           []string         `json:"errors"`
+}
+
+type renameInfoJSON struct {
+	OriginalTitle string `json:"original_title"`
+	NewTitle      string `json:"new_title"`
+}
+
+func runImport(args []string) error {
+	if len(args) < 1 || args[0] == "--help" || args[0] == "-h" {
+		fmt.Print(importUsage)
+		return errors.New("missing required argument <source>")
+	}
+	source := args[0]
+
+	fs, dbPath := newFlagSet("import "+source, importUsage)
+	asJSON := jsonFlag(fs)
+	journalsOnly := fs.Bool("journals-only", false, "Import only journals, skip pages")
+	pagesOnly := fs.Bool("pages-only", false, "Import only pages, skip journals")
+	dryRun := fs.Bool("dry-run", false, "Preview import without writing")
+	verbose := fs.Bool("verbose", false, "Show detailed progress")
+	dailyFormat := fs.String("daily-format", obsidian.DefaultDailyNoteFormat, "obsidian: Go time layout of daily note filenames")
+	dailyFolder := fs.String("daily-folder", "", "obsidian: vault folder holding daily notes")
+	assets := fs.String("assets", "", "obsidian: directory attachments are copied to")
+	parseArgs(fs, args[1:])
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return errors.New("missing required argument <path>")
+	}
+	if *journalsOnly && *pagesOnly {
+		return errors.New("--journals-only and --pages-only cannot be used together")
+	}
+
+	// Progress output would corrupt the JSON document
+	if *asJSON {
+		*verbose = false
+	}
+
+	var result *common.ImportResult
+	var err error
+	switch source {
+	case "logseq":
+		result, err = logseq.Import(logseq.ImportOptions{
+			LogseqPath:   fs.Arg(0),
+			DBPath:       *dbPath,
+			JournalsOnly: *journalsOnly,
+			PagesOnly:    *pagesOnly,
+			DryRun:       *dryRun,
+			Verbose:      *verbose,
+		})
+	case "obsidian":
+		result, err = obsidian.Import(obsidian.ImportOptions{
+			VaultPath:        fs.Arg(0),
+			DBPath:           *dbPath,
+			AssetsDir:        *assets,
+			DailyNoteFormat:  *dailyFormat,
+			DailyNotesFolder: *dailyFolder,
+			JournalsOnly:     *journalsOnly,
+			PagesOnly:        *pagesOnly,
+			DryRun:           *dryRun,
+			Verbose:          *verbose,
+		})
+	case "roam":
+		result, err = roam.Import(roam.ImportOptions{
+			ExportPath:   fs.Arg(0),
+			DBPath:       *dbPath,
+			JournalsOnly: *journalsOnly,
+			PagesOnly:    *pagesOnly,
+			DryRun:       *dryRun,
+			Verbose:      *verbose,
+		})
+	case "opml":
+		result, err = opml.Import(opml.ImportOptions{
+			Paths:   fs.Args(),
+			DBPath:  *dbPath,
+			DryRun:  *dryRun,
+			Verbose: *verbose,
+		})
+	default:
+		return fmt.Errorf("unknown import source %q (expected logseq, obsidian, roam or opml)", source)
+	}
+	if err != nil {
+		return fmt.Errorf("import failed: %w", err)
+	}
+
+	if *asJSON {
+		out := importResultJSON{
+			JournalsImported: result.JournalsImported,
+			PagesImported:    result.PagesImported,
+			AssetsCopied:     result.AssetsCopied,
+			Skipped:          result.Skipped,
+			Renamed:          make([]renameInfoJSON, 0, len(result.Renamed)),
+			Errors:           make([]string, 0, len(result.Errors)),
+		}
+		for _, r := range result.Renamed {
+			out.Renamed = append(out.Renamed, renameInfoJSON{OriginalTitle: r.OriginalTitle, NewTitle: r.NewTitle})
+		}
+		for _, e := range result.Errors {
+			out.Errors = append(out.Errors, e.Error())
+		}
+		if err := printJSON(out); err != nil {
+			return err
+		}
+	} else {
+		if *dryRun {
+			fmt.Println("Dry run, nothing was written.")
+		}
+		fmt.Printf("Journals imported: %d\n", result.JournalsImported)
+		fmt.Printf("Pages imported:    %d\n", result.PagesImported)
+		if result.AssetsCopied > 0 {
+			fmt.Printf("Assets copied:     %d\n", result.AssetsCopied)
+		}
+		for _, r := range result.Renamed {
+			fmt.Printf("Renamed: %q -> %q\n", r.OriginalTitle, r.NewTitle)
+		}
+		for _, e := range result.Errors {
+			fmt.Printf("Error: %v\n", e)
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("import finished with %d errors", len(result.Errors))
+	}
+	return nil
+}
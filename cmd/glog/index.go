@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"glog/db"
+	"glog/domain"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const reindexUsage = `Usage: glog reindex [flags]
+
+Rebuilds the search index from scratch.
+
+Flags:
+`
+
+func runReindex(args []string) error {
+	fs, dbPath := newFlagSet("reindex", reindexUsage)
+	parseArgs(fs, args)
+
+	store, err := openStore(*dbPath, false)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	start := time.Now()
+	if err := store.ReindexSearch(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Search index rebuilt in %s\n", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+const verifyUsage = `Usage: glog verify [flags]
+
+Checks that the search index matches the database and reindexes only the
+documents that are missing, stale or orphaned, without blocking the app.
+Exits with status 1 if the index needs a full rebuild with glog reindex.
+
+Flags:
+`
+
+type verifyJSON struct {
+	Documents int      `json:"documents"`
+	Entries   int      `json:"entries"`
+	Missing   []string `json:"missing"`
+	Stale     []string `json:"stale"`
+	Orphaned  []string `json:"orphaned"`
+	Repaired  int      `json:"repaired"`
+}
+
+func runVerify(args []string) error {
+	fs, dbPath := newFlagSet("verify", verifyUsage)
+	asJSON := jsonFlag(fs)
+	parseArgs(fs, args)
+
+	store, err := openStore(*dbPath, false)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	result, err := store.VerifySearchIndex()
+	if errors.Is(err, db.ErrReindexRequired) {
+		return fmt.Errorf("%w, run glog reindex", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	ids := func(ids []domain.DocumentID) []string {
+		result := make([]string, len(ids))
+		for i, id := range ids {
+			result[i] = uuid.UUID(id).String()
+		}
+		return result
+	}
+	out := verifyJSON{
+		Documents: result.Documents,
+		Entries:   result.Entries,
+		Missing:   ids(result.Missing),
+		Stale:     ids(result.Stale),
+		Orphaned:  ids(result.Orphaned),
+		Repaired:  result.Repaired,
+	}
+	if *asJSON {
+		return printJSON(out)
+	}
+
+	fmt.Printf("Checked %d documents and %d index entries\n", out.Documents, out.Entries)
+	for _, group := range []struct {
+		name string
+		ids  []string
+	}{{"Missing", out.Missing}, {"Stale", out.Stale}, {"Orphaned", out.Orphaned}} {
+		fmt.Printf("%-9s %d\n", group.name+":", len(group.ids))
+		for _, id := range group.ids {
+			fmt.Printf("  %s\n", id)
+		}
+	}
+	if !result.Consistent() {
+		fmt.Printf("Reindexed %d documents\n", out.Repaired)
+	}
+	return nil
+}
+
+const analysisUsage = `Usage: glog analysis [flags]
+
+Shows how the search index analyzes text, or changes it when any of
+--lang, --fold or --cjk is given. Changing the analysis rebuilds the
+search index. Supported languages: ` + "%s" + `
+
+Examples:
+  glog analysis --lang en,es --fold
+  glog analysis --lang "" --fold=false --cjk=false
+
+Flags:
+`
+
+func runAnalysis(args []string) error {
+	fs, dbPath := newFlagSet("analysis", fmt.Sprintf(analysisUsage, strings.Join(db.SearchLanguages(), ", ")))
+	lang := fs.String("lang", "", "Comma-separated languages to stem, e.g. en,es")
+	fold := fs.Bool("fold", false, "Fold accented letters to ASCII")
+	cjkBigrams := fs.Bool("cjk", false, "Index Chinese, Japanese and Korean text as bigrams")
+	asJSON := jsonFlag(fs)
+	parseArgs(fs, args)
+
+	change := false
+	fs.Visit(func(f *flag.Flag) {
+		change = change || f.Name == "lang" || f.Name == "fold" || f.Name == "cjk"
+	})
+
+	store, err := openStore(*dbPath, !change)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	analysis, err := store.GetSearchAnalysis()
+	if err != nil {
+		return err
+	}
+	if change {
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "lang":
+				analysis.Languages = nil
+				for _, code := range strings.Split(*lang, ",") {
+					if code = strings.TrimSpace(code); code != "" {
+						analysis.Languages = append(analysis.Languages, code)
+					}
+				}
+			case "fold":
+				analysis.FoldAccents = *fold
+			case "cjk":
+				analysis.CJK = *cjkBigrams
+			}
+		})
+		if err := store.SetSearchAnalysis(analysis); err != nil {
+			return err
+		}
+		if analysis, err = store.GetSearchAnalysis(); err != nil {
+			return err
+		}
+	}
+
+	if *asJSON {
+		return printJSON(analysis)
+	}
+	languages := strings.Join(analysis.Languages, ", ")
+	if languages == "" {
+		languages = "none"
+	}
+	fmt.Printf("Stemming:     %s\n", languages)
+	fmt.Printf("Fold accents: %t\n", analysis.FoldAccents)
+	fmt.Printf("CJK bigrams:  %t\n", analysis.CJK)
+	if change {
+		fmt.Println("The search index is rebuilt with the new analysis")
+	}
+	return nil
+}
+
+const healthUsage = `Usage: glog health [flags]
+
+Reports the health of the search index. Exits with status 1 if the index
+is unhealthy, so it can be used from cron or monitoring scripts.
+
+Flags:
+`
+
+type healthJSON struct {
+	IsHealthy          bool   `json:"isHealthy"`
+	FailedDocuments    int    `json:"failedDocuments"`
+	LastHealthCheck    string `json:"lastHealthCheck"`
+	RequiresReindex    bool   `json:"requiresReindex"`
+	HealthCheckMessage string `json:"healthCheckMessage"`
+	QueueDepth         int    `json:"queueDepth"`
+	QueueLagMs         int64  `json:"queueLagMs"`
+}
+
+func runHealth(args []string) error {
+	fs, dbPath := newFlagSet("health", healthUsage)
+	asJSON := jsonFlag(fs)
+	parseArgs(fs, args)
+
+	store, err := openStore(*dbPath, true)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	health := store.GetIndexHealth()
+	result := healthJSON{
+		IsHealthy:          health.IsHealthy,
+		FailedDocuments:    health.FailedDocuments,
+		LastHealthCheck:    health.LastHealthCheck.Format(time.RFC3339),
+		RequiresReindex:    health.RequiresReindex,
+		HealthCheckMessage: health.HealthCheckMessage,
+		QueueDepth:         health.QueueDepth,
+		QueueLagMs:         health.QueueLag.Milliseconds(),
+	}
+
+	if *asJSON {
+		if err := printJSON(result); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Healthy:          %t\n", result.IsHealthy)
+		fmt.Printf("Failed documents: %d\n", result.FailedDocuments)
+		fmt.Printf("Requires reindex: %t\n", result.RequiresReindex)
+		fmt.Printf("Queued documents: %d (oldest %s)\n", result.QueueDepth, health.QueueLag.Round(time.Millisecond))
+		fmt.Printf("Message:          %s\n", result.HealthCheckMessage)
+	}
+
+	if !health.IsHealthy {
+		return errors.New("search index is unhealthy")
+	}
+	return nil
+}
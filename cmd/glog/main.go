@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+const usage = `glog - command-line tools for a glog database
+
+Usage:
+  glog <command> [flags] [arguments]
+
+Commands:
+%s
+Every command accepts --db <path> to select the database (default: ./glog.db).
+Query commands accept --json for machine-readable output.
+Run 'glog <command> --help' for the flags of a command.
+`
+
+// command is a glog subcommand.
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"backlinks":   {"List documents linking to a page", runBacklinks},
+	"analysis":    {"Show or change how the search index analyzes text", runAnalysis},
+	"capture":     {"Append blocks to today's journal", runCapture},
+	"cat":         {"Print a document by title or ID", runCat},
+	"dump":        {"Write every document and index entry as JSON Lines", runDump},
+	"export-html": {"Export pages as a static HTML site", runExportHTML},
+	"health":      {"Report the health of the search index", runHealth},
+	"import":      {"Import from Logseq, Obsidian, Roam or OPML", runImport},
+	"journal":     {"Print the journal of a day (default: today)", runJournal},
+	"ls":          {"List documents", runLs},
+	"reindex":     {"Rebuild the search index", runReindex},
+	"restore":     {"Rebuild a fresh database from a dump", runRestore},
+	"search":      {"Full-text search across documents", runSearch},
+	"serve":       {"Serve the database as a REST/JSON API", runServe},
+	"tasks":       {"List upcoming scheduled tasks", runTasks},
+	"verify":      {"Check the search index and repair what differs", runVerify},
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "--help" || os.Args[1] == "-h" {
+		printUsage()
+		if len(os.Args) < 2 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	name := os.Args[1]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", name)
+		printUsage()
+		os.Exit(1)
+	}
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := ""
+	for _, name := range names {
+		list += fmt.Sprintf("  %-12s %s\n", name, commands[name].summary)
+	}
+	fmt.Printf(usage, list)
+}
+
+// newFlagSet creates the flag set of a command with the shared --db flag.
+func newFlagSet(name string, usage string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	dbPath := fs.String("db", "./glog.db", "Path to glog database")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	return fs, dbPath
+}
+
+// parseArgs parses flags like fs.Parse but also accepts flags after
+// positional arguments, so 'glog cat "My Page" --json' works.
+func parseArgs(fs *flag.FlagSet, args []string) {
+	var positional []string
+	for {
+		_ = fs.Parse(args)
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	_ = fs.Parse(append([]string{"--"}, positional...))
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"glog/db"
+	"glog/domain"
+	"glog/ipc"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const searchUsage = `Usage: glog search [flags] <query>
+
+Full-text search across all documents. Quote phrases for exact matches.
+Filters: title:word exact:word -excluded "a OR b" is:journal is:page
+has:task links:[[Page]] before:YYYY-MM-DD after:YYYY-MM-DD.
+
+Flags:
+`
+
+func runSearch(args []string) error {
+	fs, dbPath := newFlagSet("search", searchUsage)
+	asJSON := jsonFlag(fs)
+	parseArgs(fs, args)
+
+	query := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		fs.Usage()
+		return errors.New("missing required argument <query>")
+	}
+
+	store, err := openStore(*dbPath, true)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	ids, err := store.Search(query)
+	if err != nil {
+		return err
+	}
+
+	results := make([]summaryJSON, 0, len(ids))
+	for _, id := range ids {
+		doc, err := store.GetDocument(id)
+		if err != nil {
+			// Skip hits for documents that no longer exist
+			continue
+		}
+		results = append(results, summaryJSON{
+			Id:        doc.ID.String(),
+			Title:     doc.Title,
+			Date:      doc.Date.Format(time.RFC3339),
+			IsJournal: doc.IsJournal,
+		})
+	}
+
+	if *asJSON {
+		return printJSON(results)
+	}
+	for _, r := range results {
+		fmt.Printf("%s  %s\n", r.Id, r.Title)
+	}
+	return nil
+}
+
+const catUsage = `Usage: glog cat [flags] <title|id>
+
+Prints a document as a markdown outline. The argument is matched as a
+document ID first and as a title (case-insensitive) otherwise.
+
+Flags:
+`
+
+func runCat(args []string) error {
+	fs, dbPath := newFlagSet("cat", catUsage)
+	asJSON := jsonFlag(fs)
+	parseArgs(fs, args)
+
+	ref := strings.Join(fs.Args(), " ")
+	if ref == "" {
+		fs.Usage()
+		return errors.New("missing required argument <title|id>")
+	}
+
+	store, err := openStore(*dbPath, true)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	doc, err := findDocument(store, ref)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(toDocumentJSON(doc))
+	}
+	printDocument(doc)
+	return nil
+}
+
+// findDocument resolves a document by ID or, failing that, by title.
+func findDocument(store ipc.Store, ref string) (*domain.Document, error) {
+	if id, err := uuid.Parse(ref); err == nil {
+		doc, err := store.GetDocument(domain.DocumentID(id))
+		if err == nil || !errors.Is(err, db.ErrDocumentNotFound) {
+			return doc, err
+		}
+	}
+
+	doc, err := store.GetDocumentByTitle(ref)
+	if errors.Is(err, db.ErrDocumentNotFound) {
+		return nil, fmt.Errorf("no document with ID or title %q", ref)
+	}
+	return doc, err
+}
+
+const lsUsage = `Usage: glog ls [flags]
+
+Lists documents, sorted by title.
+
+Flags:
+`
+
+func runLs(args []string) error {
+	fs, dbPath := newFlagSet("ls", lsUsage)
+	asJSON := jsonFlag(fs)
+	journals := fs.Bool("journals", false, "Only list journals")
+	pages := fs.Bool("pages", false, "Only list pages")
+	byDate := fs.Bool("date", false, "Sort by date, newest first")
+	parseArgs(fs, args)
+
+	if *journals && *pages {
+		return errors.New("--journals and --pages cannot be used together")
+	}
+
+	store, err := openStore(*dbPath, true)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var docs []*domain.Document
+	err = store.ForEachDocument(func(doc *domain.Document) error {
+		if (*journals && !doc.IsJournal) || (*pages && doc.IsJournal) {
+			return nil
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		if *byDate {
+			return docs[i].Date.After(docs[j].Date)
+		}
+		return strings.ToLower(docs[i].Title) < strings.ToLower(docs[j].Title)
+	})
+
+	results := make([]summaryJSON, len(docs))
+	for i, doc := range docs {
+		results[i] = summaryJSON{
+			Id:        doc.ID.String(),
+			Title:     doc.Title,
+			Date:      doc.Date.Format(time.RFC3339),
+			IsJournal: doc.IsJournal,
+		}
+	}
+
+	if *asJSON {
+		return printJSON(results)
+	}
+	for _, r := range results {
+		fmt.Printf("%s  %s  %s\n", r.Id, r.Date[:10], r.Title)
+	}
+	return nil
+}
+
+const journalUsage = `Usage: glog journal [flags] [YYYY-MM-DD]
+
+Prints the journal of the given day (default: today).
+
+Flags:
+`
+
+func runJournal(args []string) error {
+	fs, dbPath := newFlagSet("journal", journalUsage)
+	asJSON := jsonFlag(fs)
+	parseArgs(fs, args)
+
+	day := db.JournalDay(time.Now())
+	if fs.NArg() > 0 {
+		d, err := time.Parse("2006-01-02", fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", fs.Arg(0))
+		}
+		day = db.JournalDay(d)
+	}
+
+	store, err := openStore(*dbPath, true)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	docs, err := store.LoadJournals(day, day)
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return fmt.Errorf("no journal for %s", day.Format("2006-01-02"))
+	}
+
+	if *asJSON {
+		return printJSON(toDocumentJSON(docs[0]))
+	}
+	printDocument(docs[0])
+	return nil
+}
+
+const tasksUsage = `Usage: glog tasks [flags]
+
+Lists open scheduled tasks (blocks with '/scheduled YYYY-MM-DD' and
+without '/DONE') from today onwards.
+
+Flags:
+`
+
+type taskJSON struct {
+	Id          string `json:"id"`
+	Description string `json:"description"`
+	DueDate     string `json:"due_date"` // RFC 3339 format
+	Title       string `json:"title"`
+	BlockId     string `json:"block_id"`
+	DocId       string `json:"doc_id"`
+}
+
+func runTasks(args []string) error {
+	fs, dbPath := newFlagSet("tasks", tasksUsage)
+	asJSON := jsonFlag(fs)
+	days := fs.Int("days", 7, "Number of days to look ahead, including today")
+	parseArgs(fs, args)
+
+	store, err := openStore(*dbPath, true)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	tasks, err := store.GetScheduledTasks(time.Now(), *days)
+	if err != nil {
+		return err
+	}
+
+	results := make([]taskJSON, 0, len(tasks))
+	for _, task := range tasks {
+		doc, err := store.GetDocument(task.DocID)
+		if err != nil {
+			continue
+		}
+
+		var content string
+		for _, block := range doc.Blocks {
+			if block.ID == task.BlockID {
+				content = block.Content
+				break
+			}
+		}
+		if db.IsDone(content) {
+			continue
+		}
+
+		results = append(results, taskJSON{
+			Id:          task.ID.String(),
+			Description: content,
+			DueDate:     task.Time.Format(time.RFC3339),
+			Title:       doc.Title,
+			BlockId:     task.BlockID.String(),
+			DocId:       task.DocID.String(),
+		})
+	}
+
+	if *asJSON {
+		return printJSON(results)
+	}
+	for _, r := range results {
+		fmt.Printf("%s  %s  (%s)\n", r.DueDate[:10], firstLine(r.Description), r.Title)
+	}
+	return nil
+}
+
+const backlinksUsage = `Usage: glog backlinks [flags] <title>
+
+Lists the documents linking to a page with [[title]], with the blocks
+that contain the link.
+
+Flags:
+`
+
+type backlinkJSON struct {
+	Id     string      `json:"id"`
+	Title  string      `json:"title"`
+	Blocks []blockJSON `json:"blocks"`
+}
+
+func runBacklinks(args []string) error {
+	fs, dbPath := newFlagSet("backlinks", backlinksUsage)
+	asJSON := jsonFlag(fs)
+	parseArgs(fs, args)
+
+	title := strings.Join(fs.Args(), " ")
+	if title == "" {
+		fs.Usage()
+		return errors.New("missing required argument <title>")
+	}
+
+	store, err := openStore(*dbPath, true)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	ids, err := store.GetReferences(title)
+	if err != nil {
+		return err
+	}
+
+	needle := "[[" + strings.ToLower(title) + "]]"
+	results := make([]backlinkJSON, 0, len(ids))
+	for _, id := range ids {
+		doc, err := store.GetDocument(id)
+		if err != nil {
+			continue
+		}
+
+		ref := backlinkJSON{Id: doc.ID.String(), Title: doc.Title, Blocks: []blockJSON{}}
+		for _, block := range doc.Blocks {
+			if strings.Contains(strings.ToLower(block.Content), needle) {
+				ref.Blocks = append(ref.Blocks, blockJSON{Id: block.ID.String(), Content: block.Content, Indent: block.Indent})
+			}
+		}
+		results = append(results, ref)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return strings.ToLower(results[i].Title) < strings.ToLower(results[j].Title)
+	})
+
+	if *asJSON {
+		return printJSON(results)
+	}
+	for _, r := range results {
+		fmt.Println(r.Title)
+		for _, block := range r.Blocks {
+			fmt.Printf("  - %s\n", firstLine(block.Content))
+		}
+	}
+	return nil
+}
+
+func firstLine(content string) string {
+	line, _, _ := strings.Cut(content, "\n")
+	return line
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"glog/db"
+	"glog/domain"
+	"glog/ipc"
+	"os"
+	"strings"
+	"time"
+)
+
+// jsonFlag adds the shared --json flag to a command's flag set.
+func jsonFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("json", false, "Print machine-readable JSON instead of text")
+}
+
+// printJSON writes v to stdout as indented JSON.
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// openStore opens the database for a command, or connects to the glog
+// app when it is running on the database. Commands that only read open
+// the database read-only so they can run side by side.
+func openStore(path string, readOnly bool) (ipc.Store, error) {
+	store, err := ipc.Open(path, db.Options{Timeout: db.DefaultLockTimeout, ReadOnly: readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return store, nil
+}
+
+type blockJSON struct {
+	Id      string `json:"id"`
+	Content string `json:"content"`
+	Indent  int    `json:"indent"`
+}
+
+type documentJSON struct {
+	Id        string      `json:"id"`
+	Title     string      `json:"title"`
+	Date      string      `json:"date"` // RFC 3339 format
+	IsJournal bool        `json:"is_journal"`
+	Blocks    []blockJSON `json:"blocks"`
+}
+
+type summaryJSON struct {
+	Id        string `json:"id"`
+	Title     string `json:"title"`
+	Date      string `json:"date"` // RFC 3339 format
+	IsJournal bool   `json:"is_journal,omitempty"`
+}
+
+func toDocumentJSON(doc *domain.Document) documentJSON {
+	blocks := make([]blockJSON, len(doc.Blocks))
+	for i, b := range doc.Blocks {
+		blocks[i] = blockJSON{
+			Id:      b.ID.String(),
+			Content: b.Content,
+			Indent:  b.Indent,
+		}
+	}
+
+	return documentJSON{
+		Id:        doc.ID.String(),
+		Title:     doc.Title,
+		Date:      doc.Date.Format(time.RFC3339),
+		IsJournal: doc.IsJournal,
+		Blocks:    blocks,
+	}
+}
+
+// printDocument prints a document as a markdown outline.
+func printDocument(doc *domain.Document) {
+	fmt.Printf("# %s\n", doc.Title)
+	fmt.Printf("%s\n\n", doc.Date.Format("Monday, January 2, 2006"))
+	for _, block := range doc.Blocks {
+		printBlock(block.Content, block.Indent)
+	}
+}
+
+// printBlock prints one block as a bullet; continuation lines of
+// multi-line blocks are aligned with the bullet text.
+func printBlock(content string, indent int) {
+	prefix := strings.Repeat("  ", indent)
+	lines := strings.Split(content, "\n")
+	fmt.Printf("%s- %s\n", prefix, lines[0])
+	for _, line := range lines[1:] {
+		fmt.Printf("%s  %s\n", prefix, line)
+	}
+}
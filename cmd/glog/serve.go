@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"glog/api"
+	"glog/db"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+)
+
+const serveUsage = `Usage: glog serve [flags]
+
+Serves the database as a REST/JSON API on a loopback address, for
+scripts, editor plugins and browser extensions. Requests must carry
+"Authorization: Bearer <token>"; the API is described at
+/api/openapi.json.
+
+The token is taken from --token, then from $GLOG_API_TOKEN; otherwise a
+new one is generated and printed. The desktop app can serve the same API
+itself, so this command is for when the app is not running.
+
+Flags:
+`
+
+func runServe(args []string) error {
+	fs, dbPath := newFlagSet("serve", serveUsage)
+	addr := fs.String("addr", "127.0.0.1:8765", "Loopback address to listen on")
+	token := fs.String("token", "", "API token (default: $GLOG_API_TOKEN or a new random token)")
+	assets := fs.String("assets", "", "Directory for pasted images (default: assets next to the database)")
+	parseArgs(fs, args)
+
+	if *token == "" {
+		*token = os.Getenv("GLOG_API_TOKEN")
+	}
+	generated := false
+	if *token == "" {
+		t, err := api.NewToken()
+		if err != nil {
+			return err
+		}
+		*token = t
+		generated = true
+	}
+	if *assets == "" {
+		*assets = filepath.Join(filepath.Dir(*dbPath), "assets")
+	}
+
+	store, err := db.NewDocumentStore(*dbPath)
+	if err != nil {
+		if errors.Is(err, db.ErrDatabaseLocked) {
+			return fmt.Errorf("%w; start the API from the app instead", err)
+		}
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	server, err := api.Listen(api.NewService(store, *assets), *addr, *token)
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+
+	fmt.Printf("Serving %s on http://%s/api/\n", *dbPath, server.Addr())
+	if generated {
+		fmt.Printf("API token: %s\n", *token)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	fmt.Println("Shutting down")
+	return nil
+}
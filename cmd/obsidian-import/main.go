@@ -0,0 +1,164 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"glog/import/obsidian"
+)
+
+const usage = `glog-obsidian-import - Import an Obsidian vault into glog
+
+Usage:
+  glog-obsidian-import [flags] <vault-path>
+
+Arguments:
+  vault-path           Path to the Obsidian vault directory
+
+Flags:
+  --db <path>              Path to glog database (default: ./glog.db)
+  --assets <path>          Directory attachments are copied to
+                           (default: 'assets' next to the database)
+  --daily-format <layout>  Go time layout of daily note filenames
+                           (default: 2006-01-02, i.e. YYYY-MM-DD)
+  --daily-folder <path>    Vault folder holding daily notes; if empty,
+                           any note named like a date is a daily note
+  --journals-only          Import only daily notes, skip other notes
+  --pages-only             Import only regular notes, skip daily notes
+  --dry-run                Preview what would be imported without writing
+  --verbose                Show detailed progress for each file
+  --help                   Show this help message
+
+Examples:
+  glog-obsidian-import ~/Documents/my-vault
+  glog-obsidian-import --daily-folder "Daily Notes" ~/vault
+  glog-obsidian-import --daily-format 02.01.2006 --dry-run ~/vault
+
+Note:
+  - Flags must be specified before the path argument
+  - Daily notes become journals; other notes become pages
+  - Frontmatter tags and aliases are kept as 'tags::' and 'alias::'
+    properties in the first block
+  - When importing documents with titles that already exist in glog,
+    a suffix will be added (e.g., "My Page" -> "My Page (2)").
+`
+
+func main() {
+	// Define flags
+	dbPath := flag.String("db", "./glog.db", "Path to glog database")
+	assetsDir := flag.String("assets", "", "Directory attachments are copied to")
+	dailyFormat := flag.String("daily-format", obsidian.DefaultDailyNoteFormat, "Go time layout of daily note filenames")
+	dailyFolder := flag.String("daily-folder", "", "Vault folder holding daily notes")
+	journalsOnly := flag.Bool("journals-only", false, "Import only daily notes, skip other notes")
+	pagesOnly := flag.Bool("pages-only", false, "Import only regular notes, skip daily notes")
+	dryRun := flag.Bool("dry-run", false, "Preview import without writing")
+	verbose := flag.Bool("verbose", false, "Show detailed progress")
+	help := flag.Bool("help", false, "Show help message")
+
+	// Custom usage function
+	flag.Usage = func() {
+		fmt.Print(usage)
+	}
+
+	flag.Parse()
+
+	// Show help if requested
+	if *help {
+		flag.Usage()
+		os.Exit(0)
+	}
+
+	// Check for required positional argument
+	args := flag.Args()
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "Error: missing required argument <vault-path>")
+		fmt.Fprintln(os.Stderr, "")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	vaultPath := args[0]
+
+	// Validate conflicting flags
+	if *journalsOnly && *pagesOnly {
+		fmt.Fprintln(os.Stderr, "Error: --journals-only and --pages-only cannot be used together")
+		os.Exit(1)
+	}
+
+	// Print header
+	fmt.Println("glog-obsidian-import - Obsidian to glog importer")
+	fmt.Println("")
+	fmt.Printf("Obsidian vault: %s\n", vaultPath)
+	fmt.Printf("Target database: %s\n", *dbPath)
+	if *dryRun {
+		fmt.Println("Mode: DRY RUN (no changes will be made)")
+	}
+	fmt.Println("")
+
+	// Create import options
+	opts := obsidian.ImportOptions{
+		VaultPath:        vaultPath,
+		DBPath:           *dbPath,
+		AssetsDir:        *assetsDir,
+		DailyNoteFormat:  *dailyFormat,
+		DailyNotesFolder: *dailyFolder,
+		JournalsOnly:     *journalsOnly,
+		PagesOnly:        *pagesOnly,
+		DryRun:           *dryRun,
+		Verbose:          *verbose,
+	}
+
+	// Run import
+	result, err := obsidian.Import(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Print results
+	printResults(result, *dryRun)
+
+	// Exit with error code if there were errors
+	if len(result.Errors) > 0 {
+		os.Exit(1)
+	}
+}
+
+func printResults(result *obsidian.ImportResult, dryRun bool) {
+	fmt.Println("")
+	if dryRun {
+		fmt.Println("=== DRY RUN RESULTS ===")
+		fmt.Println("The following would be imported:")
+	} else {
+		fmt.Println("=== IMPORT COMPLETE ===")
+	}
+	fmt.Println("")
+
+	fmt.Printf("  Journals imported: %d\n", result.JournalsImported)
+	fmt.Printf("  Pages imported:    %d\n", result.PagesImported)
+	fmt.Printf("  Total:             %d\n", result.JournalsImported+result.PagesImported)
+	fmt.Printf("  Attachments:       %d\n", result.AssetsCopied)
+
+	if result.Skipped > 0 {
+		fmt.Printf("  Skipped:           %d\n", result.Skipped)
+	}
+
+	if len(result.Renamed) > 0 {
+		fmt.Println("")
+		fmt.Printf("  Renamed (duplicates): %d\n", len(result.Renamed))
+		for _, r := range result.Renamed {
+			fmt.Printf("    - \"%s\" -> \"%s\"\n", r.OriginalTitle, r.NewTitle)
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		fmt.Println("")
+		fmt.Printf("  Errors: %d\n", len(result.Errors))
+		for _, e := range result.Errors {
+			fmt.Printf("    - %v\n", e)
+		}
+	}
+
+	fmt.Println("")
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"glog/import/roam"
+)
+
+const usage = `glog-roam-import - Import a Roam Research JSON export into glog
+
+Usage:
+  glog-roam-import [flags] <export.json>
+
+Arguments:
+  export.json          Path to the JSON file exported from Roam
+                       (All Pages > Export All > JSON)
+
+Flags:
+  --db <path>          Path to glog database (default: ./glog.db)
+  --journals-only      Import only daily pages, skip other pages
+  --pages-only         Import only regular pages, skip daily pages
+  --dry-run            Preview what would be imported without writing
+  --verbose            Show detailed progress for each page
+  --help               Show this help message
+
+Examples:
+  glog-roam-import ~/Downloads/my-graph.json
+  glog-roam-import --db ~/glog.db --verbose my-graph.json
+  glog-roam-import --dry-run my-graph.json
+
+Note:
+  - Flags must be specified before the path argument
+  - Daily pages ("October 17th, 2026") become journals
+  - {{[[TODO]]}} and {{[[DONE]]}} become /TODO and /DONE markers
+  - Block references ((uid)) are rewritten to the imported block IDs
+  - When importing documents with titles that already exist in glog,
+    a suffix will be added (e.g., "My Page" -> "My Page (2)").
+`
+
+func main() {
+	// Define flags
+	dbPath := flag.String("db", "./glog.db", "Path to glog database")
+	journalsOnly := flag.Bool("journals-only", false, "Import only daily pages, skip other pages")
+	pagesOnly := flag.Bool("pages-only", false, "Import only regular pages, skip daily pages")
+	dryRun := flag.Bool("dry-run", false, "Preview import without writing")
+	verbose := flag.Bool("verbose", false, "Show detailed progress")
+	help := flag.Bool("help", false, "Show help message")
+
+	// Custom usage function
+	flag.Usage = func() {
+		fmt.Print(usage)
+	}
+
+	flag.Parse()
+
+	// Show help if requested
+	if *help {
+		flag.Usage()
+		os.Exit(0)
+	}
+
+	// Check for required positional argument
+	args := flag.Args()
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "Error: missing required argument <export.json>")
+		fmt.Fprintln(os.Stderr, "")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	exportPath := args[0]
+
+	// Validate conflicting flags
+	if *journalsOnly && *pagesOnly {
+		fmt.Fprintln(os.Stderr, "Error: --journals-only and --pages-only cannot be used together")
+		os.Exit(1)
+	}
+
+	// Print header
+	fmt.Println("glog-roam-import - Roam Research to glog importer")
+	fmt.Println("")
+	fmt.Printf("Roam export: %s\n", exportPath)
+	fmt.Printf("Target database: %s\n", *dbPath)
+	if *dryRun {
+		fmt.Println("Mode: DRY RUN (no changes will be made)")
+	}
+	fmt.Println("")
+
+	// Create import options
+	opts := roam.ImportOptions{
+		ExportPath:   exportPath,
+		DBPath:       *dbPath,
+		JournalsOnly: *journalsOnly,
+		PagesOnly:    *pagesOnly,
+		DryRun:       *dryRun,
+		Verbose:      *verbose,
+	}
+
+	// Run import
+	result, err := roam.Import(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Print results
+	printResults(result, *dryRun)
+
+	// Exit with error code if there were errors
+	if len(result.Errors) > 0 {
+		os.Exit(1)
+	}
+}
+
+func printResults(result *roam.ImportResult, dryRun bool) {
+	fmt.Println("")
+	if dryRun {
+		fmt.Println("=== DRY RUN RESULTS ===")
+		fmt.Println("The following would be imported:")
+	} else {
+		fmt.Println("=== IMPORT COMPLETE ===")
+	}
+	fmt.Println("")
+
+	fmt.Printf("  Journals imported: %d\n", result.JournalsImported)
+	fmt.Printf("  Pages imported:    %d\n", result.PagesImported)
+	fmt.Printf("  Total:             %d\n", result.JournalsImported+result.PagesImported)
+
+	if result.Skipped > 0 {
+		fmt.Printf("  Skipped:           %d\n", result.Skipped)
+	}
+
+	if len(result.Renamed) > 0 {
+		fmt.Println("")
+		fmt.Printf("  Renamed (duplicates): %d\n", len(result.Renamed))
+		for _, r := range result.Renamed {
+			fmt.Printf("    - \"%s\" -> \"%s\"\n", r.OriginalTitle, r.NewTitle)
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		fmt.Println("")
+		fmt.Printf("  Errors: %d\n", len(result.Errors))
+		for _, e := range result.Errors {
+			fmt.Printf("    - %v\n", e)
+		}
+	}
+
+	fmt.Println("")
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"errors"
+	"glog/domain"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestLockTimeoutAndReadOnly(t *testing.T) {
+	defer func() {
+		_ = os.Remove("./testaccess.db")
+		_ = os.RemoveAll("./testaccess.db.bleve")
+	}()
+
+	writer, err := NewDocumentStore("./testaccess.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+
+	doc := &domain.Document{
+		ID:    domain.DocumentID(uuid.New()),
+		Title: "Inspect me",
+		Date:  time.Now(),
+		Blocks: []*domain.Block{
+			{ID: domain.BlockID(uuid.New()), Content: "read only content"},
+		},
+	}
+	if err := writer.Save(doc); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// A second writer gives up with a clear error instead of blocking
+	start := time.Now()
+	_, err = NewDocumentStoreWithOptions("./testaccess.db", Options{Timeout: 100 * time.Millisecond})
+	if !errors.Is(err, ErrDatabaseLocked) {
+		t.Errorf("Expected ErrDatabaseLocked, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected to fail after the timeout, took %v", elapsed)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close DocumentStore: %v", err)
+	}
+
+	// Any number of read-only stores can be open at once
+	opts := Options{Timeout: time.Second, ReadOnly: true}
+	first, err := NewDocumentStoreWithOptions("./testaccess.db", opts)
+	if err != nil {
+		t.Fatalf("Failed to open read-only: %v", err)
+	}
+	defer first.Close()
+	second, err := NewDocumentStoreWithOptions("./testaccess.db", opts)
+	if err != nil {
+		t.Fatalf("Failed to open a second read-only store: %v", err)
+	}
+	defer second.Close()
+
+	loaded, err := second.LoadDocumentByTitle("Inspect me")
+	if err != nil || loaded.ID != doc.ID {
+		t.Errorf("Expected to load the document read-only, got %v (err %v)", loaded, err)
+	}
+	ids, err := first.Search("content")
+	if err != nil || len(ids) != 1 {
+		t.Errorf("Expected one search hit, got %v (err %v)", ids, err)
+	}
+
+	if err := first.Save(doc); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from Save, got %v", err)
+	}
+	if err := first.ReindexSearch(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from ReindexSearch, got %v", err)
+	}
+}
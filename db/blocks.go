@@ -0,0 +1,131 @@
+package db
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"glog/domain"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+// blockIndex maps block IDs to the document holding the block, so
+// ((block-id)) references can be resolved.
+type blockIndex struct {
+	bucket     []byte
+	docsBucket []byte
+}
+
+// newBlockIndex opens the block index, building it from the documents of
+// databases created before it existed. Read-only stores of such databases
+// look blocks up by scanning the documents instead.
+func newBlockIndex(db *bolt.DB, docsBucket []byte) (*blockIndex, error) {
+	bi := &blockIndex{bucket: []byte("block_index"), docsBucket: docsBucket}
+	if db.IsReadOnly() {
+		return bi, nil
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(bi.bucket) != nil {
+			return nil
+		}
+		bucket, err := tx.CreateBucket(bi.bucket)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(docsBucket).ForEach(func(k, v []byte) error {
+			var docDb DocDb
+			if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&docDb); err != nil {
+				return fmt.Errorf("failed to index the blocks of %s: %w", k, err)
+			}
+			for _, block := range docDb.Blocks {
+				if err := bucket.Put([]byte(block.ID.String()), k); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bi, nil
+}
+
+// update records that the blocks of a document changed from before to after.
+// Blocks that left the document are only removed when no other document
+// claimed them since, as when blocks are moved.
+func (bi *blockIndex) update(tx *bolt.Tx, docID uuid.UUID, before []*BlockDb, after []*BlockDb) error {
+	bucket := tx.Bucket(bi.bucket)
+	if bucket == nil {
+		return nil
+	}
+	id := []byte(docID.String())
+
+	kept := make(map[uuid.UUID]struct{}, len(after))
+	for _, block := range after {
+		kept[block.ID] = struct{}{}
+	}
+	previous := make(map[uuid.UUID]struct{}, len(before))
+	for _, block := range before {
+		previous[block.ID] = struct{}{}
+		if _, ok := kept[block.ID]; ok {
+			continue
+		}
+		key := []byte(block.ID.String())
+		if bytes.Equal(bucket.Get(key), id) {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+	for _, block := range after {
+		if _, ok := previous[block.ID]; ok {
+			continue
+		}
+		if err := bucket.Put([]byte(block.ID.String()), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup returns the ID of the document holding a block.
+func (bi *blockIndex) lookup(tx *bolt.Tx, blockID uuid.UUID) (uuid.UUID, error) {
+	docs := tx.Bucket(bi.docsBucket)
+	if bucket := tx.Bucket(bi.bucket); bucket != nil {
+		v := bucket.Get([]byte(blockID.String()))
+		if v == nil || docs.Get(v) == nil {
+			return uuid.Nil, ErrDocumentNotFound
+		}
+		return uuid.Parse(string(v))
+	}
+
+	cursor := docs.Cursor()
+	for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+		var docDb DocDb
+		if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&docDb); err != nil {
+			return uuid.Nil, err
+		}
+		for _, block := range docDb.Blocks {
+			if block.ID == blockID {
+				return docDb.ID, nil
+			}
+		}
+	}
+	return uuid.Nil, ErrDocumentNotFound
+}
+
+// GetBlockDocument returns the ID of the document holding a block, to
+// resolve ((block-id)) references. It fails with ErrDocumentNotFound when
+// no document holds the block.
+func (store *DocumentStore) GetBlockDocument(blockID domain.BlockID) (domain.DocumentID, error) {
+	var docID uuid.UUID
+	err := store.bolt.View(func(tx *bolt.Tx) error {
+		var err error
+		docID, err = store.blockIndex.lookup(tx, uuid.UUID(blockID))
+		return err
+	})
+	return domain.DocumentID(docID), err
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"errors"
+	"glog/domain"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestGetBlockDocument(t *testing.T) {
+	store, err := NewDocumentStore("./testblocks.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove("./testblocks.db")
+		_ = os.RemoveAll("./testblocks.db.bleve")
+	}()
+
+	doc := saveTestDoc(t, store, "Garden", "Plant tomatoes", "Water daily")
+	kept, dropped := doc.Blocks[0].ID, doc.Blocks[1].ID
+	if docID, err := store.GetBlockDocument(dropped); err != nil || docID != doc.ID {
+		t.Errorf("Expected the block to resolve to its document, got %v (err %v)", docID, err)
+	}
+
+	doc.Blocks = doc.Blocks[:1]
+	if err := store.Save(doc); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := store.GetBlockDocument(dropped); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("Expected ErrDocumentNotFound for a removed block, got %v", err)
+	}
+
+	// Databases from before the index are indexed when opened
+	if err := store.bolt.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(store.blockIndex.bucket)
+	}); err != nil {
+		t.Fatalf("Failed to drop the block index: %v", err)
+	}
+	if _, err := newBlockIndex(store.bolt, store.blockIndex.docsBucket); err != nil {
+		t.Fatalf("Failed to rebuild the block index: %v", err)
+	}
+	if docID, err := store.GetBlockDocument(kept); err != nil || docID != doc.ID {
+		t.Errorf("Expected the rebuilt index to resolve the block, got %v (err %v)", docID, err)
+	}
+
+	if err := store.Delete(uuid.UUID(doc.ID)); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.GetBlockDocument(kept); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("Expected ErrDocumentNotFound after deleting the document, got %v", err)
+	}
+	if _, err := store.GetBlockDocument(domain.BlockID(uuid.New())); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("Expected ErrDocumentNotFound for an unknown block, got %v", err)
+	}
+}
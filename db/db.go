@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/gob"
 	"errors"
+	"fmt"
 
 	"glog/domain"
 	"strings"
@@ -13,17 +14,37 @@ import (
 	"github.com/google/uuid"
 	"github.com/labstack/gommon/log"
 	bolt "go.etcd.io/bbolt"
+	berrors "go.etcd.io/bbolt/errors"
 )
 
 var ErrDocumentNotFound = errors.New("document not found")
 var ErrDuplicateTitle = errors.New("document title already exists")
+var ErrDatabaseLocked = errors.New("database is locked by another process")
+var ErrReadOnly = errors.New("database is opened read-only")
+
+// DefaultLockTimeout is how long NewDocumentStore waits for the file lock
+// held by another process before failing with ErrDatabaseLocked.
+const DefaultLockTimeout = 2 * time.Second
+
+// Options configures how a DocumentStore opens its files.
+//
+// Access model: the Bolt file and the Bleve index can be opened by one
+// writer or by any number of read-only processes, never both. The desktop
+// app is the writer while it runs; other tools either talk to it over the
+// socket of the glog/ipc package or fail after Timeout with ErrDatabaseLocked.
+type Options struct {
+	// Timeout is how long to wait for the file lock. Zero waits forever.
+	Timeout time.Duration
+	// ReadOnly opens the files with a shared lock for inspection tools;
+	// every write method returns ErrReadOnly.
+	ReadOnly bool
+}
 
 // failedIndexEntry tracks a document that failed to index
 type failedIndexEntry struct {
 	docID       string
 	attempts    int
 	lastAttempt time.Time
-	doc         *DocDb
 }
 
 // IndexHealth represents the health status of the search index
@@ -33,6 +54,8 @@ type IndexHealth struct {
 	LastHealthCheck    time.Time
 	RequiresReindex    bool
 	HealthCheckMessage string
+	QueueDepth         int           // documents waiting in the index outbox
+	QueueLag           time.Duration // how long the oldest of them has waited
 }
 
 // DocumentStore provides thread-safe access to document storage and search.
@@ -42,7 +65,7 @@ type IndexHealth struct {
 // DocumentStore is safe for concurrent use. All methods that access
 // the search index are protected by an internal RWMutex:
 //
-//   - Save() acquires a read lock when indexing (allows concurrent saves)
+//   - the indexing worker acquires a read lock per batch
 //   - Search() acquires a read lock (allows concurrent searches)
 //   - ReindexSearch() acquires a write lock (blocks all other operations)
 //   - Close() acquires a write lock (ensures clean shutdown)
@@ -51,16 +74,22 @@ type IndexHealth struct {
 // other search index operations, preventing race conditions.
 type DocumentStore struct {
 	path               string
+	readOnly           bool
 	bolt               *bolt.DB
 	bucketDocs         []byte
 	bucketTimeIndex    []byte
 	bucketTitleIndex   []byte
 	bucketJournalIndex []byte
 	search             *bleveSearch
-	searchMu           sync.RWMutex // protects search index operations
+	searchMu           sync.RWMutex   // protects search index operations
+	searchAnalysis     SearchAnalysis // configured; guarded by searchMu
 	referencesIndex    *referencesIndex
 	scheduledIndex     *scheduledTasks
 	recentsDocs        *recentsDocs
+	changeLog          *changeLog
+	blockIndex         *blockIndex
+	events             eventBus
+	indexQueue         *indexQueue // nil when read-only
 
 	// Index health tracking
 	failedIndexes   map[string]*failedIndexEntry
@@ -69,9 +98,18 @@ type DocumentStore struct {
 	indexHealthMu   sync.RWMutex
 }
 
+// NewDocumentStore opens the store for writing, waiting up to
+// DefaultLockTimeout for other processes to release the database.
 func NewDocumentStore(path string) (*DocumentStore, error) {
-	db, err := bolt.Open(path, 0600, nil)
+	return NewDocumentStoreWithOptions(path, Options{Timeout: DefaultLockTimeout})
+}
+
+func NewDocumentStoreWithOptions(path string, opts Options) (*DocumentStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: opts.Timeout, ReadOnly: opts.ReadOnly})
 	if err != nil {
+		if errors.Is(err, berrors.ErrTimeout) {
+			return nil, fmt.Errorf("%w: %s (is the glog app running?)", ErrDatabaseLocked, path)
+		}
 		return nil, err
 	}
 
@@ -80,38 +118,28 @@ func NewDocumentStore(path string) (*DocumentStore, error) {
 	titleIndexKey := []byte("title_index")
 	journalIndexKey := []byte("journal_index")
 
-	err = db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists(docsKey)
-		if err != nil {
-			return err
-		}
-
-		_, err = tx.CreateBucketIfNotExists(timeIndexKey)
-		if err != nil {
-			return err
-		}
-
-		_, err = tx.CreateBucketIfNotExists(titleIndexKey)
-		if err != nil {
-			return err
-		}
-
-		_, err = tx.CreateBucketIfNotExists(journalIndexKey)
-		if err != nil {
-			return err
-		}
+	err = ensureBuckets(db, docsKey, timeIndexKey, titleIndexKey, journalIndexKey)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
 
-		return nil
+	var searchAnalysis SearchAnalysis
+	err = db.View(func(tx *bolt.Tx) error {
+		searchAnalysis, err = searchAnalysisTx(tx)
+		return err
 	})
-
 	if err != nil {
 		_ = db.Close()
 		return nil, err
 	}
 
-	search, err := openBleveSearch(bleveIndexPath(path))
+	search, err := openBleveSearch(bleveIndexPath(path), opts, searchAnalysis)
 	if err != nil {
 		_ = db.Close()
+		if errors.Is(err, berrors.ErrTimeout) {
+			return nil, fmt.Errorf("%w: %s (is the glog app running?)", ErrDatabaseLocked, bleveIndexPath(path))
+		}
 		return nil, err
 	}
 
@@ -136,17 +164,36 @@ func NewDocumentStore(path string) (*DocumentStore, error) {
 		return nil, err
 	}
 
+	changeLog, err := newChangeLog(db)
+	if err != nil {
+		_ = db.Close()
+		_ = search.Close()
+		return nil, err
+	}
+
+	blockIndex, err := newBlockIndex(db, docsKey)
+	if err != nil {
+		_ = db.Close()
+		_ = search.Close()
+		return nil, err
+	}
+
 	store := &DocumentStore{
 		bolt:               db,
 		path:               path,
+		readOnly:           opts.ReadOnly,
 		bucketDocs:         docsKey,
 		bucketTimeIndex:    timeIndexKey,
 		bucketTitleIndex:   titleIndexKey,
 		bucketJournalIndex: journalIndexKey,
 		search:             search,
+		searchAnalysis:     searchAnalysis,
 		referencesIndex:    referencesIndex,
 		scheduledIndex:     scheduledIndex,
 		recentsDocs:        recentsDocs,
+		changeLog:          changeLog,
+		blockIndex:         blockIndex,
+		events:             eventBus{subscribers: make(map[*Subscription]struct{})},
 		failedIndexes:      make(map[string]*failedIndexEntry),
 		indexHealth: IndexHealth{
 			IsHealthy:       true,
@@ -154,13 +201,86 @@ func NewDocumentStore(path string) (*DocumentStore, error) {
 		},
 	}
 
+	// Saves queue search index updates in the outbox, in the same
+	// transaction, for the indexing worker
+	if !opts.ReadOnly {
+		if err := ensureBuckets(db, indexOutboxBucket); err != nil {
+			_ = db.Close()
+			_ = search.Close()
+			return nil, err
+		}
+		store.indexQueue = newIndexQueue()
+	}
+
 	// Perform initial health check
 	store.checkIndexHealth()
 
+	// Indexes built with an older mapping or another analysis are rebuilt
+	// in the background
+	if store.searchOutdated() && !opts.ReadOnly {
+		store.searchMu.Lock()
+		store.reindexInBackgroundLocked("Rebuilding the outdated search index")
+	}
+
+	// Changes left in the outbox by a previous run are indexed first
+	if store.indexQueue != nil {
+		go store.runIndexer()
+		store.indexQueue.notify()
+	}
+
 	return store, nil
 }
 
+// searchOutdated reports whether the index needs a rebuild to match the
+// current mapping and the configured analysis.
+func (store *DocumentStore) searchOutdated() bool {
+	return !store.search.current || !store.search.analysis.Equal(store.searchAnalysis)
+}
+
+// reindexInBackgroundLocked rebuilds the search index in a goroutine that
+// releases searchMu when done; searchMu must be held for writing. Holding
+// it from the caller on makes searches and saves wait for the new index.
+func (store *DocumentStore) reindexInBackgroundLocked(task string) {
+	go func() {
+		defer store.searchMu.Unlock()
+		if err := store.reindexSearchLocked(); err != nil {
+			log.Errorf("%s failed: %v", task, err)
+		}
+	}()
+}
+
+// ensureBuckets creates the named buckets, or checks that they exist
+// when the database is opened read-only.
+func ensureBuckets(db *bolt.DB, names ...[]byte) error {
+	if db.IsReadOnly() {
+		return db.View(func(tx *bolt.Tx) error {
+			for _, name := range names {
+				if tx.Bucket(name) == nil {
+					return fmt.Errorf("database is not initialized: missing bucket %q", name)
+				}
+			}
+			return nil
+		})
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		for _, name := range names {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReadOnly reports whether the store was opened with Options.ReadOnly.
+func (store *DocumentStore) ReadOnly() bool {
+	return store.readOnly
+}
+
 func (store *DocumentStore) Close() error {
+	store.stopIndexer()
+
 	// Acquire write lock to ensure no operations are in-flight during shutdown
 	store.searchMu.Lock()
 	defer store.searchMu.Unlock()
@@ -172,13 +292,18 @@ func (store *DocumentStore) Close() error {
 	return store.bolt.Close()
 }
 
-// checkIndexHealth performs a health check on the search index
+// checkIndexHealth performs a health check on the search index; searchMu
+// must be held
 func (store *DocumentStore) checkIndexHealth() {
 	store.indexHealthMu.Lock()
 	defer store.indexHealthMu.Unlock()
 
+	store.failedIndexesMu.Lock()
+	failed := len(store.failedIndexes)
+	store.failedIndexesMu.Unlock()
+
 	store.indexHealth.LastHealthCheck = time.Now()
-	store.indexHealth.FailedDocuments = len(store.failedIndexes)
+	store.indexHealth.FailedDocuments = failed
 
 	// Check if index is accessible
 	if store.search == nil || store.search.index == nil {
@@ -188,6 +313,21 @@ func (store *DocumentStore) checkIndexHealth() {
 		return
 	}
 
+	if !store.search.current {
+		store.indexHealth.IsHealthy = false
+		store.indexHealth.RequiresReindex = true
+		store.indexHealth.HealthCheckMessage = "Search index was built by an older version and needs a reindex"
+		return
+	}
+
+	if !store.search.analysis.Equal(store.searchAnalysis) {
+		store.indexHealth.IsHealthy = false
+		store.indexHealth.RequiresReindex = true
+		store.indexHealth.HealthCheckMessage = "Search index was built with another text analysis and needs a reindex"
+		return
+	}
+	store.indexHealth.RequiresReindex = false
+
 	// Consider unhealthy if there are failed documents
 	if store.indexHealth.FailedDocuments > 0 {
 		store.indexHealth.IsHealthy = false
@@ -198,55 +338,19 @@ func (store *DocumentStore) checkIndexHealth() {
 	}
 }
 
-// GetIndexHealth returns the current health status of the search index
+// GetIndexHealth returns the current health status of the search index,
+// with the depth and lag of the indexing outbox
 func (store *DocumentStore) GetIndexHealth() IndexHealth {
 	store.indexHealthMu.RLock()
-	defer store.indexHealthMu.RUnlock()
-	return store.indexHealth
-}
-
-// indexDocWithRetry attempts to index a document with exponential backoff
-func (store *DocumentStore) indexDocWithRetry(doc *DocDb, maxAttempts int) error {
-	docID := doc.ID.String()
-
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		err := store.search.IndexDoc(doc)
-		if err == nil {
-			// Success - remove from failed index tracking if it was there
-			store.failedIndexesMu.Lock()
-			delete(store.failedIndexes, docID)
-			store.failedIndexesMu.Unlock()
-			return nil
-		}
-
-		// Log the error
-		log.Warnf("Bleve indexing attempt %d/%d failed for document %s: %v", attempt, maxAttempts, docID, err)
-
-		// If this is not the last attempt, wait with exponential backoff
-		if attempt < maxAttempts {
-			backoff := time.Duration(attempt*attempt) * 100 * time.Millisecond
-			time.Sleep(backoff)
-		}
-	}
+	health := store.indexHealth
+	store.indexHealthMu.RUnlock()
 
-	// All attempts failed - track the failure
-	store.failedIndexesMu.Lock()
-	entry, exists := store.failedIndexes[docID]
-	if !exists {
-		entry = &failedIndexEntry{
-			docID: docID,
-			doc:   doc,
-		}
-		store.failedIndexes[docID] = entry
+	health.QueueDepth, health.QueueLag = store.indexQueueStats()
+	if health.IsHealthy && health.QueueLag > indexLagWarning {
+		health.IsHealthy = false
+		health.HealthCheckMessage = fmt.Sprintf("%d documents have waited %s to be indexed", health.QueueDepth, health.QueueLag.Round(time.Second))
 	}
-	entry.attempts++
-	entry.lastAttempt = time.Now()
-	store.failedIndexesMu.Unlock()
-
-	// Update health check
-	store.checkIndexHealth()
-
-	return errors.New("failed to index document after multiple attempts")
+	return health
 }
 
 func (store *DocumentStore) GetRecents() ([]domain.DocumentID, error) {
@@ -263,35 +367,35 @@ func (store *DocumentStore) GetRecents() ([]domain.DocumentID, error) {
 	return docIDs, nil
 }
 
-// RetryFailedIndexing attempts to reindex all documents that previously failed
+// RetryFailedIndexing retries the documents that failed to index right
+// away instead of after their backoff, and returns how many now indexed.
 func (store *DocumentStore) RetryFailedIndexing() (int, error) {
-	store.failedIndexesMu.Lock()
-	failedDocs := make([]*DocDb, 0, len(store.failedIndexes))
-	for _, entry := range store.failedIndexes {
-		failedDocs = append(failedDocs, entry.doc)
+	if store.indexQueue == nil {
+		return 0, nil
 	}
-	store.failedIndexesMu.Unlock()
 
-	if len(failedDocs) == 0 {
+	store.failedIndexesMu.Lock()
+	failed := len(store.failedIndexes)
+	store.failedIndexesMu.Unlock()
+	if failed == 0 {
 		return 0, nil
 	}
 
-	successCount := 0
-	store.searchMu.RLock()
-	defer store.searchMu.RUnlock()
+	store.indexQueue.wait(true)
 
-	for _, doc := range failedDocs {
-		err := store.indexDocWithRetry(doc, 3)
-		if err == nil {
-			successCount++
-		}
-	}
-
-	store.checkIndexHealth()
-	return successCount, nil
+	store.failedIndexesMu.Lock()
+	defer store.failedIndexesMu.Unlock()
+	return max(failed-len(store.failedIndexes), 0), nil
 }
 
 func (store *DocumentStore) saveDoc(tx *bolt.Tx, doc *domain.Document) (*DocDb, error) {
+	var revision uint64
+	prev, err := store.getDocDb(tx, doc.ID)
+	if err == nil {
+		revision = prev.Revision
+	} else if !errors.Is(err, ErrDocumentNotFound) {
+		return nil, err
+	}
 
 	// Create DocDb from domain.Document
 	docDb := DocDb{
@@ -300,6 +404,7 @@ func (store *DocumentStore) saveDoc(tx *bolt.Tx, doc *domain.Document) (*DocDb,
 		Date:      doc.Date.UTC().Format(time.RFC3339),
 		IsJournal: doc.IsJournal,
 		Blocks:    make([]*BlockDb, len(doc.Blocks)),
+		Revision:  revision,
 	}
 
 	for i, block := range doc.Blocks {
@@ -310,16 +415,53 @@ func (store *DocumentStore) saveDoc(tx *bolt.Tx, doc *domain.Document) (*DocDb,
 		}
 	}
 
+	if err := store.putDocDb(tx, &docDb); err != nil {
+		return nil, err
+	}
+	var before []*BlockDb
+	if prev != nil {
+		before = prev.Blocks
+	}
+	if err := store.blockIndex.update(tx, docDb.ID, before, docDb.Blocks); err != nil {
+		return nil, err
+	}
+	doc.Revision = docDb.Revision
+	return &docDb, nil
+}
+
+// putDocDb stores docDb with the next revision.
+func (store *DocumentStore) putDocDb(tx *bolt.Tx, docDb *DocDb) error {
+	docDb.Revision++
+
 	// Serialize DocDb
 	var buf bytes.Buffer
 	enc := gob.NewEncoder(&buf)
 	err := enc.Encode(docDb)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	bucket := tx.Bucket(store.bucketDocs)
-	return &docDb, bucket.Put([]byte(doc.ID.String()), buf.Bytes())
+	return bucket.Put([]byte(docDb.ID.String()), buf.Bytes())
+}
+
+// getDocDb reads the stored form of a document.
+func (store *DocumentStore) getDocDb(tx *bolt.Tx, id domain.DocumentID) (*DocDb, error) {
+	bucket := tx.Bucket(store.bucketDocs)
+	data := bucket.Get([]byte(id.String()))
+	if data == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	// Deserialize DocDb
+	var docDb DocDb
+	buf := bytes.NewBuffer(data)
+	dec := gob.NewDecoder(buf)
+	err := dec.Decode(&docDb)
+	if err != nil {
+		return nil, err
+	}
+	return &docDb, nil
 }
 
 func (store *DocumentStore) saveTimeIndex(tx *bolt.Tx, doc *domain.Document) error {
@@ -367,85 +509,146 @@ func (store *DocumentStore) saveJournalIndex(tx *bolt.Tx, doc *domain.Document)
 }
 
 func (store *DocumentStore) Save(doc *domain.Document) error {
-	var savedDoc *DocDb
+	if store.readOnly {
+		return ErrReadOnly
+	}
+
 	if err := store.bolt.Update(func(tx *bolt.Tx) error {
-		docDb, err := store.saveDoc(tx, doc)
-		if err != nil {
-			return err
-		}
-		savedDoc = docDb
+		_, err := store.saveTx(tx, doc)
+		return err
+	}); err != nil {
+		return err
+	}
 
-		err = store.saveTimeIndex(tx, doc)
-		if err != nil {
-			return err
-		}
+	store.notifyIndexer()
+	return nil
+}
 
-		err = store.saveTitleIndex(tx, doc)
-		if err != nil {
-			return err
-		}
+// saveTx writes a document and its Bolt indexes within tx.
+func (store *DocumentStore) saveTx(tx *bolt.Tx, doc *domain.Document) (*DocDb, error) {
+	docDb, err := store.saveDoc(tx, doc)
+	if err != nil {
+		return nil, err
+	}
 
-		err = store.saveJournalIndex(tx, doc)
-		if err != nil {
-			return err
-		}
+	err = store.saveTimeIndex(tx, doc)
+	if err != nil {
+		return nil, err
+	}
 
-		err = store.referencesIndex.save(tx, docDb)
-		if err != nil {
-			return err
+	err = store.saveTitleIndex(tx, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.saveJournalIndex(tx, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.referencesIndex.save(tx, docDb)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.recordScheduledTasks(tx, docDb)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.scheduledIndex.save(tx, docDb)
+	if err != nil {
+		return nil, err
+	}
+
+	err = enqueueIndex(tx, docDb.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.recordEvent(tx, Event{
+		Type:      EventDocumentSaved,
+		DocID:     doc.ID,
+		Title:     doc.Title,
+		IsJournal: doc.IsJournal,
+		doc:       docDbToDomain(docDb),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return docDb, nil
+}
+
+// UpdateJournal loads the journal of the day of now, creating it with
+// NewJournal if it does not exist, applies fn and saves the result in a
+// single transaction, so concurrent updates of the same journal are not lost.
+func (store *DocumentStore) UpdateJournal(now time.Time, fn func(doc *domain.Document) error) (*domain.Document, error) {
+	if store.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	var doc *domain.Document
+	err := store.bolt.Update(func(tx *bolt.Tx) error {
+		doc = nil
+		key := JournalDay(now).Format(time.RFC3339)
+		if v := tx.Bucket(store.bucketJournalIndex).Get([]byte(key)); v != nil {
+			id, err := uuid.Parse(string(v))
+			if err != nil {
+				return err
+			}
+			doc, err = store.loadDocument(tx, domain.DocumentID(id))
+			if err != nil && !errors.Is(err, ErrDocumentNotFound) {
+				return err
+			}
+		}
+		if doc == nil {
+			doc = NewJournal(now)
 		}
 
-		err = store.scheduledIndex.save(tx, docDb)
-		if err != nil {
+		if err := fn(doc); err != nil {
 			return err
 		}
 
-		return nil
-	}); err != nil {
+		_, err := store.saveTx(tx, doc)
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Index the document in the search index with retry logic.
-	// Note: This happens outside the BoltDB transaction. If indexing fails
-	// after retries, the document is still saved to the database but won't
-	// be searchable until RetryFailedIndexing() or ReindexSearch() is called.
-	// We use RLock here to allow concurrent Save operations while preventing
-	// ReindexSearch from running concurrently.
-	if savedDoc != nil {
-		store.searchMu.RLock()
-		err := store.indexDocWithRetry(savedDoc, 3) // Retry up to 3 times
-		store.searchMu.RUnlock()
-		if err != nil {
-			log.Errorf("Bleve indexing failed after retries: %v", err)
-			// Don't return error - document is saved, just not indexed
-		}
-	}
-
-	return nil
+	store.notifyIndexer()
+	return doc, nil
 }
 
 func (store *DocumentStore) loadDocument(tx *bolt.Tx, id domain.DocumentID) (*domain.Document, error) {
-	var doc domain.Document
-	bucket := tx.Bucket(store.bucketDocs)
-	data := bucket.Get([]byte(id.String()))
+	docDb, err := store.getDocDb(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	return docDbToDomain(docDb), nil
+}
+
+func (store *DocumentStore) loadDocumentByTitle(tx *bolt.Tx, title string) (*domain.Document, error) {
+	data := tx.Bucket(store.bucketTitleIndex).Get([]byte(strings.ToLower(title)))
 	if data == nil {
 		return nil, ErrDocumentNotFound
 	}
 
-	// Deserialize DocDb
-	var docDb DocDb
-	buf := bytes.NewBuffer(data)
-	dec := gob.NewDecoder(buf)
-	err := dec.Decode(&docDb)
+	id, err := uuid.Parse(string(data))
 	if err != nil {
 		return nil, err
 	}
+	return store.loadDocument(tx, domain.DocumentID(id))
+}
 
-	// Populate domain.Document
+func docDbToDomain(docDb *DocDb) *domain.Document {
+	var doc domain.Document
 	doc.ID = domain.DocumentID(docDb.ID)
 	doc.Title = docDb.Title
 	doc.Date, _ = time.Parse(time.RFC3339, docDb.Date)
 	doc.IsJournal = docDb.IsJournal
+	doc.Revision = docDb.Revision
 	doc.Blocks = make([]*domain.Block, len(docDb.Blocks))
 
 	for i, blockDb := range docDb.Blocks {
@@ -456,7 +659,7 @@ func (store *DocumentStore) loadDocument(tx *bolt.Tx, id domain.DocumentID) (*do
 		}
 	}
 
-	return &doc, nil
+	return &doc
 }
 
 func (store *DocumentStore) LoadDocument(id domain.DocumentID) (*domain.Document, error) {
@@ -474,6 +677,10 @@ func (store *DocumentStore) LoadDocument(id domain.DocumentID) (*domain.Document
 		return nil, err
 	}
 
+	if store.readOnly {
+		return doc, nil
+	}
+
 	err = store.bolt.Update(func(tx *bolt.Tx) error {
 		return store.recentsDocs.update(tx, uuid.UUID(id))
 	})
@@ -484,6 +691,45 @@ func (store *DocumentStore) LoadDocument(id domain.DocumentID) (*domain.Document
 	return doc, nil
 }
 
+// GetDocument loads a document like LoadDocument, but without marking it
+// as recently opened. Use it for reads that are not the user opening a
+// document, such as command-line queries and exports.
+func (store *DocumentStore) GetDocument(id domain.DocumentID) (*domain.Document, error) {
+	var doc *domain.Document
+	err := store.bolt.View(func(tx *bolt.Tx) error {
+		d, err := store.loadDocument(tx, id)
+		if err != nil {
+			return err
+		}
+		doc = d
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// GetDocumentByTitle looks a document up by title like LoadDocumentByTitle,
+// but without marking it as recently opened.
+func (store *DocumentStore) GetDocumentByTitle(title string) (*domain.Document, error) {
+	var doc *domain.Document
+	err := store.bolt.View(func(tx *bolt.Tx) error {
+		d, err := store.loadDocumentByTitle(tx, title)
+		if err != nil {
+			return err
+		}
+		doc = d
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
 type DocumentSummary struct {
 	ID    domain.DocumentID
 	Title string
@@ -522,6 +768,26 @@ func (store *DocumentStore) ListDocuments() ([]DocumentSummary, error) {
 	return summaries, nil
 }
 
+// ForEachDocument calls fn for every stored document, in ID order. Unlike
+// LoadDocument it does not mark documents as recently opened, so it is
+// meant for exports and other bulk reads. fn runs inside a read
+// transaction and must not write to the store.
+func (store *DocumentStore) ForEachDocument(fn func(doc *domain.Document) error) error {
+	return store.bolt.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(store.bucketDocs).ForEach(func(k, v []byte) error {
+			id, err := uuid.Parse(string(k))
+			if err != nil {
+				return err
+			}
+			doc, err := store.loadDocument(tx, domain.DocumentID(id))
+			if err != nil {
+				return err
+			}
+			return fn(doc)
+		})
+	})
+}
+
 func (store *DocumentStore) LoadJournals(from time.Time, to time.Time) ([]*domain.Document, error) {
 	var docs []*domain.Document
 	err := store.bolt.View(func(tx *bolt.Tx) error {
@@ -589,17 +855,20 @@ func (store *DocumentStore) LoadDocumentByTitle(title string) (*domain.Document,
 		return nil, err
 	}
 
-	err = store.bolt.Update(func(tx *bolt.Tx) error {
-		return store.recentsDocs.update(tx, uuid.UUID(docId))
-	})
-	if err != nil {
-		return nil, err
+	if !store.readOnly {
+		err = store.bolt.Update(func(tx *bolt.Tx) error {
+			return store.recentsDocs.update(tx, uuid.UUID(docId))
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return store.LoadDocument(docId)
 }
 
 func (store *DocumentStore) Search(query string) ([]domain.DocumentID, error) {
+	store.waitIndexed()
 	store.searchMu.RLock()
 	ids, err := store.search.Search(query)
 	store.searchMu.RUnlock()
@@ -616,16 +885,166 @@ func (store *DocumentStore) Search(query string) ([]domain.DocumentID, error) {
 	return resultIDs, nil
 }
 
+// SearchSort is the order of SearchPage results.
+type SearchSort string
+
+const (
+	SortRelevance SearchSort = "relevance" // best match first
+	SortDate      SearchSort = "date"      // newest first
+	SortTitle     SearchSort = "title"     // A to Z, ignoring case
+)
+
+// SearchResults is a page of documents matching a search.
+type SearchResults struct {
+	Total int // matches in the search index, including the skipped stale ones
+	Hits  []DocumentSummary
+}
+
+// SearchPage returns up to limit documents matching query from offset,
+// sorted by order, with the total number of matches. Summaries come from
+// the search index; hits for documents deleted since they were indexed
+// are skipped.
+func (store *DocumentStore) SearchPage(query string, offset int, limit int, order SearchSort) (*SearchResults, error) {
+	switch order {
+	case "":
+		order = SortRelevance
+	case SortRelevance, SortDate, SortTitle:
+	default:
+		return nil, fmt.Errorf("%w: unknown sort %q, use relevance, date or title", ErrInvalidQuery, order)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+
+	store.waitIndexed()
+	store.searchMu.RLock()
+	hits, total, err := store.search.SearchPage(query, offset, limit, order)
+	store.searchMu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	results := &SearchResults{Total: int(total), Hits: make([]DocumentSummary, 0, len(hits))}
+	err = store.bolt.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(store.bucketDocs)
+		for _, hit := range hits {
+			id := domain.DocumentID(hit.ID)
+			if hit.Title == "" {
+				// Indexes with an older mapping do not store summaries
+				docDb, err := store.getDocDb(tx, id)
+				if errors.Is(err, ErrDocumentNotFound) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				date, _ := time.Parse(time.RFC3339, docDb.Date)
+				results.Hits = append(results.Hits, DocumentSummary{ID: id, Title: docDb.Title, Date: date})
+				continue
+			}
+
+			if bucket.Get([]byte(id.String())) == nil {
+				continue
+			}
+			results.Hits = append(results.Hits, DocumentSummary{ID: id, Title: hit.Title, Date: hit.Date})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// BlockSearchHit is a block matching a SearchBlocks query.
+type BlockSearchHit struct {
+	DocID      domain.DocumentID
+	Title      string // title of the document
+	BlockID    domain.BlockID
+	Content    string
+	Breadcrumb []string // first lines of the block's ancestors, outermost first
+	Fragments  []string // HTML-escaped, with matches in <mark> tags
+	Score      float64
+}
+
+// SearchBlocks returns up to limit blocks matching query, best first.
+// Hits whose document or block changed since they were indexed are
+// skipped. Indexes created before block search return no hits until
+// ReindexSearch is called.
+func (store *DocumentStore) SearchBlocks(query string, limit int) ([]BlockSearchHit, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	store.waitIndexed()
+	store.searchMu.RLock()
+	hits, err := store.search.SearchBlocks(query, limit)
+	store.searchMu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BlockSearchHit, 0, len(hits))
+	err = store.bolt.View(func(tx *bolt.Tx) error {
+		docs := make(map[uuid.UUID]*domain.Document)
+		for _, hit := range hits {
+			doc, ok := docs[hit.DocID]
+			if !ok {
+				var err error
+				doc, err = store.loadDocument(tx, domain.DocumentID(hit.DocID))
+				if err != nil && !errors.Is(err, ErrDocumentNotFound) {
+					return err
+				}
+				docs[hit.DocID] = doc
+			}
+			if doc == nil {
+				continue
+			}
+
+			i := findBlock(doc.Blocks, domain.BlockID(hit.BlockID))
+			if i < 0 {
+				continue
+			}
+			results = append(results, BlockSearchHit{
+				DocID:      doc.ID,
+				Title:      doc.Title,
+				BlockID:    doc.Blocks[i].ID,
+				Content:    doc.Blocks[i].Content,
+				Breadcrumb: Breadcrumb(doc.Blocks, i),
+				Fragments:  hit.Fragments,
+				Score:      hit.Score,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
 // ReindexSearch rebuilds the search index from scratch by re-indexing all
 // documents in the database. This operation acquires an exclusive write lock,
 // blocking all concurrent Save() and Search() operations until the reindex
 // is complete. Use this when the search index becomes corrupted or out of sync.
 func (store *DocumentStore) ReindexSearch() error {
+	if store.readOnly {
+		return ErrReadOnly
+	}
+
 	// Acquire write lock to ensure no other operations access the search index
 	// while we're closing, deleting, and recreating it.
 	store.searchMu.Lock()
 	defer store.searchMu.Unlock()
 
+	return store.reindexSearchLocked()
+}
+
+// reindexSearchLocked rebuilds the search index; searchMu must be held.
+func (store *DocumentStore) reindexSearchLocked() error {
 	// Create new index first before closing the old one to avoid leaving
 	// store.search pointing to a closed index if recreation fails.
 	oldSearch := store.search
@@ -639,7 +1058,7 @@ func (store *DocumentStore) ReindexSearch() error {
 	}
 
 	// Create new index
-	newSearch, err := openBleveSearch(bleveIndexPath(store.path))
+	newSearch, err := openBleveSearch(bleveIndexPath(store.path), Options{}, store.searchAnalysis)
 	if err != nil {
 		// If we can't create a new index after successfully deleting the old one,
 		// surface the error to the caller rather than attempting a second,
@@ -650,6 +1069,8 @@ func (store *DocumentStore) ReindexSearch() error {
 	// Assign new index to store
 	store.search = newSearch
 
+	defer store.checkIndexHealth()
+
 	// Reindex all documents
 	return store.bolt.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(store.bucketDocs)
@@ -680,6 +1101,10 @@ func (store *DocumentStore) GetReferences(title string) ([]domain.DocumentID, er
 }
 
 func (store *DocumentStore) ScheduleTask(date time.Time, docID domain.DocumentID, blockID domain.BlockID) error {
+	if store.readOnly {
+		return ErrReadOnly
+	}
+
 	// Create new time to set hours, minutes, seconds, nanoseconds to zero
 	scheduledTime := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
 	err := store.bolt.Update(func(tx *bolt.Tx) error {
@@ -724,6 +1149,10 @@ func (store *DocumentStore) GetScheduledTasks(date time.Time, days int) ([]domai
 // This includes removing from: documents bucket, time_index, title_index,
 // journal_index, references_index, scheduled_index, and Bleve search index.
 func (store *DocumentStore) Delete(id uuid.UUID) error {
+	if store.readOnly {
+		return ErrReadOnly
+	}
+
 	var docDb *DocDb
 
 	// First, load the document to get its metadata for index cleanup
@@ -750,73 +1179,78 @@ func (store *DocumentStore) Delete(id uuid.UUID) error {
 
 	// Delete from all BoltDB indexes in a single transaction
 	if err := store.bolt.Update(func(tx *bolt.Tx) error {
-		// Delete from documents bucket
-		docsBucket := tx.Bucket(store.bucketDocs)
-		if err := docsBucket.Delete([]byte(id.String())); err != nil {
-			return err
-		}
+		return store.deleteTx(tx, docDb)
+	}); err != nil {
+		return err
+	}
 
-		// Delete from time_index
-		timeBucket := tx.Bucket(store.bucketTimeIndex)
-		if timeBucket != nil {
-			date, _ := time.Parse(time.RFC3339, docDb.Date)
-			timeKey := date.UTC().Format(time.RFC3339)
-			_ = timeBucket.Delete([]byte(timeKey))
-		}
+	store.notifyIndexer()
+	return nil
+}
 
-		// Delete from title_index
-		titleBucket := tx.Bucket(store.bucketTitleIndex)
-		if titleBucket != nil {
-			titleKey := strings.ToLower(docDb.Title)
-			_ = titleBucket.Delete([]byte(titleKey))
-		}
+// deleteTx removes a document and its Bolt index entries within tx.
+func (store *DocumentStore) deleteTx(tx *bolt.Tx, docDb *DocDb) error {
+	id := docDb.ID
 
-		// Delete from journal_index (if it's a journal)
-		if docDb.IsJournal {
-			journalBucket := tx.Bucket(store.bucketJournalIndex)
-			if journalBucket != nil {
-				date, _ := time.Parse(time.RFC3339, docDb.Date)
-				// Use UTC to match how saveJournalIndex creates keys
-				utcDate := date.UTC()
-				dateKey := time.Date(utcDate.Year(), utcDate.Month(), utcDate.Day(), 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
-				_ = journalBucket.Delete([]byte(dateKey))
-			}
-		}
+	// Delete from documents bucket
+	docsBucket := tx.Bucket(store.bucketDocs)
+	if err := docsBucket.Delete([]byte(id.String())); err != nil {
+		return err
+	}
+	if err := enqueueIndex(tx, id); err != nil {
+		return err
+	}
+	if err := store.blockIndex.update(tx, id, docDb.Blocks, nil); err != nil {
+		return err
+	}
 
-		// Delete from references_index
-		if err := store.referencesIndex.delete(tx, docDb); err != nil {
-			return err
-		}
+	// Delete from time_index
+	timeBucket := tx.Bucket(store.bucketTimeIndex)
+	if timeBucket != nil {
+		date, _ := time.Parse(time.RFC3339, docDb.Date)
+		timeKey := date.UTC().Format(time.RFC3339)
+		_ = timeBucket.Delete([]byte(timeKey))
+	}
 
-		// Delete from scheduled_index
-		if err := store.scheduledIndex.delete(tx, docDb); err != nil {
-			return err
-		}
+	// Delete from title_index
+	titleBucket := tx.Bucket(store.bucketTitleIndex)
+	if titleBucket != nil {
+		titleKey := strings.ToLower(docDb.Title)
+		_ = titleBucket.Delete([]byte(titleKey))
+	}
 
-		// Delete from recents
-		if err := store.recentsDocs.delete(tx, docDb.ID); err != nil {
-			return err
+	// Delete from journal_index (if it's a journal)
+	if docDb.IsJournal {
+		journalBucket := tx.Bucket(store.bucketJournalIndex)
+		if journalBucket != nil {
+			date, _ := time.Parse(time.RFC3339, docDb.Date)
+			// Use UTC to match how saveJournalIndex creates keys
+			utcDate := date.UTC()
+			dateKey := time.Date(utcDate.Year(), utcDate.Month(), utcDate.Day(), 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
+			_ = journalBucket.Delete([]byte(dateKey))
 		}
+	}
 
-		return nil
-	}); err != nil {
+	// Delete from references_index
+	if err := store.referencesIndex.delete(tx, docDb); err != nil {
 		return err
 	}
 
-	// Delete from Bleve search index
-	store.searchMu.RLock()
-	err = store.search.DeleteDoc(id.String())
-	store.searchMu.RUnlock()
-
-	if err != nil {
-		log.Warnf("Failed to delete document from search index: %v", err)
-		// Don't return error - document is deleted from main storage
+	// Delete from scheduled_index
+	if err := store.scheduledIndex.delete(tx, docDb); err != nil {
+		return err
 	}
 
-	// Remove from failed indexes tracking if present
-	store.failedIndexesMu.Lock()
-	delete(store.failedIndexes, id.String())
-	store.failedIndexesMu.Unlock()
+	// Delete from recents
+	if err := store.recentsDocs.delete(tx, docDb.ID); err != nil {
+		return err
+	}
 
-	return nil
+	return store.recordEvent(tx, Event{
+		Type:      EventDocumentDeleted,
+		DocID:     domain.DocumentID(docDb.ID),
+		Title:     docDb.Title,
+		IsJournal: docDb.IsJournal,
+		doc:       docDbToDomain(docDb),
+	})
 }
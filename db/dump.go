@@ -0,0 +1,521 @@
+package db
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"glog/domain"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	dumpFormat  = "glog-dump"
+	dumpVersion = 2
+)
+
+// ErrDumpTargetExists is returned by Restore when the target database
+// already exists. Restore only ever writes into a fresh database.
+var ErrDumpTargetExists = errors.New("restore target already exists")
+
+// DumpRecord is one line of a JSON Lines database dump. The Type field
+// selects which of the other fields is set:
+//
+//   - "header":   Format and Version
+//   - "document": Document
+//   - "index":    Bucket, Key and Value (derived index metadata)
+//   - "data":     Bucket, Key and Value (user data other than documents,
+//     like saved searches, restored as it is)
+type DumpRecord struct {
+	Type     string        `json:"type"`
+	Format   string        `json:"format,omitempty"`
+	Version  int           `json:"version,omitempty"`
+	Document *DumpDocument `json:"document,omitempty"`
+	Bucket   string        `json:"bucket,omitempty"`
+	Key      string        `json:"key,omitempty"`
+	Value    interface{}   `json:"value,omitempty"`
+}
+
+// DumpDocument is the JSON form of a stored document.
+type DumpDocument struct {
+	ID        string      `json:"id"`
+	Title     string      `json:"title"`
+	Date      string      `json:"date"` // RFC 3339 format, as stored
+	IsJournal bool        `json:"is_journal"`
+	Blocks    []DumpBlock `json:"blocks"`
+}
+
+// DumpBlock is the JSON form of a stored block.
+type DumpBlock struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+	Indent  int    `json:"indent"`
+}
+
+// dumpScheduledTask is the JSON form of a scheduled_index entry.
+type dumpScheduledTask struct {
+	ID      string `json:"id"`
+	DocID   string `json:"doc_id"`
+	BlockID string `json:"block_id"`
+}
+
+// RestoreResult contains the results of a restore operation.
+type RestoreResult struct {
+	Documents int
+	Skipped   int // index records, which are rebuilt rather than restored
+	Data      int // data records restored, see DumpRecord
+	Errors    []error
+}
+
+// Dump writes every document, the user data and the derived index metadata to w as JSON
+// Lines. Documents and index entries are written in key order and set
+// values are sorted, so two dumps of the same database are identical and
+// can be diffed to spot corruption.
+func (store *DocumentStore) Dump(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(DumpRecord{Type: "header", Format: dumpFormat, Version: dumpVersion}); err != nil {
+		return err
+	}
+
+	return store.bolt.View(func(tx *bolt.Tx) error {
+		err := tx.Bucket(store.bucketDocs).ForEach(func(k, v []byte) error {
+			docDb, err := decodeDocDb(v)
+			if err != nil {
+				return fmt.Errorf("document %s: %w", k, err)
+			}
+			return enc.Encode(DumpRecord{Type: "document", Document: toDumpDocument(docDb)})
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, data := range store.dumpData() {
+			if err := dumpBucket(tx, enc, "data", data.dumpIndex); err != nil {
+				return err
+			}
+		}
+		for _, index := range store.dumpIndexes() {
+			if err := dumpBucket(tx, enc, "index", index); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func dumpBucket(tx *bolt.Tx, enc *json.Encoder, recordType string, index dumpIndex) error {
+	bucket := tx.Bucket([]byte(index.bucket))
+	if bucket == nil {
+		return nil
+	}
+	return bucket.ForEach(func(k, v []byte) error {
+		value, err := index.decode(v)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", index.bucket, k, err)
+		}
+		return enc.Encode(DumpRecord{Type: recordType, Bucket: index.bucket, Key: string(k), Value: value})
+	})
+}
+
+// dumpIndex describes how to render the values of an index bucket.
+type dumpIndex struct {
+	bucket string
+	decode func(v []byte) (interface{}, error)
+}
+
+// dumpData describes a bucket of user data: Dump renders its values and
+// Restore encodes them back, as they are.
+type dumpData struct {
+	dumpIndex
+	encode func(tx *bolt.Tx, key string, value interface{}) ([]byte, error)
+}
+
+func (store *DocumentStore) dumpData() []dumpData {
+	return []dumpData{
+		// Settings values are JSON
+		{dumpIndex{string(settingsBucket), decodeDumpJSON}, func(tx *bolt.Tx, key string, value interface{}) ([]byte, error) {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			if key == string(searchAnalysisKey) {
+				var analysis SearchAnalysis
+				if err := json.Unmarshal(data, &analysis); err != nil {
+					return nil, err
+				}
+				if analysis, err = analysis.normalize(); err != nil {
+					return nil, err
+				}
+				return json.Marshal(analysis)
+			}
+			return data, nil
+		}},
+		{dumpIndex{string(savedSearchesBucket), decodeDumpJSON}, func(tx *bolt.Tx, key string, value interface{}) ([]byte, error) {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			var search SavedSearch
+			if err := json.Unmarshal(data, &search); err != nil {
+				return nil, err
+			}
+			return json.Marshal(search)
+		}},
+		{dumpIndex{string(savedSearchMatchesBucket), decodeSortedUUIDs}, func(tx *bolt.Tx, key string, value interface{}) ([]byte, error) {
+			// Documents that failed to restore are left out
+			docs := tx.Bucket(store.bucketDocs)
+			ids := make(map[uuid.UUID]struct{})
+			for _, id := range parseDumpedIDs(value) {
+				if docs.Get([]byte(id.String())) != nil {
+					ids[id] = struct{}{}
+				}
+			}
+			return encodeUUIDSet(ids)
+		}},
+	}
+}
+
+func decodeDumpJSON(v []byte) (interface{}, error) {
+	if !json.Valid(v) {
+		return nil, errors.New("invalid JSON")
+	}
+	return json.RawMessage(v), nil
+}
+
+func decodeSortedUUIDs(v []byte) (interface{}, error) {
+	ids := decodeUUIDSet(v)
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id.String())
+	}
+	sort.Strings(sorted)
+	return sorted, nil
+}
+
+func (store *DocumentStore) dumpIndexes() []dumpIndex {
+	asString := func(v []byte) (interface{}, error) {
+		return string(v), nil
+	}
+
+	return []dumpIndex{
+		{string(store.bucketTitleIndex), asString},
+		{string(store.bucketTimeIndex), asString},
+		{string(store.bucketJournalIndex), asString},
+		{string(store.referencesIndex.referenceIndex), decodeSortedUUIDs},
+		{string(store.referencesIndex.docReferenceIndex), func(v []byte) (interface{}, error) {
+			var titles map[string]struct{}
+			if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&titles); err != nil {
+				return nil, err
+			}
+			return sortedKeys(titles), nil
+		}},
+		{string(store.scheduledIndex.scheduledIndex), func(v []byte) (interface{}, error) {
+			tasks, err := decodeScheduleTasksDb(v)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]dumpScheduledTask, 0, len(tasks))
+			for _, task := range tasks {
+				out = append(out, dumpScheduledTask{
+					ID:      task.ID.String(),
+					DocID:   task.DocDbID.String(),
+					BlockID: task.BlockDbID.String(),
+				})
+			}
+			sort.Slice(out, func(i, j int) bool {
+				if out[i].DocID != out[j].DocID {
+					return out[i].DocID < out[j].DocID
+				}
+				return out[i].BlockID < out[j].BlockID
+			})
+			return out, nil
+		}},
+		{string(store.scheduledIndex.scheduledInvertedIndex), func(v []byte) (interface{}, error) {
+			dates, err := decodeScheduledDates(v)
+			if err != nil {
+				return nil, err
+			}
+			return sortedKeys(dates), nil
+		}},
+		{string(store.recentsDocs.recentsBucket), func(v []byte) (interface{}, error) {
+			ids, err := deserializeRecents(v)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]string, len(ids))
+			for i, id := range ids {
+				out[i] = id.String()
+			}
+			return out, nil
+		}},
+	}
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func decodeDocDb(data []byte) (*DocDb, error) {
+	var docDb DocDb
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&docDb); err != nil {
+		return nil, err
+	}
+	return &docDb, nil
+}
+
+func toDumpDocument(docDb *DocDb) *DumpDocument {
+	doc := &DumpDocument{
+		ID:        docDb.ID.String(),
+		Title:     docDb.Title,
+		Date:      docDb.Date,
+		IsJournal: docDb.IsJournal,
+		Blocks:    make([]DumpBlock, 0, len(docDb.Blocks)),
+	}
+	for _, block := range docDb.Blocks {
+		if block == nil {
+			continue
+		}
+		doc.Blocks = append(doc.Blocks, DumpBlock{
+			ID:      block.ID.String(),
+			Content: block.Content,
+			Indent:  block.Indent,
+		})
+	}
+	return doc
+}
+
+// NewDumpDocument converts a domain.Document into its JSON form.
+func NewDumpDocument(doc *domain.Document) *DumpDocument {
+	d := &DumpDocument{
+		ID:        doc.ID.String(),
+		Title:     doc.Title,
+		Date:      doc.Date.UTC().Format(time.RFC3339),
+		IsJournal: doc.IsJournal,
+		Blocks:    make([]DumpBlock, len(doc.Blocks)),
+	}
+	for i, block := range doc.Blocks {
+		d.Blocks[i] = DumpBlock{
+			ID:      block.ID.String(),
+			Content: block.Content,
+			Indent:  block.Indent,
+		}
+	}
+	return d
+}
+
+// ToDomain converts a dumped document back into a domain.Document.
+func (d *DumpDocument) ToDomain() (*domain.Document, error) {
+	id, err := uuid.Parse(d.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing document id: %s", err)
+	}
+
+	date, err := time.Parse(time.RFC3339, d.Date)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing date: %s", err)
+	}
+
+	doc := &domain.Document{
+		ID:        domain.DocumentID(id),
+		Title:     d.Title,
+		Date:      date,
+		IsJournal: d.IsJournal,
+		Blocks:    make([]*domain.Block, len(d.Blocks)),
+	}
+
+	for i, b := range d.Blocks {
+		blockID, err := uuid.Parse(b.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing block id: %s", err)
+		}
+		doc.Blocks[i] = &domain.Block{
+			ID:      domain.BlockID(blockID),
+			Content: b.Content,
+			Indent:  b.Indent,
+		}
+	}
+
+	return doc, nil
+}
+
+// Restore builds a fresh database at path from a dump written by Dump.
+// Documents are replayed through Save, so every derived index is rebuilt
+// from the documents themselves rather than copied from the dump; the
+// search index is rebuilt from scratch once all documents are saved.
+// Restore refuses to write into an existing database.
+func Restore(r io.Reader, path string) (*RestoreResult, error) {
+	for _, p := range []string{path, bleveIndexPath(path)} {
+		if _, err := os.Stat(p); err == nil {
+			return nil, fmt.Errorf("%w: %s", ErrDumpTargetExists, p)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	dec := json.NewDecoder(r)
+
+	var header DumpRecord
+	if err := dec.Decode(&header); err != nil {
+		return nil, fmt.Errorf("failed to read dump header: %w", err)
+	}
+	if header.Type != "header" || header.Format != dumpFormat {
+		return nil, errors.New("not a glog dump")
+	}
+	if header.Version > dumpVersion {
+		return nil, fmt.Errorf("unsupported dump version %d", header.Version)
+	}
+
+	store, err := NewDocumentStore(path)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	result := &RestoreResult{}
+	var recents []uuid.UUID
+	var data []DumpRecord
+
+	for {
+		var record DumpRecord
+		if err := dec.Decode(&record); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return result, fmt.Errorf("failed to read dump: %w", err)
+		}
+
+		switch record.Type {
+		case "document":
+			if record.Document == nil {
+				result.Errors = append(result.Errors, errors.New("document record without document"))
+				continue
+			}
+			doc, err := record.Document.ToDomain()
+			if err == nil {
+				err = store.Save(doc)
+			}
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("document %s: %w", record.Document.ID, err))
+				continue
+			}
+			result.Documents++
+		case "data":
+			data = append(data, record)
+		case "index":
+			// Recently opened documents are user state rather than derived
+			// from documents, so they are the only index carried over.
+			if record.Bucket == string(store.recentsDocs.recentsBucket) {
+				recents = parseDumpedIDs(record.Value)
+			}
+			result.Skipped++
+		default:
+			result.Errors = append(result.Errors, fmt.Errorf("unknown record type %q", record.Type))
+		}
+	}
+
+	// After the documents, which data like saved search matches refers to
+	restored, errs := store.restoreData(data)
+	result.Data = restored
+	result.Errors = append(result.Errors, errs...)
+
+	if len(recents) > 0 {
+		if err := store.restoreRecents(recents); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("recents: %w", err))
+		}
+	}
+
+	// The search index is rebuilt with the restored analysis settings
+	analysis, err := store.GetSearchAnalysis()
+	if err != nil {
+		return result, err
+	}
+	store.searchMu.Lock()
+	store.searchAnalysis = analysis
+	store.searchMu.Unlock()
+
+	if err := store.ReindexSearch(); err != nil {
+		return result, fmt.Errorf("failed to rebuild search index: %w", err)
+	}
+
+	return result, nil
+}
+
+// restoreData writes the data records of a dump, returning how many were
+// restored and an error for each that could not be.
+func (store *DocumentStore) restoreData(records []DumpRecord) (int, []error) {
+	encoders := make(map[string]dumpData)
+	for _, data := range store.dumpData() {
+		encoders[data.bucket] = data
+	}
+
+	restored := 0
+	var errs []error
+	err := store.bolt.Update(func(tx *bolt.Tx) error {
+		for _, record := range records {
+			data, ok := encoders[record.Bucket]
+			if !ok {
+				errs = append(errs, fmt.Errorf("unknown data bucket %q", record.Bucket))
+				continue
+			}
+			value, err := data.encode(tx, record.Key, record.Value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %w", record.Bucket, record.Key, err))
+				continue
+			}
+			bucket, err := tx.CreateBucketIfNotExists([]byte(record.Bucket))
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(record.Key), value); err != nil {
+				return err
+			}
+			restored++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, append(errs, err)
+	}
+	return restored, errs
+}
+
+// restoreRecents replaces the recents list, keeping only documents that exist.
+func (store *DocumentStore) restoreRecents(ids []uuid.UUID) error {
+	return store.bolt.Update(func(tx *bolt.Tx) error {
+		docs := tx.Bucket(store.bucketDocs)
+		kept := make([]uuid.UUID, 0, len(ids))
+		for _, id := range ids {
+			if docs.Get([]byte(id.String())) != nil {
+				kept = append(kept, id)
+			}
+		}
+		return store.recentsDocs.put(tx, kept)
+	})
+}
+
+func parseDumpedIDs(value interface{}) []uuid.UUID {
+	items, _ := value.([]interface{})
+	ids := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		s, _ := item.(string)
+		if id, err := uuid.Parse(s); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
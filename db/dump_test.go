@@ -0,0 +1,180 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"glog/domain"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDumpAndRestore(t *testing.T) {
+	store, err := NewDocumentStore("./testdump.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		err := store.Close()
+		if err != nil {
+			t.Errorf("Failed to close DocumentStore: %v", err)
+		}
+
+		_ = os.Remove("./testdump.db")
+		_ = os.RemoveAll("./testdump.db.bleve")
+	}()
+
+	project := &domain.Document{
+		ID:    domain.DocumentID(uuid.New()),
+		Title: "Project",
+		Date:  time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC),
+		Blocks: []*domain.Block{
+			{ID: domain.BlockID(uuid.New()), Content: "Kickoff", Indent: 0},
+		},
+	}
+	journal := &domain.Document{
+		ID:        domain.DocumentID(uuid.New()),
+		Title:     "Monday, January 15, 2024",
+		Date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		IsJournal: true,
+		Blocks: []*domain.Block{
+			{ID: domain.BlockID(uuid.New()), Content: "Review [[Project]] /scheduled 2024-01-20", Indent: 0},
+			{ID: domain.BlockID(uuid.New()), Content: "Details", Indent: 1},
+		},
+	}
+	for _, doc := range []*domain.Document{project, journal} {
+		if err := store.Save(doc); err != nil {
+			t.Fatalf("Failed to save document: %v", err)
+		}
+	}
+	if _, err := store.LoadDocument(project.ID); err != nil {
+		t.Fatalf("Failed to load document: %v", err)
+	}
+	if err := store.SetSearchAnalysis(SearchAnalysis{Languages: []string{"en"}}); err != nil {
+		t.Fatalf("SetSearchAnalysis failed: %v", err)
+	}
+	search, err := store.SaveSearch(SavedSearch{Name: "Project notes", Query: "links:[[Project]]", Notify: true})
+	if err != nil {
+		t.Fatalf("SaveSearch failed: %v", err)
+	}
+
+	var first, second bytes.Buffer
+	if err := store.Dump(&first); err != nil {
+		t.Fatalf("Dump() error = %v", err)
+	}
+	if err := store.Dump(&second); err != nil {
+		t.Fatalf("Dump() error = %v", err)
+	}
+	if first.String() != second.String() {
+		t.Error("Expected two dumps of the same database to be identical")
+	}
+
+	buckets := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(first.String()), "\n") {
+		var record DumpRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid dump line %q: %v", line, err)
+		}
+		if record.Type == "index" || record.Type == "data" {
+			buckets[record.Bucket] = true
+		}
+	}
+	for _, bucket := range []string{"title_index", "references_index", "scheduled_index", "recents_index", "saved_searches", "saved_search_matches", "settings"} {
+		if !buckets[bucket] {
+			t.Errorf("Expected dump to contain %s entries", bucket)
+		}
+	}
+
+	restorePath := filepath.Join(t.TempDir(), "restored.db")
+	result, err := Restore(bytes.NewReader(first.Bytes()), restorePath)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if result.Documents != 2 || result.Data != 3 || len(result.Errors) != 0 {
+		t.Fatalf("unexpected restore result: %+v", result)
+	}
+
+	restored, err := NewDocumentStore(restorePath)
+	if err != nil {
+		t.Fatalf("Failed to open restored DocumentStore: %v", err)
+	}
+	defer restored.Close()
+
+	got, err := restored.LoadDocumentByTitle("Monday, January 15, 2024")
+	if err != nil {
+		t.Fatalf("Failed to load restored journal: %v", err)
+	}
+	if len(got.Blocks) != 2 || got.Blocks[1].Indent != 1 || got.Blocks[0].ID != journal.Blocks[0].ID {
+		t.Errorf("restored journal blocks mismatch: %+v", got.Blocks)
+	}
+
+	refs, err := restored.GetReferences("Project")
+	if err != nil || len(refs) != 1 || refs[0] != journal.ID {
+		t.Errorf("references not rebuilt: %v, %v", refs, err)
+	}
+
+	tasks, err := restored.GetScheduledTasks(time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC), 1)
+	if err != nil || len(tasks) != 1 {
+		t.Errorf("scheduled tasks not rebuilt: %v, %v", tasks, err)
+	}
+
+	ids, err := restored.Search("kickoff")
+	if err != nil || len(ids) != 1 || ids[0] != project.ID {
+		t.Errorf("search index not rebuilt: %v, %v", ids, err)
+	}
+
+	// Rebuilt with the restored analysis, which stems "kickoffs"
+	if analysis, err := restored.GetSearchAnalysis(); err != nil || len(analysis.Languages) != 1 {
+		t.Errorf("search analysis not restored: %+v, %v", analysis, err)
+	}
+	if ids, err := restored.Search("kickoffs"); err != nil || len(ids) != 1 {
+		t.Errorf("search index not rebuilt with the restored analysis: %v, %v", ids, err)
+	}
+
+	gotSearch, err := restored.GetSavedSearch(search.ID)
+	if err != nil || gotSearch.Name != search.Name || gotSearch.Query != search.Query || !gotSearch.Notify || !gotSearch.Created.Equal(search.Created) {
+		t.Errorf("saved search not restored: %+v, %v", gotSearch, err)
+	}
+	var restoredDump bytes.Buffer
+	if err := restored.Dump(&restoredDump); err != nil {
+		t.Fatalf("Dump() error = %v", err)
+	}
+	for _, bucket := range []string{"saved_searches", "saved_search_matches"} {
+		if got, want := dumpedRecords(t, restoredDump.String(), bucket), dumpedRecords(t, first.String(), bucket); got != want {
+			t.Errorf("%s not restored: got %s, want %s", bucket, got, want)
+		}
+	}
+}
+
+// dumpedRecords returns the dump lines of a bucket.
+func dumpedRecords(t *testing.T, dump string, bucket string) string {
+	t.Helper()
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(dump), "\n") {
+		var record DumpRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid dump line %q: %v", line, err)
+		}
+		if record.Bucket == bucket {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestRestoreRefusesExistingDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "existing.db")
+	if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Restore(strings.NewReader(`{"type":"header","format":"glog-dump","version":1}`), path)
+	if !errors.Is(err, ErrDumpTargetExists) {
+		t.Errorf("Restore() error = %v, want ErrDumpTargetExists", err)
+	}
+}
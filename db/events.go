@@ -0,0 +1,276 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"glog/domain"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+// EventType identifies the kind of change an Event describes.
+type EventType string
+
+const (
+	EventDocumentSaved   EventType = "DocumentSaved"
+	EventDocumentDeleted EventType = "DocumentDeleted"
+	EventTaskScheduled   EventType = "TaskScheduled"
+	EventIndexFailed     EventType = "IndexFailed"
+	EventSearchMatched   EventType = "SearchMatched"
+)
+
+// changeLogRetention is the number of newest events the change log keeps.
+// Older events are trimmed as new ones are appended, so the log does not
+// grow with every autosave.
+const changeLogRetention = 10000
+
+// Event is a change to the store. Events are recorded in the change log
+// in the transaction that makes the change and published to subscribers
+// once it commits.
+type Event struct {
+	Seq       uint64 // position in the change log, increasing by one per event
+	Type      EventType
+	Time      time.Time
+	DocID     domain.DocumentID
+	Title     string
+	IsJournal bool
+	BlockID   domain.BlockID // TaskScheduled
+	Date      time.Time      // TaskScheduled: the day the task is scheduled for
+	Error     string         // IndexFailed
+	SearchID  uuid.UUID      // SearchMatched: the saved search the document started matching
+	Search    string         // SearchMatched: the name of the saved search
+
+	doc *domain.Document // not persisted, see Document
+}
+
+// Document returns the document as saved, or as it was before deletion,
+// for DocumentSaved and DocumentDeleted events received from a
+// subscription. It is nil for other events and for events read with Changes.
+func (ev Event) Document() *domain.Document {
+	return ev.doc
+}
+
+// Subscription receives the events published after Subscribe.
+type Subscription struct {
+	C  <-chan Event
+	ch chan Event
+}
+
+// changeLog is the persisted, sequenced list of events.
+type changeLog struct {
+	db     *bolt.DB
+	bucket []byte // keys are big-endian sequence numbers, values are encoded Events
+	retain uint64 // number of events kept, 0 for all
+}
+
+func newChangeLog(db *bolt.DB) (*changeLog, error) {
+	bucket := []byte("change_log")
+	err := ensureBuckets(db, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return &changeLog{
+		db:     db,
+		bucket: bucket,
+		retain: changeLogRetention,
+	}, nil
+}
+
+func seqKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+func (c *changeLog) append(tx *bolt.Tx, ev *Event) error {
+	bucket := tx.Bucket(c.bucket)
+	seq, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+	ev.Seq = seq
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(ev); err != nil {
+		return err
+	}
+	if err := bucket.Put(seqKey(seq), buf.Bytes()); err != nil {
+		return err
+	}
+	return c.trim(bucket, seq)
+}
+
+// trim deletes the events that fell out of the retention window after
+// the event last was appended.
+func (c *changeLog) trim(bucket *bolt.Bucket, last uint64) error {
+	if c.retain == 0 || last <= c.retain {
+		return nil
+	}
+	cutoff := seqKey(last - c.retain)
+	cursor := bucket.Cursor()
+	for k, _ := cursor.First(); k != nil && bytes.Compare(k, cutoff) <= 0; k, _ = cursor.First() {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *changeLog) since(tx *bolt.Tx, after uint64, limit int) ([]Event, error) {
+	events := []Event{}
+	cursor := tx.Bucket(c.bucket).Cursor()
+	for k, v := cursor.Seek(seqKey(after + 1)); k != nil; k, v = cursor.Next() {
+		if limit > 0 && len(events) >= limit {
+			break
+		}
+
+		var ev Event
+		if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&ev); err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// eventBus fans events out to subscribers.
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+func (b *eventBus) publish(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers {
+		select {
+		case sub.ch <- ev:
+		default:
+			// Never block a writer on a slow subscriber; it can catch up
+			// from the change log with Changes.
+			log.Warnf("Dropped %s event %d for a slow subscriber", ev.Type, ev.Seq)
+		}
+	}
+}
+
+// recordEvent appends ev to the change log within tx and publishes it
+// when tx commits.
+func (store *DocumentStore) recordEvent(tx *bolt.Tx, ev Event) error {
+	ev.Time = time.Now().UTC()
+	if err := store.changeLog.append(tx, &ev); err != nil {
+		return err
+	}
+	tx.OnCommit(func() {
+		store.events.publish(ev)
+	})
+	return nil
+}
+
+// recordScheduledTasks records a TaskScheduled event for every date in
+// doc that is not in the scheduled index yet. It must run before the
+// index is updated.
+func (store *DocumentStore) recordScheduledTasks(tx *bolt.Tx, doc *DocDb) error {
+	for _, block := range doc.Blocks {
+		oldDates, err := store.scheduledIndex.getInvertedIndexDates(tx, doc.ID, block.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, date := range extractScheduledDates(block.Content) {
+			if _, ok := oldDates[date.Format("2006-01-02")]; ok {
+				continue
+			}
+			err := store.recordEvent(tx, Event{
+				Type:      EventTaskScheduled,
+				DocID:     domain.DocumentID(doc.ID),
+				Title:     doc.Title,
+				IsJournal: doc.IsJournal,
+				BlockID:   domain.BlockID(block.ID),
+				Date:      date,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recordIndexFailed records that doc could not be added to the search index.
+func (store *DocumentStore) recordIndexFailed(doc *DocDb, indexErr error) {
+	if store.readOnly {
+		return
+	}
+
+	err := store.bolt.Update(func(tx *bolt.Tx) error {
+		return store.recordEvent(tx, Event{
+			Type:      EventIndexFailed,
+			DocID:     domain.DocumentID(doc.ID),
+			Title:     doc.Title,
+			IsJournal: doc.IsJournal,
+			Error:     indexErr.Error(),
+		})
+	})
+	if err != nil {
+		log.Errorf("Failed to record index failure of %s: %v", doc.ID, err)
+	}
+}
+
+// Subscribe returns a subscription receiving every event published from
+// now on. Events are dropped rather than blocking writers when the
+// channel, of the given buffer size, is full; use the Seq of the last
+// received event with Changes to catch up.
+func (store *DocumentStore) Subscribe(buffer int) *Subscription {
+	ch := make(chan Event, buffer)
+	sub := &Subscription{C: ch, ch: ch}
+
+	store.events.mu.Lock()
+	store.events.subscribers[sub] = struct{}{}
+	store.events.mu.Unlock()
+	return sub
+}
+
+// Unsubscribe stops a subscription and closes its channel.
+func (store *DocumentStore) Unsubscribe(sub *Subscription) {
+	store.events.mu.Lock()
+	defer store.events.mu.Unlock()
+
+	if _, ok := store.events.subscribers[sub]; ok {
+		delete(store.events.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// Changes returns the events of the change log after the cursor, oldest
+// first, up to limit events (0 for all). Pass 0 to read from the start.
+//
+// The log keeps the newest changeLogRetention events only, so a cursor
+// that fell behind gets a gap: the first event's Seq is then greater than
+// after+1, and the events in between are gone. Callers that must see
+// every change should reload the current state instead.
+func (store *DocumentStore) Changes(after uint64, limit int) ([]Event, error) {
+	var events []Event
+	err := store.bolt.View(func(tx *bolt.Tx) error {
+		var err error
+		events, err = store.changeLog.since(tx, after, limit)
+		return err
+	})
+	return events, err
+}
+
+// LastChangeSeq returns the sequence number of the newest event, or 0.
+func (store *DocumentStore) LastChangeSeq() (uint64, error) {
+	var seq uint64
+	err := store.bolt.View(func(tx *bolt.Tx) error {
+		seq = tx.Bucket(store.changeLog.bucket).Sequence()
+		return nil
+	})
+	return seq, err
+}
@@ -0,0 +1,174 @@
+package db
+
+import (
+	"fmt"
+	"glog/domain"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func receive(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case ev := <-sub.C:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for an event")
+		return Event{}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	store, err := NewDocumentStore("./testevents.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = os.Remove("./testevents.db")
+		_ = os.RemoveAll("./testevents.db.bleve")
+	}()
+
+	sub := store.Subscribe(16)
+
+	blockID := domain.BlockID(uuid.New())
+	doc := &domain.Document{
+		ID:    domain.DocumentID(uuid.New()),
+		Title: "Plans",
+		Date:  time.Now(),
+		Blocks: []*domain.Block{
+			{ID: blockID, Content: "Renew passport /scheduled 2026-11-02"},
+		},
+	}
+	if err := store.Save(doc); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	scheduled := receive(t, sub)
+	if scheduled.Type != EventTaskScheduled || scheduled.BlockID != blockID || scheduled.Date.Format("2006-01-02") != "2026-11-02" {
+		t.Errorf("Expected TaskScheduled for the block, got %+v", scheduled)
+	}
+	saved := receive(t, sub)
+	if saved.Type != EventDocumentSaved || saved.DocID != doc.ID || saved.Title != "Plans" {
+		t.Errorf("Expected DocumentSaved, got %+v", saved)
+	}
+	if saved.Seq != scheduled.Seq+1 {
+		t.Errorf("Expected consecutive sequence numbers, got %d and %d", scheduled.Seq, saved.Seq)
+	}
+
+	// Saving again does not schedule the task twice
+	if err := store.Save(doc); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if ev := receive(t, sub); ev.Type != EventDocumentSaved {
+		t.Errorf("Expected only DocumentSaved, got %+v", ev)
+	}
+
+	if err := store.Delete(uuid.UUID(doc.ID)); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	deleted := receive(t, sub)
+	if deleted.Type != EventDocumentDeleted || deleted.DocID != doc.ID {
+		t.Errorf("Expected DocumentDeleted, got %+v", deleted)
+	}
+
+	store.Unsubscribe(sub)
+	if _, ok := <-sub.C; ok {
+		t.Errorf("Expected the channel to be closed after Unsubscribe")
+	}
+
+	// A failed save publishes nothing
+	sub = store.Subscribe(16)
+	clash := &domain.Document{ID: domain.DocumentID(uuid.New()), Title: "Clash", Date: time.Now()}
+	other := &domain.Document{ID: domain.DocumentID(uuid.New()), Title: "clash", Date: time.Now()}
+	_ = store.Save(clash)
+	receive(t, sub)
+	if err := store.Save(other); err == nil {
+		t.Fatalf("Expected a duplicate title error")
+	}
+	select {
+	case ev := <-sub.C:
+		t.Errorf("Expected no event for a failed save, got %+v", ev)
+	default:
+	}
+	store.Unsubscribe(sub)
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Failed to close DocumentStore: %v", err)
+	}
+
+	// The change log survives reopening and continues its sequence
+	store, err = NewDocumentStore("./testevents.db")
+	if err != nil {
+		t.Fatalf("Failed to reopen DocumentStore: %v", err)
+	}
+	defer store.Close()
+
+	all, err := store.Changes(0, 0)
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if len(all) != 5 {
+		t.Fatalf("Expected 5 logged events, got %d", len(all))
+	}
+	for i, ev := range all {
+		if ev.Seq != uint64(i+1) {
+			t.Errorf("Event %d has Seq %d", i, ev.Seq)
+		}
+	}
+
+	resumed, err := store.Changes(deleted.Seq, 10)
+	if err != nil || len(resumed) != 1 || resumed[0].Title != "Clash" {
+		t.Errorf("Expected to resume after the delete, got %+v (err %v)", resumed, err)
+	}
+	if page, _ := store.Changes(0, 2); len(page) != 2 {
+		t.Errorf("Expected the limit to be applied, got %d events", len(page))
+	}
+
+	if err := store.Save(clash); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if last, err := store.LastChangeSeq(); err != nil || last != 6 {
+		t.Errorf("Expected the sequence to continue at 6, got %d (err %v)", last, err)
+	}
+}
+
+func TestChangeLogRetention(t *testing.T) {
+	store, err := NewDocumentStore("./testchangelog.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove("./testchangelog.db")
+		_ = os.RemoveAll("./testchangelog.db.bleve")
+	}()
+	store.changeLog.retain = 5
+
+	for i := 0; i < 8; i++ {
+		saveTestDoc(t, store, fmt.Sprintf("Page %d", i), "text")
+	}
+
+	last, err := store.LastChangeSeq()
+	if err != nil || last != 8 {
+		t.Fatalf("Expected 8 events, got %d (err %v)", last, err)
+	}
+	events, err := store.Changes(0, 0)
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if len(events) != 5 || events[0].Seq != 4 || events[4].Seq != 8 {
+		t.Errorf("Expected only the 5 newest events, got %+v", events)
+	}
+
+	// A cursor behind the trimmed events sees a gap
+	events, err = store.Changes(2, 2)
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Seq != 4 {
+		t.Errorf("Expected the events after the gap, got %+v", events)
+	}
+}
@@ -0,0 +1,28 @@
+package db
+
+import (
+	"glog/domain"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// testDoc returns a page titled title with one block per content.
+func testDoc(title string, contents ...string) *domain.Document {
+	doc := &domain.Document{ID: domain.DocumentID(uuid.New()), Title: title, Date: time.Now()}
+	for _, content := range contents {
+		doc.Blocks = append(doc.Blocks, &domain.Block{ID: domain.BlockID(uuid.New()), Content: content})
+	}
+	return doc
+}
+
+// saveTestDoc saves a testDoc to store.
+func saveTestDoc(t *testing.T, store *DocumentStore, title string, contents ...string) *domain.Document {
+	t.Helper()
+	doc := testDoc(title, contents...)
+	if err := store.Save(doc); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	return doc
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"glog/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JournalTitleLayout is the layout of journal titles, e.g. "Sunday, October 18, 2026".
+const JournalTitleLayout = "Monday, January 2, 2006"
+
+// JournalDay returns the UTC day journals of t are keyed by.
+func JournalDay(t time.Time) time.Time {
+	utc := t.UTC()
+	return time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// NewJournal creates an empty journal for now with a single empty block.
+// The title uses the local date for display, the date is the UTC day so
+// it matches the journal index keys.
+func NewJournal(now time.Time) *domain.Document {
+	return &domain.Document{
+		ID:        domain.DocumentID(uuid.New()),
+		Title:     now.Local().Format(JournalTitleLayout),
+		Date:      JournalDay(now),
+		IsJournal: true,
+		Blocks: []*domain.Block{
+			{
+				ID:      domain.BlockID(uuid.New()),
+				Content: "",
+				Indent:  0,
+			},
+		},
+	}
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"errors"
+	"glog/domain"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestUpdateJournal(t *testing.T) {
+	store, err := NewDocumentStore("./testjournal.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		err := store.Close()
+		if err != nil {
+			t.Errorf("Failed to close DocumentStore: %v", err)
+		}
+
+		_ = os.Remove("./testjournal.db")
+		_ = os.RemoveAll("./testjournal.db.bleve")
+	}()
+
+	now := time.Date(2026, 10, 18, 15, 30, 0, 0, time.UTC)
+	appendBlock := func(content string) func(doc *domain.Document) error {
+		return func(doc *domain.Document) error {
+			doc.Blocks = append(doc.Blocks, &domain.Block{ID: domain.BlockID(uuid.New()), Content: content})
+			return nil
+		}
+	}
+
+	// Creates the journal when it does not exist
+	first, err := store.UpdateJournal(now, appendBlock("first /scheduled 2026-10-20"))
+	if err != nil {
+		t.Fatalf("UpdateJournal failed: %v", err)
+	}
+	if !first.IsJournal || !first.Date.Equal(JournalDay(now)) {
+		t.Errorf("Expected a journal dated %v, got %+v", JournalDay(now), first)
+	}
+	if first.Title != now.Local().Format(JournalTitleLayout) {
+		t.Errorf("Unexpected journal title %q", first.Title)
+	}
+
+	// Updates the same journal later that day
+	second, err := store.UpdateJournal(now.Add(time.Hour), appendBlock("second"))
+	if err != nil {
+		t.Fatalf("UpdateJournal failed: %v", err)
+	}
+	if second.ID != first.ID {
+		t.Fatalf("Expected the existing journal %v, got %v", first.ID, second.ID)
+	}
+
+	docs, err := store.LoadJournals(now, now)
+	if err != nil || len(docs) != 1 {
+		t.Fatalf("Expected one journal, got %d (err %v)", len(docs), err)
+	}
+	if got := len(docs[0].Blocks); got != 3 {
+		t.Errorf("Expected 3 blocks (empty, first, second), got %d", got)
+	}
+
+	tasks, err := store.GetScheduledTasks(time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), 1)
+	if err != nil || len(tasks) != 1 {
+		t.Errorf("Expected the captured block to be scheduled, got %v (err %v)", tasks, err)
+	}
+
+	// Errors from fn abort without saving
+	boom := errors.New("boom")
+	if _, err := store.UpdateJournal(now.Add(48*time.Hour), func(doc *domain.Document) error { return boom }); !errors.Is(err, boom) {
+		t.Errorf("Expected fn error, got %v", err)
+	}
+	if docs, _ := store.LoadJournals(now.Add(48*time.Hour), now.Add(48*time.Hour)); len(docs) != 0 {
+		t.Errorf("Expected no journal to be saved when fn fails")
+	}
+}
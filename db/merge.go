@@ -0,0 +1,167 @@
+package db
+
+import (
+	"fmt"
+	"glog/domain"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+// MergePlan lists what merging one document into another changes.
+type MergePlan struct {
+	Keep     DocumentSummary   // receives the blocks of Merge
+	Merge    DocumentSummary   // deleted
+	Blocks   int               // number of blocks appended to Keep
+	Relinked []DocumentSummary // documents whose [[Merge]] links are rewritten to [[Keep]]
+}
+
+// Documents returns every document the merge touches, Keep first.
+func (p *MergePlan) Documents() []DocumentSummary {
+	docs := []DocumentSummary{p.Keep, p.Merge}
+	for _, doc := range p.Relinked {
+		if doc.ID != p.Keep.ID {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// PreviewMerge returns what MergeDocuments would change, without changing it.
+func (store *DocumentStore) PreviewMerge(keepID domain.DocumentID, mergeID domain.DocumentID) (*MergePlan, error) {
+	var plan *MergePlan
+	err := store.bolt.View(func(tx *bolt.Tx) error {
+		var err error
+		plan, _, err = store.planMerge(tx, keepID, mergeID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// MergeDocuments merges document mergeID into keepID in a single
+// transaction: the blocks of the merged document are appended to the kept
+// one with their IDs and scheduled tasks, [[merged title]] links across
+// the graph are rewritten to the kept title, the kept document takes the
+// merged one's place in the recents, and the merged document is deleted.
+func (store *DocumentStore) MergeDocuments(keepID domain.DocumentID, mergeID domain.DocumentID) (*MergePlan, error) {
+	if store.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	var plan *MergePlan
+	err := store.bolt.Update(func(tx *bolt.Tx) error {
+		var docs []*domain.Document
+		var err error
+		plan, docs, err = store.planMerge(tx, keepID, mergeID)
+		if err != nil {
+			return err
+		}
+
+		mergeDb, err := store.getDocDb(tx, mergeID)
+		if err != nil {
+			return err
+		}
+		ids := make([]uuid.UUID, len(mergeDb.Blocks))
+		for i, block := range mergeDb.Blocks {
+			ids[i] = block.ID
+		}
+		if err := store.scheduledIndex.migrate(tx, uuid.UUID(mergeID), uuid.UUID(keepID), ids); err != nil {
+			return err
+		}
+		if err := store.recentsDocs.replace(tx, uuid.UUID(mergeID), uuid.UUID(keepID)); err != nil {
+			return err
+		}
+		// Frees the merged title before the links to it are rewritten
+		if err := store.deleteTx(tx, mergeDb); err != nil {
+			return err
+		}
+
+		for _, doc := range docs {
+			if _, err := store.saveTx(tx, doc); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	store.notifyIndexer()
+	return plan, nil
+}
+
+// planMerge computes the merge of mergeID into keepID and returns the plan
+// with the documents to save: the kept document with the appended blocks
+// and every other document whose links change.
+func (store *DocumentStore) planMerge(tx *bolt.Tx, keepID domain.DocumentID, mergeID domain.DocumentID) (*MergePlan, []*domain.Document, error) {
+	if keepID == mergeID {
+		return nil, nil, fmt.Errorf("%w: cannot merge a document into itself", ErrInvalidOp)
+	}
+
+	keep, err := store.loadDocument(tx, keepID)
+	if err != nil {
+		return nil, nil, err
+	}
+	merge, err := store.loadDocument(tx, mergeID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan := &MergePlan{
+		Keep:   DocumentSummary{ID: keep.ID, Title: keep.Title, Date: keep.Date},
+		Merge:  DocumentSummary{ID: merge.ID, Title: merge.Title, Date: merge.Date},
+		Blocks: len(merge.Blocks),
+	}
+
+	keep.Blocks = append(keep.Blocks, merge.Blocks...)
+	docs := []*domain.Document{keep}
+	if relinkDocument(keep, merge.Title, keep.Title) {
+		plan.Relinked = append(plan.Relinked, plan.Keep)
+	}
+
+	data := tx.Bucket(store.referencesIndex.referenceIndex).Get([]byte(strings.ToLower(merge.Title)))
+	for id := range decodeUUIDSet(data) {
+		docID := domain.DocumentID(id)
+		if docID == keepID || docID == mergeID {
+			continue
+		}
+		doc, err := store.loadDocument(tx, docID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if relinkDocument(doc, merge.Title, keep.Title) {
+			plan.Relinked = append(plan.Relinked, DocumentSummary{ID: doc.ID, Title: doc.Title, Date: doc.Date})
+			docs = append(docs, doc)
+		}
+	}
+
+	sort.Slice(plan.Relinked, func(i, j int) bool {
+		return strings.ToLower(plan.Relinked[i].Title) < strings.ToLower(plan.Relinked[j].Title)
+	})
+	return plan, docs, nil
+}
+
+// relinkDocument rewrites the [[from]] links of doc, ignoring case, to
+// [[to]] and reports whether any changed.
+func relinkDocument(doc *domain.Document, from string, to string) bool {
+	changed := false
+	for _, block := range doc.Blocks {
+		content := referenceRegex.ReplaceAllStringFunc(block.Content, func(match string) string {
+			if strings.EqualFold(strings.TrimSpace(match[2:len(match)-2]), from) {
+				return "[[" + to + "]]"
+			}
+			return match
+		})
+		if content != block.Content {
+			block.Content = content
+			changed = true
+		}
+	}
+	return changed
+}
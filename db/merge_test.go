@@ -0,0 +1,90 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMergeDocuments(t *testing.T) {
+	store, err := NewDocumentStore("./testmerge.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove("./testmerge.db")
+		_ = os.RemoveAll("./testmerge.db.bleve")
+	}()
+
+	keep := saveTestDoc(t, store, "Project X", "Goals")
+	merge := saveTestDoc(t, store, "Project X (2)", "Ship it /scheduled 2026-11-02", "See [[project x (2)]]")
+	linking := saveTestDoc(t, store, "Weekly", "Worked on [[Project X (2)]] and [[Other]]")
+	unrelated := saveTestDoc(t, store, "Unrelated", "Nothing here")
+
+	if _, err := store.LoadDocument(merge.ID); err != nil {
+		t.Fatalf("LoadDocument failed: %v", err)
+	}
+	if _, err := store.LoadDocument(unrelated.ID); err != nil {
+		t.Fatalf("LoadDocument failed: %v", err)
+	}
+
+	plan, err := store.PreviewMerge(keep.ID, merge.ID)
+	if err != nil {
+		t.Fatalf("PreviewMerge failed: %v", err)
+	}
+	if plan.Blocks != 2 || len(plan.Relinked) != 2 || plan.Relinked[0].ID != keep.ID || plan.Relinked[1].ID != linking.ID {
+		t.Errorf("Unexpected plan: %+v", plan)
+	}
+	if docs := plan.Documents(); len(docs) != 3 {
+		t.Errorf("Expected the preview to touch 3 documents, got %+v", docs)
+	}
+	if _, err := store.GetDocument(merge.ID); err != nil {
+		t.Fatalf("Expected the preview to change nothing, got %v", err)
+	}
+
+	if _, err := store.MergeDocuments(keep.ID, merge.ID); err != nil {
+		t.Fatalf("MergeDocuments failed: %v", err)
+	}
+
+	if _, err := store.GetDocument(merge.ID); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("Expected the merged document to be deleted, got %v", err)
+	}
+	kept, err := store.GetDocument(keep.ID)
+	if err != nil {
+		t.Fatalf("GetDocument failed: %v", err)
+	}
+	if got := blockContents(kept); len(got) != 3 || got[2] != "See [[Project X]]" {
+		t.Errorf("Expected the merged blocks appended and relinked, got %v", got)
+	}
+	relinked, _ := store.GetDocument(linking.ID)
+	if relinked.Blocks[0].Content != "Worked on [[Project X]] and [[Other]]" {
+		t.Errorf("Expected links rewritten to the kept title, got %q", relinked.Blocks[0].Content)
+	}
+
+	if refs, _ := store.GetReferences("Project X (2)"); len(refs) != 0 {
+		t.Errorf("Expected no references to the merged title, got %v", refs)
+	}
+	if refs, _ := store.GetReferences("Project X"); len(refs) != 2 {
+		t.Errorf("Expected the kept page and Weekly to reference Project X, got %v", refs)
+	}
+
+	tasks, _ := store.GetScheduledTasks(time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC), 1)
+	if len(tasks) != 1 || tasks[0].DocID != keep.ID {
+		t.Errorf("Expected the task to move to the kept page, got %+v", tasks)
+	}
+
+	recents, _ := store.GetRecents()
+	if len(recents) != 2 || recents[0] != unrelated.ID || recents[1] != keep.ID {
+		t.Errorf("Expected the kept page in the merged page's place in the recents, got %v", recents)
+	}
+
+	if ids, _ := store.Search("ship"); len(ids) != 1 || ids[0] != keep.ID {
+		t.Errorf("Expected the merged content to be found in the kept page, got %v", ids)
+	}
+
+	if _, err := store.MergeDocuments(keep.ID, keep.ID); !errors.Is(err, ErrInvalidOp) {
+		t.Errorf("Expected ErrInvalidOp when merging a document into itself, got %v", err)
+	}
+}
@@ -8,6 +8,7 @@ type DocDb struct {
 	Date      string
 	IsJournal bool
 	Blocks    []*BlockDb
+	Revision  uint64 // incremented on every write, see ApplyOps
 }
 
 type BlockDb struct {
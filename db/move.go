@@ -0,0 +1,203 @@
+package db
+
+import (
+	"fmt"
+	"glog/domain"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BlockRef returns the ((block-id)) reference to a block.
+func BlockRef(id domain.BlockID) string {
+	return "((" + id.String() + "))"
+}
+
+// MoveOptions configures MoveBlocks and CopyBlocks.
+type MoveOptions struct {
+	// Indent is the indent of the block in the destination; its
+	// descendants keep their indent relative to it.
+	Indent int
+	// LeaveReference replaces the moved block in the source document with
+	// a ((block-id)) reference to it. Ignored by CopyBlocks.
+	LeaveReference bool
+}
+
+// MoveBlocks moves a block and its descendants from document src to
+// document dst, inserting them before the block at index position of dst,
+// or at the end when position is negative or past the last block.
+//
+// Block IDs are kept, so scheduled tasks are migrated to dst rather than
+// rescheduled, and references are updated for both documents. Both
+// documents are saved in a single transaction; the updated documents are
+// returned. Use ApplyOps to move blocks within a document.
+func (store *DocumentStore) MoveBlocks(src domain.DocumentID, blockID domain.BlockID, dst domain.DocumentID, position int, opts MoveOptions) (*domain.Document, *domain.Document, error) {
+	return store.transferBlocks(src, blockID, dst, position, opts, false)
+}
+
+// CopyBlocks copies a block and its descendants from document src to
+// document dst like MoveBlocks, giving the copies new block IDs. Scheduled
+// tasks in the copies are scheduled again for dst.
+func (store *DocumentStore) CopyBlocks(src domain.DocumentID, blockID domain.BlockID, dst domain.DocumentID, position int, opts MoveOptions) (*domain.Document, *domain.Document, error) {
+	opts.LeaveReference = false
+	return store.transferBlocks(src, blockID, dst, position, opts, true)
+}
+
+func (store *DocumentStore) transferBlocks(src domain.DocumentID, blockID domain.BlockID, dst domain.DocumentID, position int, opts MoveOptions, copyBlocks bool) (*domain.Document, *domain.Document, error) {
+	if store.readOnly {
+		return nil, nil, ErrReadOnly
+	}
+	if src == dst {
+		return nil, nil, fmt.Errorf("%w: source and destination are the same document", ErrInvalidOp)
+	}
+	if opts.Indent < 0 {
+		return nil, nil, fmt.Errorf("%w: negative indent", ErrInvalidOp)
+	}
+
+	var srcDoc, dstDoc *domain.Document
+	err := store.bolt.Update(func(tx *bolt.Tx) error {
+		var err error
+		srcDoc, err = store.loadDocument(tx, src)
+		if err != nil {
+			return err
+		}
+		dstDoc, err = store.loadDocument(tx, dst)
+		if err != nil {
+			return err
+		}
+
+		i := findBlock(srcDoc.Blocks, blockID)
+		if i < 0 {
+			return fmt.Errorf("%w: block %s not found", ErrInvalidOp, blockID)
+		}
+		end := SubtreeEnd(srcDoc.Blocks, i)
+
+		shift := opts.Indent - srcDoc.Blocks[i].Indent
+		subtree := make([]*domain.Block, 0, end-i)
+		for _, block := range srcDoc.Blocks[i:end] {
+			moved := &domain.Block{ID: block.ID, Content: block.Content, Indent: block.Indent + shift}
+			if copyBlocks {
+				moved.ID = domain.BlockID(uuid.New())
+			}
+			subtree = append(subtree, moved)
+		}
+
+		if position < 0 || position > len(dstDoc.Blocks) {
+			position = len(dstDoc.Blocks)
+		}
+		blocks := make([]*domain.Block, 0, len(dstDoc.Blocks)+len(subtree))
+		blocks = append(blocks, dstDoc.Blocks[:position]...)
+		blocks = append(blocks, subtree...)
+		dstDoc.Blocks = append(blocks, dstDoc.Blocks[position:]...)
+
+		if !copyBlocks {
+			var remaining []*domain.Block
+			remaining = append(remaining, srcDoc.Blocks[:i]...)
+			if opts.LeaveReference {
+				remaining = append(remaining, &domain.Block{
+					ID:      domain.BlockID(uuid.New()),
+					Content: BlockRef(blockID),
+					Indent:  srcDoc.Blocks[i].Indent,
+				})
+			}
+			srcDoc.Blocks = append(remaining, srcDoc.Blocks[end:]...)
+
+			ids := make([]uuid.UUID, len(subtree))
+			for n, block := range subtree {
+				ids[n] = uuid.UUID(block.ID)
+			}
+			if err := store.scheduledIndex.migrate(tx, uuid.UUID(src), uuid.UUID(dst), ids); err != nil {
+				return err
+			}
+
+			if _, err := store.saveTx(tx, srcDoc); err != nil {
+				return err
+			}
+		}
+
+		_, err = store.saveTx(tx, dstDoc)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	store.notifyIndexer()
+	return srcDoc, dstDoc, nil
+}
+
+// ExtractToPage turns a block into a page: a new document titled title is
+// created from the block's children, re-based to indent 0, and the block
+// is replaced by a [[title]] link. The block keeps its ID and place; an
+// empty title uses the block's first line. Children keep their IDs and
+// scheduled tasks. Both documents are saved in a single transaction, so
+// nothing changes when the title is taken (ErrDuplicateTitle). It returns
+// the source document and the new page.
+func (store *DocumentStore) ExtractToPage(docID domain.DocumentID, blockID domain.BlockID, title string) (*domain.Document, *domain.Document, error) {
+	if store.readOnly {
+		return nil, nil, ErrReadOnly
+	}
+
+	var doc, page *domain.Document
+	err := store.bolt.Update(func(tx *bolt.Tx) error {
+		var err error
+		doc, err = store.loadDocument(tx, docID)
+		if err != nil {
+			return err
+		}
+
+		i := findBlock(doc.Blocks, blockID)
+		if i < 0 {
+			return fmt.Errorf("%w: block %s not found", ErrInvalidOp, blockID)
+		}
+		end := SubtreeEnd(doc.Blocks, i)
+		block := doc.Blocks[i]
+
+		title = strings.TrimSpace(title)
+		if title == "" {
+			title, _, _ = strings.Cut(block.Content, "\n")
+			title = strings.TrimSpace(title)
+		}
+		if title == "" || strings.ContainsAny(title, "[]") {
+			return fmt.Errorf("%w: invalid page title %q", ErrInvalidOp, title)
+		}
+
+		page = &domain.Document{
+			ID:    domain.DocumentID(uuid.New()),
+			Title: title,
+			Date:  time.Now(),
+		}
+		ids := make([]uuid.UUID, 0, end-i-1)
+		for _, child := range doc.Blocks[i+1 : end] {
+			page.Blocks = append(page.Blocks, &domain.Block{
+				ID:      child.ID,
+				Content: child.Content,
+				Indent:  child.Indent - block.Indent - 1,
+			})
+			ids = append(ids, uuid.UUID(child.ID))
+		}
+		if len(page.Blocks) == 0 {
+			page.Blocks = []*domain.Block{{ID: domain.BlockID(uuid.New())}}
+		}
+
+		block.Content = "[[" + title + "]]"
+		doc.Blocks = append(doc.Blocks[:i+1], doc.Blocks[end:]...)
+
+		if err := store.scheduledIndex.migrate(tx, uuid.UUID(docID), uuid.UUID(page.ID), ids); err != nil {
+			return err
+		}
+		if _, err := store.saveTx(tx, page); err != nil {
+			return err
+		}
+		_, err = store.saveTx(tx, doc)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	store.notifyIndexer()
+	return doc, page, nil
+}
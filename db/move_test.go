@@ -0,0 +1,196 @@
+package db
+
+import (
+	"errors"
+	"glog/domain"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSubtreeEnd(t *testing.T) {
+	blocks := []*domain.Block{
+		{Indent: 0}, // 0
+		{Indent: 1}, // 1
+		{Indent: 2}, // 2
+		{Indent: 1}, // 3
+		{Indent: 0}, // 4
+	}
+
+	tests := []struct{ i, want int }{{0, 4}, {1, 3}, {2, 3}, {3, 4}, {4, 5}}
+	for _, tt := range tests {
+		if got := SubtreeEnd(blocks, tt.i); got != tt.want {
+			t.Errorf("SubtreeEnd(%d) = %d, want %d", tt.i, got, tt.want)
+		}
+	}
+
+	parents := ParentIndexes(blocks)
+	want := []int{-1, 0, 1, 0, -1}
+	for i := range want {
+		if parents[i] != want[i] {
+			t.Errorf("ParentIndexes = %v, want %v", parents, want)
+			break
+		}
+	}
+}
+
+func TestMoveBlocks(t *testing.T) {
+	store, err := NewDocumentStore("./testmove.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove("./testmove.db")
+		_ = os.RemoveAll("./testmove.db.bleve")
+	}()
+
+	root := domain.BlockID(uuid.New())
+	child := domain.BlockID(uuid.New())
+	journal := &domain.Document{
+		ID:        domain.DocumentID(uuid.New()),
+		Title:     "Oct 18th, 2026",
+		Date:      time.Now(),
+		IsJournal: true,
+		Blocks: []*domain.Block{
+			{ID: domain.BlockID(uuid.New()), Content: "before"},
+			{ID: root, Content: "Idea for [[Garden]]", Indent: 1},
+			{ID: child, Content: "Buy seeds /scheduled 2026-11-02", Indent: 2},
+			{ID: domain.BlockID(uuid.New()), Content: "after"},
+		},
+	}
+	project := &domain.Document{
+		ID:     domain.DocumentID(uuid.New()),
+		Title:  "Projects",
+		Date:   time.Now(),
+		Blocks: []*domain.Block{{ID: domain.BlockID(uuid.New()), Content: "first"}},
+	}
+	for _, doc := range []*domain.Document{journal, project} {
+		if err := store.Save(doc); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	sub := store.Subscribe(16)
+	defer store.Unsubscribe(sub)
+
+	src, dst, err := store.MoveBlocks(journal.ID, root, project.ID, 0, MoveOptions{LeaveReference: true})
+	if err != nil {
+		t.Fatalf("MoveBlocks failed: %v", err)
+	}
+
+	if len(dst.Blocks) != 3 || dst.Blocks[0].ID != root || dst.Blocks[1].ID != child {
+		t.Fatalf("Expected the subtree at the start of the destination, got %+v", dst.Blocks)
+	}
+	if dst.Blocks[0].Indent != 0 || dst.Blocks[1].Indent != 1 {
+		t.Errorf("Expected indents rebased to 0 and 1, got %d and %d", dst.Blocks[0].Indent, dst.Blocks[1].Indent)
+	}
+	if len(src.Blocks) != 3 || src.Blocks[1].Content != BlockRef(root) || src.Blocks[1].Indent != 1 {
+		t.Errorf("Expected a block reference in place of the subtree, got %+v", src.Blocks)
+	}
+
+	if docID, err := store.GetBlockDocument(root); err != nil || docID != project.ID {
+		t.Errorf("Expected the reference to resolve to the project, got %v (err %v)", docID, err)
+	}
+
+	// The task follows the block without being rescheduled
+	tasks, err := store.GetScheduledTasks(time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC), 1)
+	if err != nil {
+		t.Fatalf("GetScheduledTasks failed: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].DocID != project.ID || tasks[0].BlockID != child {
+		t.Errorf("Expected the task to move to the project, got %+v", tasks)
+	}
+	for len(sub.C) > 0 {
+		if ev := <-sub.C; ev.Type == EventTaskScheduled {
+			t.Errorf("Expected no TaskScheduled event for a moved task, got %+v", ev)
+		}
+	}
+
+	if refs, _ := store.GetReferences("Garden"); len(refs) != 1 || refs[0] != project.ID {
+		t.Errorf("Expected only the project to reference Garden, got %v", refs)
+	}
+
+	// Copies get new IDs and their own tasks
+	_, dst, err = store.CopyBlocks(project.ID, root, journal.ID, -1, MoveOptions{})
+	if err != nil {
+		t.Fatalf("CopyBlocks failed: %v", err)
+	}
+	last := dst.Blocks[len(dst.Blocks)-1]
+	if last.ID == child || last.Content != "Buy seeds /scheduled 2026-11-02" {
+		t.Errorf("Expected a copy with a new ID at the end, got %+v", last)
+	}
+	tasks, _ = store.GetScheduledTasks(time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC), 1)
+	if len(tasks) != 2 {
+		t.Errorf("Expected the copy to be scheduled too, got %+v", tasks)
+	}
+
+	if _, _, err := store.MoveBlocks(journal.ID, domain.BlockID(uuid.New()), project.ID, 0, MoveOptions{}); !errors.Is(err, ErrInvalidOp) {
+		t.Errorf("Expected ErrInvalidOp for an unknown block, got %v", err)
+	}
+	if _, _, err := store.MoveBlocks(journal.ID, root, journal.ID, 0, MoveOptions{}); !errors.Is(err, ErrInvalidOp) {
+		t.Errorf("Expected ErrInvalidOp for a move within a document, got %v", err)
+	}
+}
+
+func TestExtractToPage(t *testing.T) {
+	store, err := NewDocumentStore("./testextract.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove("./testextract.db")
+		_ = os.RemoveAll("./testextract.db.bleve")
+	}()
+
+	bullet := domain.BlockID(uuid.New())
+	task := domain.BlockID(uuid.New())
+	journal := &domain.Document{
+		ID:        domain.DocumentID(uuid.New()),
+		Title:     "Oct 18th, 2026",
+		Date:      time.Now(),
+		IsJournal: true,
+		Blocks: []*domain.Block{
+			{ID: bullet, Content: "Kitchen renovation", Indent: 1},
+			{ID: task, Content: "Call plumber /scheduled 2026-11-02", Indent: 2},
+			{ID: domain.BlockID(uuid.New()), Content: "Quotes", Indent: 3},
+			{ID: domain.BlockID(uuid.New()), Content: "unrelated", Indent: 0},
+		},
+	}
+	existing := &domain.Document{ID: domain.DocumentID(uuid.New()), Title: "Taken", Date: time.Now()}
+	for _, doc := range []*domain.Document{journal, existing} {
+		if err := store.Save(doc); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	if _, _, err := store.ExtractToPage(journal.ID, bullet, "taken"); !errors.Is(err, ErrDuplicateTitle) {
+		t.Fatalf("Expected ErrDuplicateTitle, got %v", err)
+	}
+	if unchanged, _ := store.GetDocument(journal.ID); len(unchanged.Blocks) != 4 {
+		t.Fatalf("Expected the journal to be unchanged after the failed extract, got %+v", unchanged.Blocks)
+	}
+
+	doc, page, err := store.ExtractToPage(journal.ID, bullet, "")
+	if err != nil {
+		t.Fatalf("ExtractToPage failed: %v", err)
+	}
+
+	if page.Title != "Kitchen renovation" || len(page.Blocks) != 2 || page.Blocks[0].ID != task || page.Blocks[0].Indent != 0 || page.Blocks[1].Indent != 1 {
+		t.Errorf("Expected the children re-based to indent 0, got %q %+v", page.Title, page.Blocks)
+	}
+	if len(doc.Blocks) != 2 || doc.Blocks[0].ID != bullet || doc.Blocks[0].Content != "[[Kitchen renovation]]" {
+		t.Errorf("Expected the subtree replaced by a link, got %+v", doc.Blocks)
+	}
+
+	if refs, _ := store.GetReferences("Kitchen renovation"); len(refs) != 1 || refs[0] != journal.ID {
+		t.Errorf("Expected the journal to link to the new page, got %v", refs)
+	}
+	tasks, _ := store.GetScheduledTasks(time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC), 1)
+	if len(tasks) != 1 || tasks[0].DocID != page.ID || tasks[0].BlockID != task {
+		t.Errorf("Expected the task to move to the new page, got %+v", tasks)
+	}
+}
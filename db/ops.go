@@ -0,0 +1,267 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"glog/domain"
+	"strings"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+var ErrRevisionConflict = errors.New("document was changed since the base revision")
+var ErrInvalidOp = errors.New("invalid block operation")
+
+// OpType is the kind of change a BlockOp makes.
+type OpType string
+
+const (
+	OpInsert OpType = "insert" // insert a new block after After
+	OpUpdate OpType = "update" // replace the content of a block
+	OpDelete OpType = "delete" // remove a block, its children stay in place
+	OpMove   OpType = "move"   // move a block after After
+	OpIndent OpType = "indent" // set the indent of a block
+)
+
+// BlockOp is one change to the blocks of a document. Blocks are addressed
+// by ID; a zero After means the start of the document.
+type BlockOp struct {
+	Type    OpType
+	BlockID domain.BlockID
+	After   domain.BlockID // OpInsert, OpMove
+	Content string         // OpInsert, OpUpdate
+	Indent  int            // OpInsert, OpIndent
+}
+
+// ApplyOps applies ops to the document in order, in a single transaction,
+// as an alternative to saving the whole document. It fails with
+// ErrRevisionConflict when the document's revision is not baseRevision,
+// and with ErrInvalidOp when an operation does not apply; nothing is
+// written in either case.
+//
+// Only the derived indexes of the touched blocks are updated: scheduled
+// tasks of inserted, updated and deleted blocks, references when the
+// touched blocks change the set of linked titles, and the search entries
+// of the document and the touched blocks when content changed. Moves and
+// indents only rewrite the document.
+func (store *DocumentStore) ApplyOps(id domain.DocumentID, baseRevision uint64, ops []BlockOp) (*domain.Document, error) {
+	if store.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	var doc *domain.Document
+	err := store.bolt.Update(func(tx *bolt.Tx) error {
+		docDb, err := store.getDocDb(tx, id)
+		if err != nil {
+			return err
+		}
+		if docDb.Revision != baseRevision {
+			return fmt.Errorf("%w: base %d, current %d", ErrRevisionConflict, baseRevision, docDb.Revision)
+		}
+
+		oldTitles := make(map[string]struct{})
+		for _, title := range getReferencedTitles(docDb) {
+			oldTitles[title] = struct{}{}
+		}
+
+		changes, err := applyBlockOps(docDb, ops)
+		if err != nil {
+			return err
+		}
+
+		if err := store.putDocDb(tx, docDb); err != nil {
+			return err
+		}
+
+		touched := &DocDb{ID: docDb.ID, Title: docDb.Title, IsJournal: docDb.IsJournal}
+		for _, block := range docDb.Blocks {
+			if _, ok := changes.content[block.ID]; ok {
+				touched.Blocks = append(touched.Blocks, block)
+			}
+		}
+
+		if err := store.blockIndex.update(tx, docDb.ID, changes.deleted, touched.Blocks); err != nil {
+			return err
+		}
+
+		if len(changes.deleted) > 0 {
+			deleted := &DocDb{ID: docDb.ID, Blocks: changes.deleted}
+			if err := store.scheduledIndex.delete(tx, deleted); err != nil {
+				return err
+			}
+		}
+		if err := store.recordScheduledTasks(tx, touched); err != nil {
+			return err
+		}
+		if err := store.scheduledIndex.save(tx, touched); err != nil {
+			return err
+		}
+
+		if changes.linksChanged(oldTitles) {
+			if err := store.referencesIndex.save(tx, docDb); err != nil {
+				return err
+			}
+		}
+
+		doc = docDbToDomain(docDb)
+		if len(changes.content) > 0 || len(changes.deleted) > 0 {
+			blocks := make([]uuid.UUID, 0, len(changes.content)+len(changes.deleted))
+			for id := range changes.content {
+				blocks = append(blocks, id)
+			}
+			for _, block := range changes.deleted {
+				blocks = append(blocks, block.ID)
+			}
+			if err := enqueueIndexBlocks(tx, docDb.ID, blocks); err != nil {
+				return err
+			}
+		}
+
+		return store.recordEvent(tx, Event{
+			Type:      EventDocumentSaved,
+			DocID:     doc.ID,
+			Title:     doc.Title,
+			IsJournal: doc.IsJournal,
+			doc:       docDbToDomain(docDb),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	store.notifyIndexer()
+	return doc, nil
+}
+
+// blockChanges records which blocks a list of operations touched.
+type blockChanges struct {
+	content  map[uuid.UUID]struct{} // inserted or updated blocks
+	deleted  []*BlockDb
+	oldLinks map[string]struct{} // titles linked from the touched blocks before the ops
+	newLinks map[string]struct{} // titles linked from the touched blocks after the ops
+}
+
+// linksChanged reports whether the touched blocks changed which titles
+// the document links to, given the titles linked before the ops.
+func (c *blockChanges) linksChanged(before map[string]struct{}) bool {
+	for title := range c.newLinks {
+		if _, ok := before[title]; !ok {
+			return true
+		}
+	}
+	// A title no longer linked from a touched block may still be linked
+	// from another block; references.save recomputes that.
+	for title := range c.oldLinks {
+		if _, ok := c.newLinks[title]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyBlockOps applies ops to the blocks of docDb.
+func applyBlockOps(docDb *DocDb, ops []BlockOp) (*blockChanges, error) {
+	changes := &blockChanges{
+		content:  make(map[uuid.UUID]struct{}),
+		oldLinks: make(map[string]struct{}),
+		newLinks: make(map[string]struct{}),
+	}
+	inserted := make(map[uuid.UUID]struct{})
+
+	indexOf := func(id uuid.UUID) int {
+		for i, block := range docDb.Blocks {
+			if block.ID == id {
+				return i
+			}
+		}
+		return -1
+	}
+	// position returns the index a block inserted after the block after goes to
+	position := func(after domain.BlockID) (int, error) {
+		if after == (domain.BlockID{}) {
+			return 0, nil
+		}
+		i := indexOf(uuid.UUID(after))
+		if i < 0 {
+			return 0, fmt.Errorf("%w: block %s not found", ErrInvalidOp, after)
+		}
+		return i + 1, nil
+	}
+	noteLinks := func(set map[string]struct{}, content string) {
+		for _, match := range referenceRegex.FindAllStringSubmatch(content, -1) {
+			if title := strings.TrimSpace(match[1]); title != "" {
+				set[title] = struct{}{}
+			}
+		}
+	}
+
+	for n, op := range ops {
+		id := uuid.UUID(op.BlockID)
+		if op.Type != OpInsert && indexOf(id) < 0 {
+			return nil, fmt.Errorf("%w: op %d (%s): block %s not found", ErrInvalidOp, n+1, op.Type, op.BlockID)
+		}
+
+		switch op.Type {
+		case OpInsert:
+			if id == uuid.Nil {
+				id = uuid.New()
+			} else if indexOf(id) >= 0 {
+				return nil, fmt.Errorf("%w: op %d: block %s already exists", ErrInvalidOp, n+1, op.BlockID)
+			}
+			if op.Indent < 0 {
+				return nil, fmt.Errorf("%w: op %d: negative indent", ErrInvalidOp, n+1)
+			}
+			pos, err := position(op.After)
+			if err != nil {
+				return nil, err
+			}
+			block := &BlockDb{ID: id, Content: op.Content, Indent: op.Indent}
+			docDb.Blocks = append(docDb.Blocks[:pos], append([]*BlockDb{block}, docDb.Blocks[pos:]...)...)
+			inserted[id] = struct{}{}
+			changes.content[id] = struct{}{}
+			noteLinks(changes.newLinks, op.Content)
+
+		case OpUpdate:
+			block := docDb.Blocks[indexOf(id)]
+			noteLinks(changes.oldLinks, block.Content)
+			noteLinks(changes.newLinks, op.Content)
+			block.Content = op.Content
+			changes.content[id] = struct{}{}
+
+		case OpDelete:
+			i := indexOf(id)
+			block := docDb.Blocks[i]
+			noteLinks(changes.oldLinks, block.Content)
+			docDb.Blocks = append(docDb.Blocks[:i], docDb.Blocks[i+1:]...)
+			delete(changes.content, id)
+			if _, ok := inserted[id]; !ok {
+				changes.deleted = append(changes.deleted, block)
+			}
+
+		case OpMove:
+			if op.After == op.BlockID {
+				return nil, fmt.Errorf("%w: op %d: block %s cannot move after itself", ErrInvalidOp, n+1, op.BlockID)
+			}
+			i := indexOf(id)
+			block := docDb.Blocks[i]
+			docDb.Blocks = append(docDb.Blocks[:i], docDb.Blocks[i+1:]...)
+			pos, err := position(op.After)
+			if err != nil {
+				return nil, err
+			}
+			docDb.Blocks = append(docDb.Blocks[:pos], append([]*BlockDb{block}, docDb.Blocks[pos:]...)...)
+
+		case OpIndent:
+			if op.Indent < 0 {
+				return nil, fmt.Errorf("%w: op %d: negative indent", ErrInvalidOp, n+1)
+			}
+			docDb.Blocks[indexOf(id)].Indent = op.Indent
+
+		default:
+			return nil, fmt.Errorf("%w: op %d: unknown type %q", ErrInvalidOp, n+1, op.Type)
+		}
+	}
+
+	return changes, nil
+}
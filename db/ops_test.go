@@ -0,0 +1,192 @@
+package db
+
+import (
+	"errors"
+	"glog/domain"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func blockContents(doc *domain.Document) []string {
+	contents := make([]string, len(doc.Blocks))
+	for i, block := range doc.Blocks {
+		contents[i] = block.Content
+	}
+	return contents
+}
+
+func TestApplyOps(t *testing.T) {
+	store, err := NewDocumentStore("./testops.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove("./testops.db")
+		_ = os.RemoveAll("./testops.db.bleve")
+	}()
+
+	a := domain.BlockID(uuid.New())
+	b := domain.BlockID(uuid.New())
+	c := domain.BlockID(uuid.New())
+	doc := &domain.Document{
+		ID:    domain.DocumentID(uuid.New()),
+		Title: "Ops",
+		Date:  time.Now(),
+		Blocks: []*domain.Block{
+			{ID: a, Content: "first"},
+			{ID: b, Content: "second [[Linked]]"},
+			{ID: c, Content: "third /scheduled 2026-11-02"},
+		},
+	}
+	if err := store.Save(doc); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if doc.Revision != 1 {
+		t.Fatalf("Expected revision 1 after the first save, got %d", doc.Revision)
+	}
+
+	d := domain.BlockID(uuid.New())
+	updated, err := store.ApplyOps(doc.ID, 1, []BlockOp{
+		{Type: OpInsert, BlockID: d, After: a, Content: "inserted [[Other]]", Indent: 1},
+		{Type: OpUpdate, BlockID: b, Content: "second, unlinked"},
+		{Type: OpMove, BlockID: c},
+		{Type: OpIndent, BlockID: a, Indent: 2},
+	})
+	if err != nil {
+		t.Fatalf("ApplyOps failed: %v", err)
+	}
+	if updated.Revision != 2 {
+		t.Errorf("Expected revision 2, got %d", updated.Revision)
+	}
+
+	want := []string{"third /scheduled 2026-11-02", "first", "inserted [[Other]]", "second, unlinked"}
+	loaded, err := store.GetDocument(doc.ID)
+	if err != nil {
+		t.Fatalf("GetDocument failed: %v", err)
+	}
+	if got := blockContents(loaded); len(got) != len(want) {
+		t.Fatalf("Expected blocks %v, got %v", want, got)
+	} else {
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("Expected blocks %v, got %v", want, got)
+			}
+		}
+	}
+	if loaded.Blocks[1].Indent != 2 || loaded.Blocks[2].Indent != 1 || loaded.Blocks[2].ID != d {
+		t.Errorf("Unexpected indents or IDs: %+v %+v", loaded.Blocks[1], loaded.Blocks[2])
+	}
+
+	// References follow the touched blocks
+	if refs, _ := store.GetReferences("Linked"); len(refs) != 0 {
+		t.Errorf("Expected no references to Linked, got %v", refs)
+	}
+	if refs, _ := store.GetReferences("Other"); len(refs) != 1 || refs[0] != doc.ID {
+		t.Errorf("Expected a reference to Other, got %v", refs)
+	}
+
+	// The search index sees the new content
+	if ids, _ := store.Search("inserted"); len(ids) != 1 {
+		t.Errorf("Expected the inserted block to be searchable, got %v", ids)
+	}
+
+	// Deleting the scheduled block removes its task
+	if _, err := store.ApplyOps(doc.ID, 2, []BlockOp{{Type: OpDelete, BlockID: c}}); err != nil {
+		t.Fatalf("ApplyOps delete failed: %v", err)
+	}
+	tasks, err := store.GetScheduledTasks(time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC), 1)
+	if err != nil {
+		t.Fatalf("GetScheduledTasks failed: %v", err)
+	}
+	if len(tasks) != 0 {
+		t.Errorf("Expected the task of the deleted block to be removed, got %v", tasks)
+	}
+
+	// Only the touched blocks are reindexed: the entry of an untouched
+	// block removed behind the store's back stays missing
+	store.waitIndexed()
+	if err := store.search.index.Delete(blockEntryID(uuid.UUID(doc.ID), uuid.UUID(a))); err != nil {
+		t.Fatalf("Deleting a block entry failed: %v", err)
+	}
+	if _, err := store.ApplyOps(doc.ID, 3, []BlockOp{{Type: OpUpdate, BlockID: d, Content: "renamed"}}); err != nil {
+		t.Fatalf("ApplyOps update failed: %v", err)
+	}
+	store.waitIndexed()
+	indexed, err := store.search.blockEntries(uuid.UUID(doc.ID).String())
+	if err != nil {
+		t.Fatalf("blockEntries failed: %v", err)
+	}
+	entries := make(map[string]bool)
+	for _, id := range indexed {
+		entries[id] = true
+	}
+	if len(entries) != 2 || !entries[blockEntryID(uuid.UUID(doc.ID), uuid.UUID(b))] || !entries[blockEntryID(uuid.UUID(doc.ID), uuid.UUID(d))] {
+		t.Errorf("Expected only the entries of the touched and the untouched indexed block, got %v", indexed)
+	}
+	if hits, _ := store.SearchBlocks("renamed", 10); len(hits) != 1 || hits[0].BlockID != d {
+		t.Errorf("Expected the updated block entry, got %+v", hits)
+	}
+	if hits, _ := store.SearchBlocks("inserted", 10); len(hits) != 0 {
+		t.Errorf("Expected the old block content to be gone, got %+v", hits)
+	}
+}
+
+func TestApplyOpsRejected(t *testing.T) {
+	store, err := NewDocumentStore("./testopsrejected.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove("./testopsrejected.db")
+		_ = os.RemoveAll("./testopsrejected.db.bleve")
+	}()
+
+	a := domain.BlockID(uuid.New())
+	doc := &domain.Document{
+		ID:     domain.DocumentID(uuid.New()),
+		Title:  "Rejected",
+		Date:   time.Now(),
+		Blocks: []*domain.Block{{ID: a, Content: "only"}},
+	}
+	if err := store.Save(doc); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		revision uint64
+		ops      []BlockOp
+		wantErr  error
+	}{
+		{"stale revision", 0, []BlockOp{{Type: OpUpdate, BlockID: a, Content: "x"}}, ErrRevisionConflict},
+		{"unknown block", 1, []BlockOp{{Type: OpUpdate, BlockID: domain.BlockID(uuid.New())}}, ErrInvalidOp},
+		{"duplicate insert", 1, []BlockOp{{Type: OpInsert, BlockID: a}}, ErrInvalidOp},
+		{"unknown anchor", 1, []BlockOp{{Type: OpMove, BlockID: a, After: domain.BlockID(uuid.New())}}, ErrInvalidOp},
+		{"negative indent", 1, []BlockOp{{Type: OpIndent, BlockID: a, Indent: -1}}, ErrInvalidOp},
+		{"unknown type", 1, []BlockOp{{Type: "split", BlockID: a}}, ErrInvalidOp},
+		// The first op is valid, but nothing is written when the second fails
+		{"partial", 1, []BlockOp{{Type: OpUpdate, BlockID: a, Content: "changed"}, {Type: OpDelete, BlockID: domain.BlockID(uuid.New())}}, ErrInvalidOp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.ApplyOps(doc.ID, tt.revision, tt.ops)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	loaded, err := store.GetDocument(doc.ID)
+	if err != nil {
+		t.Fatalf("GetDocument failed: %v", err)
+	}
+	if loaded.Revision != 1 || loaded.Blocks[0].Content != "only" {
+		t.Errorf("Expected the document to be unchanged, got revision %d, blocks %v", loaded.Revision, blockContents(loaded))
+	}
+}
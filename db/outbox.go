@@ -0,0 +1,438 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// indexBatchSize is the number of documents indexed per bleve.Batch
+	indexBatchSize = 200
+
+	// maxIndexRetryDelay bounds the backoff between retries of documents
+	// that failed to index
+	maxIndexRetryDelay = time.Minute
+
+	// indexLagWarning is how long a document may wait in the outbox before
+	// the index is reported unhealthy
+	indexLagWarning = 30 * time.Second
+)
+
+var indexOutboxBucket = []byte("index_outbox")
+
+// outboxEntry is a pending change of the search index, keyed by document
+// ID in the outbox. Saving a document again before it is indexed only
+// bumps Seq, so the worker never drops a newer change and Since keeps
+// the time of the oldest one.
+type outboxEntry struct {
+	Seq   uint64
+	Since time.Time
+	// Blocks are the blocks whose entries changed when only some did, as
+	// after ApplyOps; nil means the whole document.
+	Blocks []uuid.UUID
+}
+
+func (e outboxEntry) encode() []byte {
+	buf := make([]byte, 16, 16+16*len(e.Blocks))
+	binary.BigEndian.PutUint64(buf[:8], e.Seq)
+	binary.BigEndian.PutUint64(buf[8:], uint64(e.Since.UnixNano()))
+	for _, id := range e.Blocks {
+		buf = append(buf, id[:]...)
+	}
+	return buf
+}
+
+func decodeOutboxEntry(data []byte) (outboxEntry, error) {
+	if len(data) < 16 || len(data)%16 != 0 {
+		return outboxEntry{}, fmt.Errorf("invalid index outbox entry of %d bytes", len(data))
+	}
+	entry := outboxEntry{
+		Seq:   binary.BigEndian.Uint64(data[:8]),
+		Since: time.Unix(0, int64(binary.BigEndian.Uint64(data[8:16]))),
+	}
+	for rest := data[16:]; len(rest) > 0; rest = rest[16:] {
+		entry.Blocks = append(entry.Blocks, uuid.UUID(rest[:16]))
+	}
+	return entry, nil
+}
+
+// enqueueIndex records within tx that the search entries of a saved or
+// deleted document must be updated. It commits with the document, so no
+// change is lost if the process stops before the worker indexes it.
+func enqueueIndex(tx *bolt.Tx, id uuid.UUID) error {
+	return enqueue(tx, id, nil)
+}
+
+// enqueueIndexBlocks is enqueueIndex for a document of which only blocks
+// changed, so the worker updates the document entry and the entries of
+// those blocks only.
+func enqueueIndexBlocks(tx *bolt.Tx, id uuid.UUID, blocks []uuid.UUID) error {
+	if len(blocks) == 0 {
+		return enqueueIndex(tx, id)
+	}
+	return enqueue(tx, id, blocks)
+}
+
+func enqueue(tx *bolt.Tx, id uuid.UUID, blocks []uuid.UUID) error {
+	bucket := tx.Bucket(indexOutboxBucket)
+	if bucket == nil {
+		return nil
+	}
+	seq, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	entry := outboxEntry{Seq: seq, Since: time.Now(), Blocks: blocks}
+	if data := bucket.Get([]byte(id.String())); data != nil {
+		if old, err := decodeOutboxEntry(data); err == nil {
+			entry.Since = old.Since
+			// Pending block changes add up until the document is indexed;
+			// a pending whole document stays whole
+			if old.Blocks == nil || blocks == nil {
+				entry.Blocks = nil
+			} else {
+				entry.Blocks = unionIDs(old.Blocks, blocks)
+			}
+		}
+	}
+	return bucket.Put([]byte(id.String()), entry.encode())
+}
+
+func unionIDs(a []uuid.UUID, b []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]struct{}, len(a)+len(b))
+	var union []uuid.UUID
+	for _, ids := range [][]uuid.UUID{a, b} {
+		for _, id := range ids {
+			if _, ok := seen[id]; !ok {
+				seen[id] = struct{}{}
+				union = append(union, id)
+			}
+		}
+	}
+	return union
+}
+
+// indexQueue runs the worker draining the index outbox. Drains are
+// counted so that searches can wait for a drain that started after their
+// own writes committed.
+type indexQueue struct {
+	wake    chan struct{}
+	closing chan struct{}
+	done    chan struct{}
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	dirty     bool   // changes were committed since the last drain started
+	started   uint64 // drains started
+	completed uint64 // drains completed
+	stopped   bool
+}
+
+func newIndexQueue() *indexQueue {
+	q := &indexQueue{
+		wake:    make(chan struct{}, 1),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// notify wakes the worker after changes were committed to the outbox.
+func (q *indexQueue) notify() {
+	q.mu.Lock()
+	q.dirty = true
+	q.mu.Unlock()
+	q.poke()
+}
+
+func (q *indexQueue) poke() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// wait blocks until a drain that started after the call has completed,
+// unless nothing was committed since the last drain started and no drain
+// is running.
+func (q *indexQueue) wait(force bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.stopped || (!force && !q.dirty && q.started == q.completed) {
+		return
+	}
+	target := q.started + 1
+	q.poke()
+	for q.completed < target && !q.stopped {
+		q.cond.Wait()
+	}
+}
+
+// notifyIndexer wakes the indexing worker; call it after committing a
+// transaction that called saveTx or deleteTx.
+func (store *DocumentStore) notifyIndexer() {
+	if store.indexQueue != nil {
+		store.indexQueue.notify()
+	}
+}
+
+// waitIndexed waits until the changes committed so far are indexed, or
+// failed to index, so searches see the latest saves.
+func (store *DocumentStore) waitIndexed() {
+	if store.indexQueue != nil {
+		store.indexQueue.wait(false)
+	}
+}
+
+// runIndexer drains the outbox whenever woken, retrying documents that
+// failed with a growing delay, until the store is closed. Closing runs a
+// final drain.
+func (store *DocumentStore) runIndexer() {
+	q := store.indexQueue
+	defer close(q.done)
+
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-q.wake:
+		case <-retry:
+		case <-q.closing:
+			store.drainOutbox()
+			q.mu.Lock()
+			q.stopped = true
+			q.cond.Broadcast()
+			q.mu.Unlock()
+			return
+		}
+
+		q.mu.Lock()
+		q.dirty = false
+		q.started++
+		pass := q.started
+		q.mu.Unlock()
+
+		failed := store.drainOutbox()
+
+		q.mu.Lock()
+		q.completed = pass
+		q.cond.Broadcast()
+		q.mu.Unlock()
+
+		retry = nil
+		if failed > 0 {
+			retry = time.After(store.indexRetryDelay())
+		}
+	}
+}
+
+// stopIndexer stops the worker after a final drain.
+func (store *DocumentStore) stopIndexer() {
+	if store.indexQueue == nil {
+		return
+	}
+	select {
+	case <-store.indexQueue.closing:
+	default:
+		close(store.indexQueue.closing)
+	}
+	<-store.indexQueue.done
+}
+
+// indexRetryDelay grows with the attempts of the most retried failure.
+func (store *DocumentStore) indexRetryDelay() time.Duration {
+	store.failedIndexesMu.Lock()
+	defer store.failedIndexesMu.Unlock()
+
+	attempts := 0
+	for _, entry := range store.failedIndexes {
+		attempts = max(attempts, entry.attempts)
+	}
+	delay := time.Duration(attempts*attempts) * time.Second
+	return min(delay, maxIndexRetryDelay)
+}
+
+type pendingIndex struct {
+	id    uuid.UUID
+	entry outboxEntry
+}
+
+// drainOutbox indexes every document in the outbox, indexBatchSize at a
+// time, and returns the number of documents that failed to index. Those
+// stay in the outbox for the next drain.
+func (store *DocumentStore) drainOutbox() int {
+	var pending []pendingIndex
+	err := store.bolt.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(indexOutboxBucket)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			id, err := uuid.Parse(string(k))
+			if err != nil {
+				return nil
+			}
+			entry, err := decodeOutboxEntry(v)
+			if err != nil {
+				return nil
+			}
+			pending = append(pending, pendingIndex{id: id, entry: entry})
+			return nil
+		})
+	})
+	if err != nil {
+		log.Errorf("Reading the index outbox failed: %v", err)
+		return 0
+	}
+
+	failed := 0
+	for start := 0; start < len(pending); start += indexBatchSize {
+		indexed, batchFailed := store.indexBatch(pending[start:min(start+indexBatchSize, len(pending))])
+		store.matchSavedSearches(indexed)
+		failed += batchFailed
+	}
+	store.searchMu.RLock()
+	store.checkIndexHealth()
+	store.searchMu.RUnlock()
+	return failed
+}
+
+// indexBatch indexes the current state of a batch of documents in one
+// bleve.Batch, falling back to one document at a time when the batch
+// fails, and removes the indexed documents from the outbox. It returns
+// the indexed documents and the number that failed.
+func (store *DocumentStore) indexBatch(pending []pendingIndex) ([]uuid.UUID, int) {
+	docs := make([]*DocDb, len(pending)) // nil for deleted documents
+	err := store.bolt.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(store.bucketDocs)
+		for i, p := range pending {
+			data := bucket.Get([]byte(p.id.String()))
+			if data == nil {
+				continue
+			}
+			var docDb DocDb
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&docDb); err != nil {
+				return err
+			}
+			docs[i] = &docDb
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("Loading documents to index failed: %v", err)
+		return nil, len(pending)
+	}
+
+	add := func(batch *bleve.Batch, i int) error {
+		switch {
+		case docs[i] == nil:
+			return store.search.batchDelete(batch, pending[i].id.String())
+		case pending[i].entry.Blocks != nil:
+			return store.search.batchIndexBlocks(batch, docs[i], pending[i].entry.Blocks)
+		}
+		return store.search.batchIndex(batch, docs[i])
+	}
+
+	store.searchMu.RLock()
+	errs := make([]error, len(pending))
+	batch := store.search.index.NewBatch()
+	for i := range pending {
+		errs[i] = add(batch, i)
+	}
+	if err := errors.Join(errs...); err != nil || store.search.index.Batch(batch) != nil {
+		// Isolates the documents that fail
+		for i := range pending {
+			single := store.search.index.NewBatch()
+			if errs[i] = add(single, i); errs[i] == nil {
+				errs[i] = store.search.index.Batch(single)
+			}
+		}
+	}
+	store.searchMu.RUnlock()
+
+	failed := 0
+	var done []pendingIndex
+	var indexed []uuid.UUID
+	for i, p := range pending {
+		if errs[i] == nil {
+			done = append(done, p)
+			indexed = append(indexed, p.id)
+			store.failedIndexesMu.Lock()
+			delete(store.failedIndexes, p.id.String())
+			store.failedIndexesMu.Unlock()
+			continue
+		}
+
+		failed++
+		log.Warnf("Bleve indexing failed for document %s: %v", p.id, errs[i])
+		store.failedIndexesMu.Lock()
+		entry, exists := store.failedIndexes[p.id.String()]
+		if !exists {
+			entry = &failedIndexEntry{docID: p.id.String()}
+			store.failedIndexes[p.id.String()] = entry
+		}
+		entry.attempts++
+		entry.lastAttempt = time.Now()
+		store.failedIndexesMu.Unlock()
+		if !exists && docs[i] != nil {
+			store.recordIndexFailed(docs[i], errs[i])
+		}
+	}
+
+	// Documents changed again while being indexed stay in the outbox
+	err = store.bolt.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(indexOutboxBucket)
+		for _, p := range done {
+			data := bucket.Get([]byte(p.id.String()))
+			if data == nil {
+				continue
+			}
+			if entry, err := decodeOutboxEntry(data); err == nil && entry.Seq != p.entry.Seq {
+				continue
+			}
+			if err := bucket.Delete([]byte(p.id.String())); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("Removing indexed documents from the outbox failed: %v", err)
+	}
+	return indexed, failed
+}
+
+// indexQueueStats returns the number of documents waiting in the outbox
+// and how long the oldest one has waited.
+func (store *DocumentStore) indexQueueStats() (int, time.Duration) {
+	depth := 0
+	var oldest time.Time
+	_ = store.bolt.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(indexOutboxBucket)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			depth++
+			if entry, err := decodeOutboxEntry(v); err == nil && (oldest.IsZero() || entry.Since.Before(oldest)) {
+				oldest = entry.Since
+			}
+			return nil
+		})
+	})
+	if depth == 0 {
+		return 0, 0
+	}
+	return depth, time.Since(oldest)
+}
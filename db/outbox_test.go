@@ -0,0 +1,117 @@
+package db
+
+import (
+	"glog/domain"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestIndexOutbox(t *testing.T) {
+	store, err := NewDocumentStore("./testoutbox.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = os.Remove("./testoutbox.db")
+		_ = os.RemoveAll("./testoutbox.db.bleve")
+	}()
+
+	// Searches wait for the worker to index saves
+	first := saveTestDoc(t, store, "First", "quokka")
+	if ids, _ := store.Search("quokka"); len(ids) != 1 || ids[0] != first.ID {
+		t.Errorf("Expected the saved document to be found, got %v", ids)
+	}
+	if health := store.GetIndexHealth(); health.QueueDepth != 0 || health.QueueLag != 0 {
+		t.Errorf("Expected an empty queue after indexing, got %+v", health)
+	}
+
+	if err := store.Delete(uuid.UUID(first.ID)); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if ids, _ := store.Search("quokka"); len(ids) != 0 {
+		t.Errorf("Expected the deleted document to be unindexed, got %v", ids)
+	}
+
+	// Changes stay in the outbox while no worker runs
+	store.stopIndexer()
+	var pending []*domain.Document
+	for i := 0; i < indexBatchSize+10; i++ {
+		pending = append(pending, saveTestDoc(t, store, "Pending "+uuid.NewString(), "wombat"))
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	health := store.GetIndexHealth()
+	if health.QueueDepth != len(pending) {
+		t.Errorf("Expected a queue depth of %d, got %d", len(pending), health.QueueDepth)
+	}
+	if health.QueueLag < 10*time.Millisecond {
+		t.Errorf("Expected the queue lag of the oldest change, got %s", health.QueueLag)
+	}
+	if ids, _ := store.Search("wombat"); len(ids) != 0 {
+		t.Errorf("Expected pending documents not to be indexed yet, got %d", len(ids))
+	}
+	_ = store.Close()
+
+	// The outbox is drained when the store opens again
+	store, err = NewDocumentStore("./testoutbox.db")
+	if err != nil {
+		t.Fatalf("Failed to reopen DocumentStore: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	results, err := store.SearchPage("wombat", 0, 10, SortRelevance)
+	if err != nil {
+		t.Fatalf("SearchPage failed: %v", err)
+	}
+	if results.Total != len(pending) {
+		t.Errorf("Expected %d documents indexed after reopening, got %d", len(pending), results.Total)
+	}
+	if health := store.GetIndexHealth(); health.QueueDepth != 0 || !health.IsHealthy {
+		t.Errorf("Expected a drained, healthy queue, got %+v", health)
+	}
+}
+
+func TestOutboxMergesBlockChanges(t *testing.T) {
+	store, err := NewDocumentStore("./testoutboxblocks.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove("./testoutboxblocks.db")
+		_ = os.RemoveAll("./testoutboxblocks.db.bleve")
+	}()
+	store.stopIndexer()
+
+	id := uuid.New()
+	a, b := uuid.New(), uuid.New()
+	pending := func(changes ...[]uuid.UUID) outboxEntry {
+		var entry outboxEntry
+		err := store.bolt.Update(func(tx *bolt.Tx) error {
+			for _, blocks := range changes {
+				if err := enqueueIndexBlocks(tx, id, blocks); err != nil {
+					return err
+				}
+			}
+			var err error
+			entry, err = decodeOutboxEntry(tx.Bucket(indexOutboxBucket).Get([]byte(id.String())))
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Enqueueing failed: %v", err)
+		}
+		return entry
+	}
+
+	if entry := pending([]uuid.UUID{a}, []uuid.UUID{b, a}); len(entry.Blocks) != 2 || entry.Blocks[0] != a || entry.Blocks[1] != b {
+		t.Errorf("Expected the block changes to add up, got %v", entry.Blocks)
+	}
+	// A whole document change covers the pending block changes and later ones
+	if entry := pending(nil, []uuid.UUID{a}); entry.Blocks != nil {
+		t.Errorf("Expected the whole document to be pending, got %v", entry.Blocks)
+	}
+}
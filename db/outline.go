@@ -0,0 +1,69 @@
+package db
+
+import (
+	"glog/domain"
+	"strings"
+)
+
+// ParentIndexes returns the index of the parent of every block, or -1 for
+// top-level blocks. A block's parent is the closest previous block with a
+// smaller indent.
+func ParentIndexes(blocks []*domain.Block) []int {
+	parents := make([]int, len(blocks))
+	stack := make([]int, 0, 16)
+
+	for i, block := range blocks {
+		for len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if blocks[top].Indent < block.Indent {
+				break
+			}
+			stack = stack[:len(stack)-1]
+		}
+
+		if len(stack) == 0 {
+			parents[i] = -1
+		} else {
+			parents[i] = stack[len(stack)-1]
+		}
+
+		stack = append(stack, i)
+	}
+
+	return parents
+}
+
+// SubtreeEnd returns the index after the last descendant of blocks[i], so
+// blocks[i:SubtreeEnd(blocks, i)] is the block with its children.
+func SubtreeEnd(blocks []*domain.Block, i int) int {
+	end := i + 1
+	for end < len(blocks) && blocks[end].Indent > blocks[i].Indent {
+		end++
+	}
+	return end
+}
+
+// Breadcrumb returns the first lines of the ancestors of blocks[i],
+// outermost first.
+func Breadcrumb(blocks []*domain.Block, i int) []string {
+	var crumbs []string
+	indent := blocks[i].Indent
+	for j := i - 1; j >= 0 && indent > 0; j-- {
+		if blocks[j].Indent < indent {
+			line, _, _ := strings.Cut(blocks[j].Content, "\n")
+			crumbs = append([]string{strings.TrimSpace(line)}, crumbs...)
+			indent = blocks[j].Indent
+		}
+	}
+	return crumbs
+}
+
+// findBlock returns the index of the block with the given ID, or -1.
+func findBlock(blocks []*domain.Block, id domain.BlockID) int {
+	for i, block := range blocks {
+		if block.ID == id {
+			return i
+		}
+	}
+	return -1
+}
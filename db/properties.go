@@ -0,0 +1,107 @@
+package db
+
+import (
+	"glog/domain"
+	"regexp"
+	"strings"
+)
+
+// propertyRegex matches a "key:: value" property line
+var propertyRegex = regexp.MustCompile(`^([A-Za-z][\w-]*)::\s*(.*)$`)
+
+// tagRegex matches inline tags: #tag or #[[multi word tag]]
+var tagRegex = regexp.MustCompile(`(?:^|\s)#(?:\[\[([^\[\]]+)\]\]|([\p{L}\p{N}_/-]+))`)
+
+// PageProperties returns the "key:: value" properties of a document. Like
+// in Logseq, page properties are the property lines of the first block.
+// Keys are lower-cased; the first occurrence of a key wins.
+func PageProperties(doc *domain.Document) map[string]string {
+	props := make(map[string]string)
+	if doc == nil || len(doc.Blocks) == 0 || doc.Blocks[0] == nil {
+		return props
+	}
+
+	for _, line := range strings.Split(doc.Blocks[0].Content, "\n") {
+		match := propertyRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		key := strings.ToLower(match[1])
+		if _, exists := props[key]; !exists {
+			props[key] = strings.TrimSpace(match[2])
+		}
+	}
+
+	return props
+}
+
+// PropertyValues splits a comma-separated property value into its items,
+// removing [[link]] brackets and leading '#' characters.
+func PropertyValues(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		item = strings.TrimPrefix(item, "#")
+		item = strings.TrimSuffix(strings.TrimPrefix(item, "[["), "]]")
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// DocumentTags returns the tags of a document: the values of the "tags::"
+// page property plus inline #tag and #[[tag]] occurrences in any block.
+// Duplicates (compared case-insensitively) are removed.
+func DocumentTags(doc *domain.Document) []string {
+	if doc == nil {
+		return nil
+	}
+
+	seen := make(map[string]struct{})
+	var tags []string
+	add := func(tag string) {
+		key := strings.ToLower(tag)
+		if _, exists := seen[key]; exists {
+			return
+		}
+		seen[key] = struct{}{}
+		tags = append(tags, tag)
+	}
+
+	for _, tag := range PropertyValues(PageProperties(doc)["tags"]) {
+		add(tag)
+	}
+
+	for _, block := range doc.Blocks {
+		if block == nil {
+			continue
+		}
+		for _, match := range tagRegex.FindAllStringSubmatch(block.Content, -1) {
+			tag := match[1]
+			if tag == "" {
+				tag = match[2]
+			}
+			if tag = strings.TrimSpace(tag); tag != "" {
+				add(tag)
+			}
+		}
+	}
+
+	return tags
+}
+
+// HasTag reports whether a document has the given tag (case-insensitive).
+func HasTag(doc *domain.Document, tag string) bool {
+	for _, t := range DocumentTags(doc) {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// DocumentAliases returns the values of the "alias::" page property.
+func DocumentAliases(doc *domain.Document) []string {
+	return PropertyValues(PageProperties(doc)["alias"])
+}
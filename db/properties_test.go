@@ -0,0 +1,41 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPageProperties(t *testing.T) {
+	doc := testDoc("Doc", "public:: true\nTags:: [[work]], #urgent\nalias:: PX, Project X", "later:: ignored")
+
+	props := PageProperties(doc)
+	if props["public"] != "true" {
+		t.Errorf("public = %q, want %q", props["public"], "true")
+	}
+	if props["tags"] != "[[work]], #urgent" {
+		t.Errorf("tags = %q", props["tags"])
+	}
+	if _, ok := props["later"]; ok {
+		t.Error("Expected only first block properties to be page properties")
+	}
+
+	if got := DocumentAliases(doc); !reflect.DeepEqual(got, []string{"PX", "Project X"}) {
+		t.Errorf("DocumentAliases() = %v", got)
+	}
+}
+
+func TestDocumentTags(t *testing.T) {
+	doc := testDoc("Doc", "tags:: work, Urgent", "Meeting #standup and #[[deep work]]", "issue #42 and C# code #work")
+
+	want := []string{"work", "Urgent", "standup", "deep work", "42"}
+	if got := DocumentTags(doc); !reflect.DeepEqual(got, want) {
+		t.Errorf("DocumentTags() = %v, want %v", got, want)
+	}
+
+	if !HasTag(doc, "urgent") {
+		t.Error("Expected HasTag to be case-insensitive")
+	}
+	if HasTag(doc, "code") {
+		t.Error("Expected plain words not to be tags")
+	}
+}
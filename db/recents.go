@@ -16,14 +16,7 @@ type recentsDocs struct {
 func newRecentsDocs(db *bolt.DB) (*recentsDocs, error) {
 	recentsBucket := []byte("recents_index")
 
-	err := db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists(recentsBucket)
-		if err != nil {
-			return err
-		}
-
-		return nil
-	})
+	err := ensureBuckets(db, recentsBucket)
 	if err != nil {
 		return nil, err
 	}
@@ -150,3 +143,43 @@ func (r *recentsDocs) update(tx *bolt.Tx, id uuid.UUID) error {
 
 	return bucket.Put([]byte("recents_list"), updatedRecentsData)
 }
+
+// replace puts newID in the place of oldID in the recents list, unless
+// newID is more recent already, and removes oldID.
+func (r *recentsDocs) replace(tx *bolt.Tx, oldID uuid.UUID, newID uuid.UUID) error {
+	data := tx.Bucket(r.recentsBucket).Get([]byte("recents_list"))
+	if data == nil {
+		return nil
+	}
+
+	recentsList, err := deserializeRecents(data)
+	if err != nil {
+		return err
+	}
+
+	replaced := make([]uuid.UUID, 0, len(recentsList))
+	seen := false
+	for _, id := range recentsList {
+		if id == oldID {
+			id = newID
+		}
+		if id == newID {
+			if seen {
+				continue
+			}
+			seen = true
+		}
+		replaced = append(replaced, id)
+	}
+
+	return r.put(tx, replaced)
+}
+
+// put replaces the recents list.
+func (r *recentsDocs) put(tx *bolt.Tx, ids []uuid.UUID) error {
+	data, err := serializeRecents(ids)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(r.recentsBucket).Put([]byte("recents_list"), data)
+}
@@ -23,19 +23,7 @@ type referencesIndex struct {
 func newReferencesIndex(db *bolt.DB) (*referencesIndex, error) {
 	referencesKey := []byte("references_index")
 	docReferenceKey := []byte("doc_reference_index")
-	err := db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists(referencesKey)
-		if err != nil {
-			return err
-		}
-
-		_, err = tx.CreateBucketIfNotExists(docReferenceKey)
-		if err != nil {
-			return err
-		}
-		return nil
-	})
-
+	err := ensureBuckets(db, referencesKey, docReferenceKey)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"glog/domain"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// Weights of the signals GetRelatedDocuments combines: each shared
+	// link and tag, and text similarity from 0 to 1
+	relatedLinkWeight = 1.0
+	relatedTagWeight  = 1.0
+	relatedTextWeight = 2.0
+
+	// minTextSimilarity is the similarity below which text does not count
+	minTextSimilarity = 0.15
+
+	// maxRelatedCandidates bounds the documents each signal contributes
+	maxRelatedCandidates = 200
+)
+
+// RelatedDocument is a document GetRelatedDocuments suggests, with the
+// signals its Score is made of.
+type RelatedDocument struct {
+	DocumentSummary
+	Score       float64
+	SharedLinks []string // pages both documents link to
+	SharedTags  []string
+	Similarity  float64 // text similarity from 0 to 1, 0 when too low to count
+}
+
+// Reason explains the score, like "3 shared links, similar text".
+func (r RelatedDocument) Reason() string {
+	var parts []string
+	plural := func(n int, word string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", word)
+		}
+		return fmt.Sprintf("%d %ss", n, word)
+	}
+	if n := len(r.SharedLinks); n > 0 {
+		parts = append(parts, plural(n, "shared link"))
+	}
+	if n := len(r.SharedTags); n > 0 {
+		parts = append(parts, plural(n, "shared tag"))
+	}
+	if r.Similarity > 0 {
+		parts = append(parts, "similar text")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// GetRelatedDocuments suggests up to limit documents related to the
+// document id that it neither links nor is linked from. Documents score
+// for the pages both link to (co-citation), the tags they share and the
+// similarity of their text in the search index, and are ordered by score.
+func (store *DocumentStore) GetRelatedDocuments(id domain.DocumentID, limit int) ([]RelatedDocument, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	var doc *domain.Document
+	links := make(map[string]string) // lowercased to linked titles
+	linking := make(map[uuid.UUID]bool)
+	candidates := make(map[uuid.UUID]*RelatedDocument)
+	candidate := func(id uuid.UUID) *RelatedDocument {
+		if candidates[id] == nil {
+			candidates[id] = &RelatedDocument{DocumentSummary: DocumentSummary{ID: domain.DocumentID(id)}}
+		}
+		return candidates[id]
+	}
+
+	err := store.bolt.View(func(tx *bolt.Tx) error {
+		docDb, err := store.getDocDb(tx, id)
+		if err != nil {
+			return err
+		}
+		doc = docDbToDomain(docDb)
+		for _, title := range getReferencedTitles(docDb) {
+			links[strings.ToLower(title)] = title
+		}
+
+		references := tx.Bucket(store.referencesIndex.referenceIndex)
+		for _, name := range append([]string{doc.Title}, DocumentAliases(doc)...) {
+			for linkID := range decodeUUIDSet(references.Get([]byte(strings.ToLower(name)))) {
+				linking[linkID] = true
+			}
+		}
+
+		// Co-citation: documents linking the pages this one links
+		for key, title := range links {
+			for other := range decodeUUIDSet(references.Get([]byte(key))) {
+				if other == uuid.UUID(id) || len(candidates) >= maxRelatedCandidates && candidates[other] == nil {
+					continue
+				}
+				c := candidate(other)
+				c.SharedLinks = append(c.SharedLinks, title)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	text := []string{doc.Title}
+	for _, block := range doc.Blocks {
+		text = append(text, block.Content)
+	}
+
+	store.waitIndexed()
+	store.searchMu.RLock()
+	similar, selfScore, err := store.search.SimilarDocs(uuid.UUID(id).String(), strings.Join(text, "\n"), maxRelatedCandidates)
+	var tagged map[uuid.UUID][]string
+	if err == nil {
+		tagged, err = store.search.TaggedDocs(DocumentTags(doc), maxRelatedCandidates)
+	}
+	store.searchMu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hit := range similar {
+		if similarity := min(hit.Score/selfScore, 1); similarity >= minTextSimilarity {
+			candidate(hit.ID).Similarity = similarity
+		}
+	}
+	for other, tags := range tagged {
+		if other != uuid.UUID(id) {
+			candidate(other).SharedTags = tags
+		}
+	}
+
+	var related []RelatedDocument
+	err = store.bolt.View(func(tx *bolt.Tx) error {
+		for otherID, c := range candidates {
+			if linking[otherID] {
+				continue
+			}
+			docDb, err := store.getDocDb(tx, c.ID)
+			if errors.Is(err, ErrDocumentNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if isLinked(links, docDbToDomain(docDb)) {
+				continue
+			}
+
+			c.Title = docDb.Title
+			c.Date, _ = time.Parse(time.RFC3339, docDb.Date)
+			sort.Strings(c.SharedLinks)
+			c.Score = relatedLinkWeight*float64(len(c.SharedLinks)) +
+				relatedTagWeight*float64(len(c.SharedTags)) +
+				relatedTextWeight*c.Similarity
+			if c.Score > 0 {
+				related = append(related, *c)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(related, func(i, j int) bool {
+		if related[i].Score != related[j].Score {
+			return related[i].Score > related[j].Score
+		}
+		return strings.ToLower(related[i].Title) < strings.ToLower(related[j].Title)
+	})
+	return related[:min(len(related), limit)], nil
+}
+
+// isLinked reports whether links, keyed by lowercased title, has the
+// title or an alias of doc.
+func isLinked(links map[string]string, doc *domain.Document) bool {
+	for _, name := range append([]string{doc.Title}, DocumentAliases(doc)...) {
+		if _, ok := links[strings.ToLower(name)]; ok {
+			return true
+		}
+	}
+	return false
+}
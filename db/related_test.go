@@ -0,0 +1,63 @@
+package db
+
+import (
+	"glog/domain"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestGetRelatedDocuments(t *testing.T) {
+	store, err := NewDocumentStore("./testrelated.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove("./testrelated.db")
+		_ = os.RemoveAll("./testrelated.db.bleve")
+	}()
+
+	alpha := saveTestDoc(t, store, "Alpha", "Comparing [[Go]] and [[Rust]] #languages", "Compilers, garbage collection and goroutines").ID
+	beta := saveTestDoc(t, store, "Beta", "Notes on [[Go]]", "More on [[Rust]]").ID
+	gamma := saveTestDoc(t, store, "Gamma", "#languages", "Compilers with garbage collection scan goroutines").ID
+	saveTestDoc(t, store, "Delta", "See [[Alpha]] about [[Go]] and [[Rust]]")
+	saveTestDoc(t, store, "Go", "Compilers and goroutines, garbage collection")
+	saveTestDoc(t, store, "Bread", "Flour, water, salt and patience")
+
+	related, err := store.GetRelatedDocuments(alpha, 10)
+	if err != nil {
+		t.Fatalf("GetRelatedDocuments failed: %v", err)
+	}
+
+	// Delta links Alpha and Alpha links Go, so neither is suggested
+	var titles []string
+	for _, r := range related {
+		titles = append(titles, r.Title)
+	}
+	if strings.Join(titles, ",") != "Beta,Gamma" {
+		t.Fatalf("Expected Beta and Gamma, got %v", titles)
+	}
+
+	if related[0].ID != beta || strings.Join(related[0].SharedLinks, ",") != "Go,Rust" {
+		t.Errorf("Expected Beta to share two links, got %+v", related[0])
+	}
+	if got := related[0].Reason(); !strings.HasPrefix(got, "2 shared links") {
+		t.Errorf("Unexpected reason for Beta: %q", got)
+	}
+	if related[1].ID != gamma || len(related[1].SharedTags) != 1 || related[1].Similarity < related[0].Similarity {
+		t.Errorf("Expected Gamma to share a tag and the most text, got %+v", related[1])
+	}
+	if got := related[1].Reason(); got != "1 shared tag, similar text" {
+		t.Errorf("Unexpected reason for Gamma: %q", got)
+	}
+
+	if related, _ := store.GetRelatedDocuments(alpha, 1); len(related) != 1 {
+		t.Errorf("Expected the limit to apply, got %d", len(related))
+	}
+	if _, err := store.GetRelatedDocuments(domain.DocumentID(uuid.New()), 10); err == nil {
+		t.Errorf("Expected an error for a missing document")
+	}
+}
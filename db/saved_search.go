@@ -0,0 +1,287 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"glog/domain"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+var ErrDuplicateSearchName = errors.New("saved search name already exists")
+
+var (
+	savedSearchesBucket      = []byte("saved_searches")
+	savedSearchMatchesBucket = []byte("saved_search_matches")
+)
+
+// SavedSearch is a named search query. It opens like a page, with the
+// live results of the query.
+type SavedSearch struct {
+	ID      uuid.UUID `json:"id"`
+	Name    string    `json:"name"`
+	Query   string    `json:"query"`
+	Notify  bool      `json:"notify,omitempty"` // record SearchMatched events for new matches
+	Created time.Time `json:"created"`
+}
+
+// SaveSearch creates a saved search, or updates the one with the same ID.
+// Names are unique, ignoring case. With Notify, the documents matching
+// when the search is saved do not raise SearchMatched events; documents
+// that start matching later do, once they are indexed.
+func (store *DocumentStore) SaveSearch(search SavedSearch) (*SavedSearch, error) {
+	if store.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	search.Name = strings.TrimSpace(search.Name)
+	search.Query = strings.TrimSpace(search.Query)
+	if search.Name == "" {
+		return nil, fmt.Errorf("%w: a saved search needs a name", ErrInvalidQuery)
+	}
+	q, err := parseSearchQuery(search.Query)
+	if err != nil {
+		return nil, err
+	}
+	if q.empty() {
+		return nil, fmt.Errorf("%w: empty query", ErrInvalidQuery)
+	}
+	if search.ID == uuid.Nil {
+		search.ID = uuid.New()
+	}
+
+	var matches []domain.DocumentID
+	if search.Notify {
+		if matches, err = store.Search(search.Query); err != nil {
+			return nil, err
+		}
+	}
+
+	err = store.bolt.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(savedSearchesBucket)
+		if err != nil {
+			return err
+		}
+		matchesBucket, err := tx.CreateBucketIfNotExists(savedSearchMatchesBucket)
+		if err != nil {
+			return err
+		}
+
+		search.Created = time.Now().UTC()
+		err = bucket.ForEach(func(k, v []byte) error {
+			var other SavedSearch
+			if err := json.Unmarshal(v, &other); err != nil {
+				return err
+			}
+			if other.ID == search.ID {
+				search.Created = other.Created
+			} else if strings.EqualFold(other.Name, search.Name) {
+				return fmt.Errorf("%w: %q", ErrDuplicateSearchName, search.Name)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(search)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put([]byte(search.ID.String()), data); err != nil {
+			return err
+		}
+
+		if !search.Notify {
+			return matchesBucket.Delete([]byte(search.ID.String()))
+		}
+		ids := make(map[uuid.UUID]struct{}, len(matches))
+		for _, id := range matches {
+			ids[uuid.UUID(id)] = struct{}{}
+		}
+		encoded, err := encodeUUIDSet(ids)
+		if err != nil {
+			return err
+		}
+		return matchesBucket.Put([]byte(search.ID.String()), encoded)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+// GetSavedSearch returns the saved search with the given ID.
+func (store *DocumentStore) GetSavedSearch(id uuid.UUID) (*SavedSearch, error) {
+	var search *SavedSearch
+	err := store.bolt.View(func(tx *bolt.Tx) error {
+		var err error
+		search, err = savedSearchTx(tx, id)
+		return err
+	})
+	return search, err
+}
+
+// ListSavedSearches returns the saved searches ordered by name.
+func (store *DocumentStore) ListSavedSearches() ([]SavedSearch, error) {
+	searches := []SavedSearch{}
+	err := store.bolt.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(savedSearchesBucket)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var search SavedSearch
+			if err := json.Unmarshal(v, &search); err != nil {
+				return fmt.Errorf("saved search %s: %w", k, err)
+			}
+			searches = append(searches, search)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(searches, func(i, j int) bool {
+		return strings.ToLower(searches[i].Name) < strings.ToLower(searches[j].Name)
+	})
+	return searches, nil
+}
+
+// DeleteSavedSearch deletes a saved search.
+func (store *DocumentStore) DeleteSavedSearch(id uuid.UUID) error {
+	if store.readOnly {
+		return ErrReadOnly
+	}
+
+	return store.bolt.Update(func(tx *bolt.Tx) error {
+		if _, err := savedSearchTx(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Bucket(savedSearchesBucket).Delete([]byte(id.String())); err != nil {
+			return err
+		}
+		if bucket := tx.Bucket(savedSearchMatchesBucket); bucket != nil {
+			return bucket.Delete([]byte(id.String()))
+		}
+		return nil
+	})
+}
+
+// RunSavedSearch returns a page of the current results of a saved search,
+// see SearchPage.
+func (store *DocumentStore) RunSavedSearch(id uuid.UUID, offset int, limit int, order SearchSort) (*SavedSearch, *SearchResults, error) {
+	search, err := store.GetSavedSearch(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	results, err := store.SearchPage(search.Query, offset, limit, order)
+	if err != nil {
+		return nil, nil, err
+	}
+	return search, results, nil
+}
+
+func savedSearchTx(tx *bolt.Tx, id uuid.UUID) (*SavedSearch, error) {
+	bucket := tx.Bucket(savedSearchesBucket)
+	if bucket == nil {
+		return nil, ErrSavedSearchNotFound
+	}
+	data := bucket.Get([]byte(id.String()))
+	if data == nil {
+		return nil, ErrSavedSearchNotFound
+	}
+	var search SavedSearch
+	if err := json.Unmarshal(data, &search); err != nil {
+		return nil, fmt.Errorf("saved search %s: %w", id, err)
+	}
+	return &search, nil
+}
+
+// matchSavedSearches records a SearchMatched event for each of the just
+// indexed documents that a notifying saved search matches and did not
+// match before. Documents that stop matching are forgotten, so matching
+// again notifies again.
+func (store *DocumentStore) matchSavedSearches(ids []uuid.UUID) {
+	if len(ids) == 0 {
+		return
+	}
+	searches, err := store.ListSavedSearches()
+	if err != nil {
+		log.Errorf("Loading saved searches failed: %v", err)
+		return
+	}
+
+	docIDs := make([]string, len(ids))
+	for i, id := range ids {
+		docIDs[i] = id.String()
+	}
+	for _, search := range searches {
+		if !search.Notify {
+			continue
+		}
+
+		store.searchMu.RLock()
+		matching, err := store.search.MatchingDocs(search.Query, docIDs)
+		store.searchMu.RUnlock()
+		if err != nil {
+			log.Warnf("Saved search %q failed: %v", search.Name, err)
+			continue
+		}
+
+		err = store.bolt.Update(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(savedSearchMatchesBucket)
+			if bucket == nil || bucket.Get([]byte(search.ID.String())) == nil {
+				// Deleted, or no longer notifying, meanwhile
+				return nil
+			}
+			matched := decodeUUIDSet(bucket.Get([]byte(search.ID.String())))
+
+			for _, id := range ids {
+				if !matching[id] {
+					delete(matched, id)
+					continue
+				}
+				if _, ok := matched[id]; ok {
+					continue
+				}
+				docDb, err := store.getDocDb(tx, domain.DocumentID(id))
+				if errors.Is(err, ErrDocumentNotFound) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				matched[id] = struct{}{}
+				err = store.recordEvent(tx, Event{
+					Type:      EventSearchMatched,
+					DocID:     domain.DocumentID(id),
+					Title:     docDb.Title,
+					IsJournal: docDb.IsJournal,
+					SearchID:  search.ID,
+					Search:    search.Name,
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			encoded, err := encodeUUIDSet(matched)
+			if err != nil {
+				return err
+			}
+			return bucket.Put([]byte(search.ID.String()), encoded)
+		})
+		if err != nil {
+			log.Errorf("Recording matches of saved search %q failed: %v", search.Name, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSavedSearches(t *testing.T) {
+	store, err := NewDocumentStore("./testsavedsearch.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove("./testsavedsearch.db")
+		_ = os.RemoveAll("./testsavedsearch.db.bleve")
+	}()
+
+	matched := func(sub *Subscription) []Event {
+		store.waitIndexed()
+		var events []Event
+		for {
+			select {
+			case ev := <-sub.C:
+				if ev.Type == EventSearchMatched {
+					events = append(events, ev)
+				}
+			default:
+				return events
+			}
+		}
+	}
+
+	first := saveTestDoc(t, store, "Outage", "incident in production")
+	search, err := store.SaveSearch(SavedSearch{Name: " Incidents ", Query: "incident", Notify: true})
+	if err != nil {
+		t.Fatalf("SaveSearch failed: %v", err)
+	}
+	if search.ID == uuid.Nil || search.Name != "Incidents" || search.Created.IsZero() {
+		t.Errorf("Expected a new saved search, got %+v", search)
+	}
+
+	for _, invalid := range []SavedSearch{
+		{Name: "incidents", Query: "outage"},
+		{Name: "Bogus", Query: "is:bogus"},
+		{Name: "", Query: "incident"},
+		{Name: "Empty", Query: "  "},
+	} {
+		_, err := store.SaveSearch(invalid)
+		if !errors.Is(err, ErrDuplicateSearchName) && !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("Expected SaveSearch(%+v) to fail, got %v", invalid, err)
+		}
+	}
+
+	sub := store.Subscribe(64)
+	defer store.Unsubscribe(sub)
+
+	// Only documents that start matching notify
+	first.Blocks[0].Content = "incident in production, resolved"
+	if err := store.Save(first); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	second := saveTestDoc(t, store, "Pager", "another incident")
+	saveTestDoc(t, store, "Lunch", "sandwiches")
+	events := matched(sub)
+	if len(events) != 1 || events[0].DocID != second.ID || events[0].SearchID != search.ID || events[0].Search != "Incidents" {
+		t.Errorf("Expected one SearchMatched event for the new match, got %+v", events)
+	}
+
+	if err := store.Save(second); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if events := matched(sub); len(events) != 0 {
+		t.Errorf("Expected no event for a document still matching, got %+v", events)
+	}
+
+	// Matching again after no longer matching notifies again
+	second.Blocks[0].Content = "all quiet"
+	if err := store.Save(second); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if events := matched(sub); len(events) != 0 {
+		t.Errorf("Expected no event for a document no longer matching, got %+v", events)
+	}
+	second.Blocks[0].Content = "incident again"
+	if err := store.Save(second); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if events := matched(sub); len(events) != 1 {
+		t.Errorf("Expected a SearchMatched event for the returning match, got %+v", events)
+	}
+
+	got, results, err := store.RunSavedSearch(search.ID, 0, 10, SortTitle)
+	if err != nil {
+		t.Fatalf("RunSavedSearch failed: %v", err)
+	}
+	if got.Name != "Incidents" || results.Total != 2 || results.Hits[0].Title != "Outage" {
+		t.Errorf("Expected the two live results, got %+v", results)
+	}
+
+	renamed, err := store.SaveSearch(SavedSearch{ID: search.ID, Name: "Oncall", Query: "links:[[oncall]]"})
+	if err != nil {
+		t.Fatalf("SaveSearch failed: %v", err)
+	}
+	if !renamed.Created.Equal(search.Created) {
+		t.Errorf("Expected the creation time to be kept, got %v", renamed.Created)
+	}
+	if _, err := store.SaveSearch(SavedSearch{Name: "Incidents", Query: "incident"}); err != nil {
+		t.Errorf("Expected the old name to be free, got %v", err)
+	}
+	searches, err := store.ListSavedSearches()
+	if err != nil || len(searches) != 2 || searches[0].Name != "Incidents" || searches[1].Name != "Oncall" {
+		t.Errorf("Expected the saved searches by name, got %+v (err %v)", searches, err)
+	}
+
+	if err := store.DeleteSavedSearch(search.ID); err != nil {
+		t.Fatalf("DeleteSavedSearch failed: %v", err)
+	}
+	if _, err := store.GetSavedSearch(search.ID); !errors.Is(err, ErrSavedSearchNotFound) {
+		t.Errorf("Expected ErrSavedSearchNotFound, got %v", err)
+	}
+	if err := store.DeleteSavedSearch(search.ID); !errors.Is(err, ErrSavedSearchNotFound) {
+		t.Errorf("Expected ErrSavedSearchNotFound, got %v", err)
+	}
+}
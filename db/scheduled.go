@@ -30,19 +30,7 @@ func newScheduledTasks(db *bolt.DB) (*scheduledTasks, error) {
 	scheduledIndexKey := []byte("scheduled_index")
 	scheduledInvertedIndexKey := []byte("scheduled_inverted_index")
 
-	err := db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists(scheduledIndexKey)
-		if err != nil {
-			return err
-		}
-
-		_, err = tx.CreateBucketIfNotExists(scheduledInvertedIndexKey)
-		if err != nil {
-			return err
-		}
-
-		return nil
-	})
+	err := ensureBuckets(db, scheduledIndexKey, scheduledInvertedIndexKey)
 	if err != nil {
 		return nil, err
 	}
@@ -359,6 +347,62 @@ func (s *scheduledTasks) delete(tx *bolt.Tx, doc *DocDb) error {
 	return nil
 }
 
+// migrate moves the scheduled tasks of blocks from document srcID to
+// document dstID, keeping the task IDs and dates.
+func (s *scheduledTasks) migrate(tx *bolt.Tx, srcID uuid.UUID, dstID uuid.UUID, blockIDs []uuid.UUID) error {
+	invertedBucket := tx.Bucket(s.scheduledInvertedIndex)
+	bucket := tx.Bucket(s.scheduledIndex)
+	if invertedBucket == nil || bucket == nil {
+		return fmt.Errorf("scheduled index bucket not found")
+	}
+
+	for _, blockID := range blockIDs {
+		srcKey := []byte(fmt.Sprintf("%s_%s", srcID.String(), blockID.String()))
+		data := invertedBucket.Get(srcKey)
+		if data == nil {
+			continue
+		}
+
+		dateSet, err := decodeScheduledDates(data)
+		if err != nil {
+			return err
+		}
+
+		for dateStr := range dateSet {
+			values := bucket.Get([]byte(dateStr))
+			if values == nil {
+				continue
+			}
+			tasks, err := decodeScheduleTasksDb(values)
+			if err != nil {
+				return err
+			}
+			for i := range tasks {
+				if tasks[i].DocDbID == srcID && tasks[i].BlockDbID == blockID {
+					tasks[i].DocDbID = dstID
+				}
+			}
+			encoded, err := encodeScheduleTaskDb(tasks)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(dateStr), encoded); err != nil {
+				return err
+			}
+		}
+
+		dstKey := []byte(fmt.Sprintf("%s_%s", dstID.String(), blockID.String()))
+		if err := invertedBucket.Put(dstKey, append([]byte(nil), data...)); err != nil {
+			return err
+		}
+		if err := invertedBucket.Delete(srcKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func encodeScheduleTaskDb(tasks []ScheduleTaskDb) ([]byte, error) {
 	// Encode using gob
 	var buf bytes.Buffer
@@ -0,0 +1,234 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/analysis/char/asciifolding"
+	"github.com/blevesearch/bleve/v2/analysis/lang/cjk"
+	"github.com/blevesearch/bleve/v2/analysis/lang/da"
+	"github.com/blevesearch/bleve/v2/analysis/lang/de"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/analysis/lang/es"
+	"github.com/blevesearch/bleve/v2/analysis/lang/fi"
+	"github.com/blevesearch/bleve/v2/analysis/lang/fr"
+	"github.com/blevesearch/bleve/v2/analysis/lang/hu"
+	"github.com/blevesearch/bleve/v2/analysis/lang/it"
+	"github.com/blevesearch/bleve/v2/analysis/lang/nl"
+	"github.com/blevesearch/bleve/v2/analysis/lang/no"
+	"github.com/blevesearch/bleve/v2/analysis/lang/pt"
+	"github.com/blevesearch/bleve/v2/analysis/lang/ro"
+	"github.com/blevesearch/bleve/v2/analysis/lang/ru"
+	"github.com/blevesearch/bleve/v2/analysis/lang/sv"
+	"github.com/blevesearch/bleve/v2/analysis/lang/tr"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/mapping"
+	bolt "go.etcd.io/bbolt"
+)
+
+var ErrInvalidSetting = errors.New("invalid setting")
+
+// bleveTextAnalyzer is the name of the custom analyzer of title and
+// content when SearchAnalysis is not the default.
+const bleveTextAnalyzer = "glog_text"
+
+var (
+	settingsBucket    = []byte("settings")
+	searchAnalysisKey = []byte("search_analysis")
+	bleveAnalysisKey  = []byte("glog_analysis")
+	languageStemmers  = map[string]string{
+		"da": da.SnowballStemmerName,
+		"de": de.SnowballStemmerName,
+		"en": en.SnowballStemmerName,
+		"es": es.SnowballStemmerName,
+		"fi": fi.SnowballStemmerName,
+		"fr": fr.SnowballStemmerName,
+		"hu": hu.SnowballStemmerName,
+		"it": it.SnowballStemmerName,
+		"nl": nl.SnowballStemmerName,
+		"no": no.SnowballStemmerName,
+		"pt": pt.LightStemmerName,
+		"ro": ro.SnowballStemmerName,
+		"ru": ru.SnowballStemmerName,
+		"sv": sv.SnowballStemmerName,
+		"tr": tr.SnowballStemmerName,
+	}
+)
+
+// SearchAnalysis configures how the text of a database is analyzed for
+// search. The zero value is Bleve's standard analyzer: words are split on
+// Unicode boundaries, lowercased and English stop words are dropped.
+type SearchAnalysis struct {
+	// Languages are ISO 639-1 codes whose stemmers are applied in order,
+	// so "running" matches "run"
+	Languages []string `json:"languages,omitempty"`
+	// FoldAccents maps accented letters to ASCII, so "cancion" matches
+	// "canción"
+	FoldAccents bool `json:"foldAccents,omitempty"`
+	// CJK indexes Chinese, Japanese and Korean text as overlapping
+	// bigrams instead of whole runs of characters
+	CJK bool `json:"cjk,omitempty"`
+}
+
+// SearchLanguages returns the language codes SearchAnalysis accepts.
+func SearchLanguages() []string {
+	codes := make([]string, 0, len(languageStemmers))
+	for code := range languageStemmers {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// normalize returns a with lowercased, deduplicated languages, or an
+// ErrInvalidSetting error for an unsupported language.
+func (a SearchAnalysis) normalize() (SearchAnalysis, error) {
+	seen := make(map[string]bool)
+	var languages []string
+	for _, code := range a.Languages {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if code == "" || seen[code] {
+			continue
+		}
+		if _, ok := languageStemmers[code]; !ok {
+			return SearchAnalysis{}, fmt.Errorf("%w: unsupported search language %q (supported: %s)", ErrInvalidSetting, code, strings.Join(SearchLanguages(), ", "))
+		}
+		seen[code] = true
+		languages = append(languages, code)
+	}
+	a.Languages = languages
+	return a, nil
+}
+
+// IsDefault reports whether a is the standard analyzer.
+func (a SearchAnalysis) IsDefault() bool {
+	return len(a.Languages) == 0 && !a.FoldAccents && !a.CJK
+}
+
+// Equal reports whether a and b build the same analyzer.
+func (a SearchAnalysis) Equal(b SearchAnalysis) bool {
+	if len(a.Languages) != len(b.Languages) || a.FoldAccents != b.FoldAccents || a.CJK != b.CJK {
+		return false
+	}
+	for i := range a.Languages {
+		if a.Languages[i] != b.Languages[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// addTo registers the analyzer of a as the default analyzer of m.
+func (a SearchAnalysis) addTo(m *mapping.IndexMappingImpl) error {
+	if a.IsDefault() {
+		m.DefaultAnalyzer = standard.Name
+		return nil
+	}
+
+	// Bleve rejects null filter lists when it reopens the index
+	charFilters := []string{}
+	if a.FoldAccents {
+		charFilters = append(charFilters, asciifolding.Name)
+	}
+	var tokenFilters []string
+	if a.CJK {
+		tokenFilters = append(tokenFilters, cjk.WidthName)
+	}
+	tokenFilters = append(tokenFilters, lowercase.Name)
+	if a.CJK {
+		tokenFilters = append(tokenFilters, cjk.BigramName)
+	}
+	for _, code := range a.Languages {
+		if code == "en" {
+			tokenFilters = append(tokenFilters, en.PossessiveName)
+		}
+		tokenFilters = append(tokenFilters, languageStemmers[code])
+	}
+
+	err := m.AddCustomAnalyzer(bleveTextAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"char_filters":  charFilters,
+		"tokenizer":     unicode.Name,
+		"token_filters": tokenFilters,
+	})
+	if err != nil {
+		return err
+	}
+	m.DefaultAnalyzer = bleveTextAnalyzer
+	return nil
+}
+
+// GetSearchAnalysis returns the search analysis configured for the
+// database. The index may still be built with another one until the
+// reindex scheduled by SetSearchAnalysis completes, see GetIndexHealth.
+func (store *DocumentStore) GetSearchAnalysis() (SearchAnalysis, error) {
+	var analysis SearchAnalysis
+	err := store.bolt.View(func(tx *bolt.Tx) error {
+		var err error
+		analysis, err = searchAnalysisTx(tx)
+		return err
+	})
+	return analysis, err
+}
+
+// SetSearchAnalysis stores the search analysis of the database. When it
+// differs from the one the index was built with, a reindex is scheduled
+// in the background; searches and saves wait for it.
+func (store *DocumentStore) SetSearchAnalysis(analysis SearchAnalysis) error {
+	if store.readOnly {
+		return ErrReadOnly
+	}
+	analysis, err := analysis.normalize()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(analysis)
+	if err != nil {
+		return err
+	}
+	err = store.bolt.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(settingsBucket)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(searchAnalysisKey, data)
+	})
+	if err != nil {
+		return err
+	}
+
+	store.searchMu.Lock()
+	store.searchAnalysis = analysis
+	store.checkIndexHealth()
+	if !store.searchOutdated() {
+		store.searchMu.Unlock()
+		return nil
+	}
+	store.reindexInBackgroundLocked("Rebuilding the search index with the new analysis")
+	return nil
+}
+
+// searchAnalysisTx reads the configured search analysis; databases without
+// one use the default.
+func searchAnalysisTx(tx *bolt.Tx) (SearchAnalysis, error) {
+	var analysis SearchAnalysis
+	bucket := tx.Bucket(settingsBucket)
+	if bucket == nil {
+		return analysis, nil
+	}
+	data := bucket.Get(searchAnalysisKey)
+	if data == nil {
+		return analysis, nil
+	}
+	if err := json.Unmarshal(data, &analysis); err != nil {
+		return SearchAnalysis{}, fmt.Errorf("invalid search analysis setting: %w", err)
+	}
+	return analysis, nil
+}
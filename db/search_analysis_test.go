@@ -0,0 +1,96 @@
+package db
+
+import (
+	"errors"
+	"glog/domain"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSearchAnalysis(t *testing.T) {
+	store, err := NewDocumentStore("./testanalysis.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = os.Remove("./testanalysis.db")
+		_ = os.RemoveAll("./testanalysis.db.bleve")
+	}()
+
+	running := saveTestDoc(t, store, "Training", "Went running along the river").ID
+	song := saveTestDoc(t, store, "Lullabies", "Canción para dormir").ID
+	tokyo := saveTestDoc(t, store, "Travel", "東京都に住む友達").ID
+
+	if ids, _ := store.Search("runs"); len(ids) != 0 {
+		t.Errorf("Expected no stemming with the standard analyzer, got %v", ids)
+	}
+
+	analysis := SearchAnalysis{Languages: []string{"EN", "es", "en"}, FoldAccents: true, CJK: true}
+	if err := store.SetSearchAnalysis(analysis); err != nil {
+		t.Fatalf("SetSearchAnalysis failed: %v", err)
+	}
+	if got, _ := store.GetSearchAnalysis(); len(got.Languages) != 2 || got.Languages[0] != "en" || !got.FoldAccents || !got.CJK {
+		t.Errorf("Expected the normalized analysis to be stored, got %+v", got)
+	}
+
+	// Searches wait for the scheduled reindex
+	tests := []struct {
+		query string
+		want  domain.DocumentID
+	}{
+		{"runs", running},
+		{`"ran along"`, domain.DocumentID(uuid.Nil)},
+		{`"running along"`, running},
+		{`exact:"running along"`, running},
+		{`exact:"ran along"`, domain.DocumentID(uuid.Nil)},
+		{"cancion", song},
+		{"canciones", song},
+		{"東京", tokyo},
+	}
+	for _, tt := range tests {
+		ids, err := store.Search(tt.query)
+		if err != nil {
+			t.Errorf("Search(%q) failed: %v", tt.query, err)
+			continue
+		}
+		if tt.want == domain.DocumentID(uuid.Nil) && len(ids) != 0 || tt.want != domain.DocumentID(uuid.Nil) && (len(ids) != 1 || ids[0] != tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+	if health := store.GetIndexHealth(); health.RequiresReindex {
+		t.Errorf("Expected no reindex required after the rebuild, got %+v", health)
+	}
+
+	if err := store.SetSearchAnalysis(SearchAnalysis{Languages: []string{"xx"}}); !errors.Is(err, ErrInvalidSetting) {
+		t.Errorf("Expected ErrInvalidSetting for an unknown language, got %v", err)
+	}
+
+	// An index built with another analysis is rebuilt when the store opens
+	if err := store.search.index.SetInternal(bleveAnalysisKey, []byte("{}")); err != nil {
+		t.Fatalf("SetInternal failed: %v", err)
+	}
+	_ = store.Close()
+
+	store, err = NewDocumentStoreWithOptions("./testanalysis.db", Options{ReadOnly: true, Timeout: DefaultLockTimeout})
+	if err != nil {
+		t.Fatalf("Failed to reopen DocumentStore: %v", err)
+	}
+	if health := store.GetIndexHealth(); !health.RequiresReindex {
+		t.Errorf("Expected the analysis mismatch to require a reindex, got %+v", health)
+	}
+	_ = store.Close()
+
+	store, err = NewDocumentStore("./testanalysis.db")
+	if err != nil {
+		t.Fatalf("Failed to reopen DocumentStore: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	if ids, _ := store.Search("runs"); len(ids) != 1 || ids[0] != running {
+		t.Errorf("Expected the rebuilt index to stem, got %v", ids)
+	}
+	if health := store.GetIndexHealth(); health.RequiresReindex {
+		t.Errorf("Expected no reindex required after the rebuild, got %+v", health)
+	}
+}
@@ -1,63 +1,264 @@
 package db
 
 import (
+	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/blevesearch/bleve/v2"
-	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/analysis"
 	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/highlight/format/html"
 	"github.com/google/uuid"
 )
 
 const (
+	bleveFieldKind    = "kind"
 	bleveFieldTitle   = "title"
 	bleveFieldContent = "content"
+	bleveFieldDate    = "date"
+	bleveFieldJournal = "journal"
+	bleveFieldTask    = "task"
+	bleveFieldLinks   = "links"
+	bleveFieldTags    = "tags"
+	bleveFieldDoc     = "doc"
+	bleveFieldSort    = "sort_title" // lowercased title, for sorting
+	bleveFieldHash    = "hash"       // searchHash of the document
+
+	bleveKindDoc   = "doc"
+	bleveKindBlock = "block"
+
+	// bleveMappingVersion is stored in the index and changes with the
+	// mapping; indexes with another version need a reindex.
+	bleveMappingVersion = "5"
 
 	titleBoost = 2.0
+
+	// maxBlockFragments is the number of highlighted fragments per block hit
+	maxBlockFragments = 3
+
+	// maxSimilarTerms is the number of the most frequent terms of a
+	// document SimilarDocs searches for, ignoring terms shorter than
+	// minSimilarTermLength
+	maxSimilarTerms      = 25
+	minSimilarTermLength = 3
 )
 
+var bleveMappingVersionKey = []byte("glog_mapping_version")
+
+// bleveDoc is the search entry of a document. The title and date are
+// stored, so search results need not load the documents, and the hash so
+// VerifySearchIndex can find stale entries.
 type bleveDoc struct {
-	Title   string `json:"title"`
-	Content string `json:"content"`
-	Date    string `json:"date,omitempty"`
+	Kind    string   `json:"kind"`
+	Title   string   `json:"title"`
+	Sort    string   `json:"sort_title"`
+	Content string   `json:"content"`
+	Date    string   `json:"date,omitempty"`
+	Journal bool     `json:"journal"`
+	Task    bool     `json:"task"`  // a block has a /scheduled task
+	Links   []string `json:"links"` // lowercased titles of the [[links]]
+	Tags    []string `json:"tags"`  // lowercased, see DocumentTags
+	Hash    string   `json:"hash"`
+}
+
+func (bleveDoc) BleveType() string { return bleveKindDoc }
+
+// bleveBlockDoc is the search entry of a single block, indexed next to
+// the bleveDoc of its document with the ID docID/blockID. It carries the
+// title, date and kind of its document so the same filters apply.
+type bleveBlockDoc struct {
+	Kind    string   `json:"kind"`
+	Doc     string   `json:"doc"`
+	Title   string   `json:"title"`
+	Content string   `json:"content"`
+	Date    string   `json:"date,omitempty"`
+	Journal bool     `json:"journal"`
+	Task    bool     `json:"task"`
+	Links   []string `json:"links"`
 }
 
+func (bleveBlockDoc) BleveType() string { return bleveKindBlock }
+
 type bleveSearch struct {
 	path  string
 	index bleve.Index
+	// current is false for indexes created with an older mapping, which
+	// need a reindex before block search and query filters work
+	current bool
+	// analysis is the one the index was built with, and analyzer its
+	// analyzer of title and content
+	analysis SearchAnalysis
+	analyzer analysis.Analyzer
+}
+
+// bleveBlockHit is a block matching a SearchBlocks query.
+type bleveBlockHit struct {
+	DocID     uuid.UUID
+	BlockID   uuid.UUID
+	Score     float64
+	Fragments []string // HTML-escaped, with matches in <mark> tags
 }
 
-func openBleveSearch(path string) (*bleveSearch, error) {
-	idx, err := bleve.Open(path)
+// bleveDocHit is a document matching a SearchPage query. Title and Date
+// are empty for indexes with an older mapping.
+type bleveDocHit struct {
+	ID    uuid.UUID
+	Title string
+	Date  time.Time
+	Score float64
+}
+
+// openBleveSearch opens the index at path, or creates it with the given
+// analysis. An existing index keeps the analysis it was built with.
+func openBleveSearch(path string, opts Options, textAnalysis SearchAnalysis) (*bleveSearch, error) {
+	// The index has its own Bolt file, locked like the main database
+	config := map[string]interface{}{"read_only": opts.ReadOnly}
+	if opts.Timeout > 0 {
+		config["bolt_timeout"] = opts.Timeout.String()
+	}
+
+	idx, err := bleve.OpenUsing(path, config)
 	if err == nil {
-		return &bleveSearch{path: path, index: idx}, nil
+		version, err := idx.GetInternal(bleveMappingVersionKey)
+		if err != nil {
+			_ = idx.Close()
+			return nil, err
+		}
+		// Indexes without a stored analysis use the standard analyzer
+		var indexAnalysis SearchAnalysis
+		data, err := idx.GetInternal(bleveAnalysisKey)
+		if err == nil && data != nil {
+			err = json.Unmarshal(data, &indexAnalysis)
+		}
+		if err != nil {
+			_ = idx.Close()
+			return nil, err
+		}
+		return newBleveSearch(path, idx, string(version) == bleveMappingVersion, indexAnalysis), nil
 	}
-	if !errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+	if !errors.Is(err, bleve.ErrorIndexPathDoesNotExist) || opts.ReadOnly {
 		return nil, err
 	}
 
-	indexMapping := mapping.NewIndexMapping()
-	indexMapping.DefaultAnalyzer = standard.Name
+	indexMapping, err := newBleveMapping(textAnalysis)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(textAnalysis)
+	if err != nil {
+		return nil, err
+	}
+	idx, err = bleve.New(path, indexMapping)
+	if err != nil {
+		return nil, err
+	}
+	if err := idx.SetInternal(bleveMappingVersionKey, []byte(bleveMappingVersion)); err != nil {
+		_ = idx.Close()
+		return nil, err
+	}
+	if err := idx.SetInternal(bleveAnalysisKey, data); err != nil {
+		_ = idx.Close()
+		return nil, err
+	}
+	return newBleveSearch(path, idx, true, textAnalysis), nil
+}
+
+func newBleveSearch(path string, idx bleve.Index, current bool, textAnalysis SearchAnalysis) *bleveSearch {
+	m := idx.Mapping()
+	return &bleveSearch{
+		path:     path,
+		index:    idx,
+		current:  current,
+		analysis: textAnalysis,
+		analyzer: m.AnalyzerNamed(m.AnalyzerNameForPath(bleveFieldContent)),
+	}
+}
 
-	// Ensure fields exist and are indexed as text.
-	docMapping := mapping.NewDocumentMapping()
+func newBleveMapping(textAnalysis SearchAnalysis) (mapping.IndexMapping, error) {
 	textMapping := mapping.NewTextFieldMapping()
 	textMapping.Store = false
-	textMapping.Index = true
-	docMapping.AddFieldMappingsAt(bleveFieldTitle, textMapping)
-	docMapping.AddFieldMappingsAt(bleveFieldContent, textMapping)
 
+	// Document titles are stored for search results, block content for
+	// the highlighter
+	storedTextMapping := mapping.NewTextFieldMapping()
+	storedTextMapping.Store = true
+
+	keywordMapping := mapping.NewKeywordFieldMapping()
+	keywordMapping.Store = false
+
+	dateMapping := mapping.NewDateTimeFieldMapping()
+	dateMapping.Store = false
+
+	boolMapping := mapping.NewBooleanFieldMapping()
+	boolMapping.Store = false
+
+	storedDateMapping := mapping.NewDateTimeFieldMapping()
+	storedDateMapping.Store = true
+
+	hashMapping := mapping.NewKeywordFieldMapping()
+	hashMapping.Index = false
+	hashMapping.IncludeInAll = false
+	hashMapping.DocValues = false
+
+	newTypeMapping := func(title, content, date *mapping.FieldMapping) *mapping.DocumentMapping {
+		m := mapping.NewDocumentStaticMapping()
+		m.AddFieldMappingsAt(bleveFieldKind, keywordMapping)
+		m.AddFieldMappingsAt(bleveFieldTitle, title)
+		m.AddFieldMappingsAt(bleveFieldContent, content)
+		m.AddFieldMappingsAt(bleveFieldDate, date)
+		m.AddFieldMappingsAt(bleveFieldJournal, boolMapping)
+		m.AddFieldMappingsAt(bleveFieldTask, boolMapping)
+		m.AddFieldMappingsAt(bleveFieldLinks, keywordMapping)
+		return m
+	}
+
+	docMapping := newTypeMapping(storedTextMapping, textMapping, storedDateMapping)
+	docMapping.AddFieldMappingsAt(bleveFieldSort, keywordMapping)
+	docMapping.AddFieldMappingsAt(bleveFieldTags, keywordMapping)
+	docMapping.AddFieldMappingsAt(bleveFieldHash, hashMapping)
+	blockMapping := newTypeMapping(textMapping, storedTextMapping, dateMapping)
+	blockMapping.AddFieldMappingsAt(bleveFieldDoc, keywordMapping)
+
+	indexMapping := mapping.NewIndexMapping()
+	if err := textAnalysis.addTo(indexMapping); err != nil {
+		return nil, err
+	}
 	indexMapping.DefaultMapping = docMapping
+	indexMapping.AddDocumentMapping(bleveKindDoc, docMapping)
+	indexMapping.AddDocumentMapping(bleveKindBlock, blockMapping)
+	return indexMapping, nil
+}
 
-	idx, err = bleve.New(path, indexMapping)
+// terms returns the terms the index makes of text, so term-level queries
+// match stemmed and folded words. Text the analyzer drops entirely, like
+// a stop word, is returned as its words.
+func (s *bleveSearch) terms(text string) []string {
+	if s.analyzer == nil {
+		return strings.Fields(text)
+	}
+	var terms []string
+	for _, token := range s.analyzer.Analyze([]byte(text)) {
+		terms = append(terms, string(token.Term))
+	}
+	if len(terms) == 0 {
+		return strings.Fields(text)
+	}
+	return terms
+}
+
+// parseQuery parses query and analyzes its text like the index does.
+func (s *bleveSearch) parseQuery(query string) (searchQuery, error) {
+	q, err := parseSearchQuery(query)
 	if err != nil {
-		return nil, err
+		return q, err
 	}
-	return &bleveSearch{path: path, index: idx}, nil
+	return q.analyze(s.terms), nil
 }
 
 func (s *bleveSearch) Close() error {
@@ -78,7 +279,51 @@ func (s *bleveSearch) DeleteDoc(docID string) error {
 	if s == nil || s.index == nil {
 		return nil
 	}
-	return s.index.Delete(docID)
+
+	batch := s.index.NewBatch()
+	if err := s.batchDelete(batch, docID); err != nil {
+		return err
+	}
+	return s.index.Batch(batch)
+}
+
+// batchDelete adds the removal of a document and its block entries to
+// batch.
+func (s *bleveSearch) batchDelete(batch *bleve.Batch, docID string) error {
+	batch.Delete(docID)
+	if !s.current {
+		return nil
+	}
+	indexed, err := s.blockEntries(docID)
+	if err != nil {
+		return err
+	}
+	for _, id := range indexed {
+		batch.Delete(id)
+	}
+	return nil
+}
+
+// blockEntries returns the IDs of the block entries indexed for a document.
+func (s *bleveSearch) blockEntries(docID string) ([]string, error) {
+	q := bleve.NewTermQuery(docID)
+	q.SetField(bleveFieldDoc)
+	req := bleve.NewSearchRequestOptions(q, 100000, 0, false)
+	req.Fields = []string{}
+	result, err := s.index.Search(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(result.Hits))
+	for i, hit := range result.Hits {
+		ids[i] = hit.ID
+	}
+	return ids, nil
+}
+
+func blockEntryID(docID uuid.UUID, blockID uuid.UUID) string {
+	return docID.String() + "/" + blockID.String()
 }
 
 func (s *bleveSearch) IndexDoc(doc *DocDb) error {
@@ -86,120 +331,485 @@ func (s *bleveSearch) IndexDoc(doc *DocDb) error {
 		return nil
 	}
 
+	batch := s.index.NewBatch()
+	if err := s.batchIndex(batch, doc); err != nil {
+		return err
+	}
+	return s.index.Batch(batch)
+}
+
+// batchIndex adds the entries of a document and its blocks to batch, and
+// the removal of block entries no longer in the document.
+func (s *bleveSearch) batchIndex(batch *bleve.Batch, doc *DocDb) error {
+	if err := batch.Index(doc.ID.String(), newBleveDoc(doc)); err != nil {
+		return err
+	}
+	if !s.current {
+		return nil
+	}
+
+	// Blocks deleted or emptied since the last save leave stale entries
+	indexed, err := s.blockEntries(doc.ID.String())
+	if err != nil {
+		return err
+	}
+	stale := make(map[string]bool, len(indexed))
+	for _, id := range indexed {
+		stale[id] = true
+	}
+
+	for _, block := range doc.Blocks {
+		if block == nil || strings.TrimSpace(block.Content) == "" {
+			continue
+		}
+		id := blockEntryID(doc.ID, block.ID)
+		delete(stale, id)
+		if err := batch.Index(id, newBleveBlockDoc(doc, block)); err != nil {
+			return err
+		}
+	}
+	for id := range stale {
+		batch.Delete(id)
+	}
+	return nil
+}
+
+// batchIndexBlocks is batchIndex for a document of which only the given
+// blocks changed: the document entry and the entries of those blocks are
+// updated, and the other block entries are left alone.
+func (s *bleveSearch) batchIndexBlocks(batch *bleve.Batch, doc *DocDb, blocks []uuid.UUID) error {
+	if err := batch.Index(doc.ID.String(), newBleveDoc(doc)); err != nil {
+		return err
+	}
+	if !s.current {
+		return nil
+	}
+
+	changed := make(map[uuid.UUID]bool, len(blocks))
+	for _, id := range blocks {
+		changed[id] = true
+	}
+	for _, block := range doc.Blocks {
+		if block == nil || !changed[block.ID] || strings.TrimSpace(block.Content) == "" {
+			continue
+		}
+		delete(changed, block.ID)
+		if err := batch.Index(blockEntryID(doc.ID, block.ID), newBleveBlockDoc(doc, block)); err != nil {
+			return err
+		}
+	}
+	// Deleted or emptied blocks
+	for id := range changed {
+		batch.Delete(blockEntryID(doc.ID, id))
+	}
+	return nil
+}
+
+// newBleveDoc returns the document entry of doc.
+func newBleveDoc(doc *DocDb) bleveDoc {
+	bdoc := bleveDoc{
+		Kind:    bleveKindDoc,
+		Title:   doc.Title,
+		Sort:    strings.ToLower(doc.Title),
+		Date:    doc.Date,
+		Journal: doc.IsJournal,
+		Links:   []string{},
+		Hash:    searchHash(doc),
+	}
 	blocks := make([]string, 0, len(doc.Blocks))
 	for _, block := range doc.Blocks {
 		if block == nil {
 			continue
 		}
 		blocks = append(blocks, block.Content)
+		bdoc.Task = bdoc.Task || scheduledRegex.MatchString(block.Content)
+	}
+	bdoc.Content = strings.Join(blocks, "\n")
+	for _, title := range getReferencedTitles(doc) {
+		bdoc.Links = append(bdoc.Links, strings.ToLower(title))
+	}
+	bdoc.Tags = []string{}
+	for _, tag := range DocumentTags(docDbToDomain(doc)) {
+		bdoc.Tags = append(bdoc.Tags, strings.ToLower(tag))
 	}
+	return bdoc
+}
 
-	bdoc := bleveDoc{
+// newBleveBlockDoc returns the entry of a block of doc.
+func newBleveBlockDoc(doc *DocDb, block *BlockDb) bleveBlockDoc {
+	return bleveBlockDoc{
+		Kind:    bleveKindBlock,
+		Doc:     doc.ID.String(),
 		Title:   doc.Title,
-		Content: strings.Join(blocks, "\n"),
+		Content: block.Content,
 		Date:    doc.Date,
+		Journal: doc.IsJournal,
+		Task:    scheduledRegex.MatchString(block.Content),
+		Links:   blockLinks(block.Content),
 	}
+}
 
-	return s.index.Index(doc.ID.String(), bdoc)
+// blockLinks returns the lowercased titles of the [[links]] in content.
+func blockLinks(content string) []string {
+	links := []string{}
+	for _, match := range referenceRegex.FindAllStringSubmatch(content, -1) {
+		if title := strings.TrimSpace(match[1]); title != "" {
+			links = append(links, strings.ToLower(title))
+		}
+	}
+	return links
 }
 
+// Search returns the IDs of the documents matching query, best first. See
+// parseSearchQuery for the query language.
 func (s *bleveSearch) Search(query string) ([]uuid.UUID, error) {
 	if s == nil || s.index == nil {
 		return nil, nil
 	}
 
-	phrases, tokens := parseSearchQuery(query)
+	q, err := s.parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
 
-	// Return empty result set if query contains no valid phrases or tokens
-	if len(phrases) == 0 && len(tokens) == 0 {
+	// Return empty result set if query contains no valid clauses
+	if q.empty() {
 		return []uuid.UUID{}, nil
 	}
 
-	conj := bleve.NewConjunctionQuery()
+	// Outdated indexes opened read-only have no kinds, see openBleveSearch
+	kind := bleveKindDoc
+	if !s.current {
+		kind = ""
+	}
 
-	for _, phrase := range phrases {
-		phrase = strings.TrimSpace(phrase)
-		if phrase == "" {
+	searchRequest := bleve.NewSearchRequest(q.bleveQuery(kind))
+	searchRequest.Fields = []string{}
+	searchRequest.Size = 10000 // Set a high limit for search results
+	searchResult, err := s.index.Search(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(searchResult.Hits))
+	for _, hit := range searchResult.Hits {
+		id, err := uuid.Parse(hit.ID)
+		if err != nil {
 			continue
 		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// MatchingDocs returns which of the documents ids match query.
+func (s *bleveSearch) MatchingDocs(query string, ids []string) (map[uuid.UUID]bool, error) {
+	matching := make(map[uuid.UUID]bool)
+	if s == nil || s.index == nil || len(ids) == 0 {
+		return matching, nil
+	}
+
+	q, err := s.parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if q.empty() {
+		return matching, nil
+	}
 
-		contentQ := bleve.NewPhraseQuery(strings.Fields(phrase), bleveFieldContent)
-		titleQ := bleve.NewPhraseQuery(strings.Fields(phrase), bleveFieldTitle)
-		titleQ.SetBoost(titleBoost)
-		conj.AddQuery(bleve.NewDisjunctionQuery(titleQ, contentQ))
+	kind := bleveKindDoc
+	if !s.current {
+		kind = ""
 	}
 
-	for _, token := range tokens {
-		fuzziness := 1
-		if len(token) >= 5 {
-			fuzziness = 2
+	conj := bleve.NewConjunctionQuery(q.bleveQuery(kind), bleve.NewDocIDQuery(ids))
+	searchRequest := bleve.NewSearchRequestOptions(conj, len(ids), 0, false)
+	searchRequest.Fields = []string{}
+	searchResult, err := s.index.Search(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+	for _, hit := range searchResult.Hits {
+		if id, err := uuid.Parse(hit.ID); err == nil {
+			matching[id] = true
 		}
+	}
+	return matching, nil
+}
 
-		contentQ := bleve.NewFuzzyQuery(token)
-		contentQ.SetField(bleveFieldContent)
-		contentQ.SetFuzziness(fuzziness)
+// SearchPage returns limit documents matching query from offset, in the
+// given order, with the total number of matches.
+func (s *bleveSearch) SearchPage(query string, offset int, limit int, order SearchSort) ([]bleveDocHit, uint64, error) {
+	if s == nil || s.index == nil {
+		return nil, 0, nil
+	}
 
-		titleQ := bleve.NewFuzzyQuery(token)
-		titleQ.SetField(bleveFieldTitle)
-		titleQ.SetFuzziness(fuzziness)
-		titleQ.SetBoost(titleBoost)
+	q, err := s.parseQuery(query)
+	if err != nil {
+		return nil, 0, err
+	}
+	if q.empty() {
+		return []bleveDocHit{}, 0, nil
+	}
 
-		conj.AddQuery(bleve.NewDisjunctionQuery(titleQ, contentQ))
+	kind := bleveKindDoc
+	if !s.current {
+		kind = ""
 	}
 
-	searchRequest := bleve.NewSearchRequest(conj)
-	searchRequest.Fields = []string{}
-	searchRequest.Size = 10000 // Set a high limit for search results
+	searchRequest := bleve.NewSearchRequestOptions(q.bleveQuery(kind), limit, offset, false)
+	searchRequest.Fields = []string{bleveFieldTitle, bleveFieldDate}
+	switch order {
+	case SortDate:
+		searchRequest.SortBy([]string{"-" + bleveFieldDate, "-_score"})
+	case SortTitle:
+		searchRequest.SortBy([]string{bleveFieldSort, "-_score"})
+	}
+	searchResult, err := s.index.Search(searchRequest)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]bleveDocHit, 0, len(searchResult.Hits))
+	for _, hit := range searchResult.Hits {
+		id, err := uuid.Parse(hit.ID)
+		if err != nil {
+			continue
+		}
+		title, _ := hit.Fields[bleveFieldTitle].(string)
+		stored, _ := hit.Fields[bleveFieldDate].(string)
+		date, _ := time.Parse(time.RFC3339, stored)
+		hits = append(hits, bleveDocHit{ID: id, Title: title, Date: date, Score: hit.Score})
+	}
+	return hits, searchResult.Total, nil
+}
+
+// SuggestTitles returns up to limit documents whose title has words
+// starting with, or close to, the words of text.
+func (s *bleveSearch) SuggestTitles(text string, limit int) ([]bleveDocHit, error) {
+	if s == nil || s.index == nil {
+		return nil, nil
+	}
+
+	words := s.terms(strings.ToLower(text))
+	if len(words) == 0 {
+		return []bleveDocHit{}, nil
+	}
+
+	conj := bleve.NewConjunctionQuery()
+	if s.current {
+		kindQ := bleve.NewTermQuery(bleveKindDoc)
+		kindQ.SetField(bleveFieldKind)
+		conj.AddQuery(kindQ)
+	}
+	for _, word := range words {
+		prefixQ := bleve.NewPrefixQuery(word)
+		prefixQ.SetField(bleveFieldTitle)
+		prefixQ.SetBoost(titleBoost)
+		conj.AddQuery(bleve.NewDisjunctionQuery(prefixQ, fuzzyQuery(word, bleveFieldTitle)))
+	}
+
+	searchRequest := bleve.NewSearchRequestOptions(conj, limit, 0, false)
+	searchRequest.Fields = []string{bleveFieldTitle}
 	searchResult, err := s.index.Search(searchRequest)
 	if err != nil {
 		return nil, err
 	}
 
-	ids := make([]uuid.UUID, 0, len(searchResult.Hits))
+	hits := make([]bleveDocHit, 0, len(searchResult.Hits))
 	for _, hit := range searchResult.Hits {
 		id, err := uuid.Parse(hit.ID)
 		if err != nil {
 			continue
 		}
-		ids = append(ids, id)
+		title, _ := hit.Fields[bleveFieldTitle].(string)
+		hits = append(hits, bleveDocHit{ID: id, Title: title, Score: hit.Score})
 	}
+	return hits, nil
+}
 
-	return ids, nil
+// SearchBlocks returns the blocks matching query, best first, with up to
+// maxBlockFragments highlighted fragments each. Unlike Search, text must
+// match within the same block; title: and the filters apply to the
+// block's document, except has:task and links: which apply to the block.
+func (s *bleveSearch) SearchBlocks(query string, limit int) ([]bleveBlockHit, error) {
+	if s == nil || s.index == nil || !s.current {
+		return nil, nil
+	}
+
+	q, err := s.parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if q.empty() {
+		return []bleveBlockHit{}, nil
+	}
+
+	searchRequest := bleve.NewSearchRequestOptions(q.bleveQuery(bleveKindBlock), limit, 0, false)
+	searchRequest.Fields = []string{}
+	searchRequest.Highlight = bleve.NewHighlightWithStyle(html.Name)
+	searchRequest.Highlight.AddField(bleveFieldContent)
+	searchResult, err := s.index.Search(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return blockHits(searchResult), nil
 }
 
-var quoteRe = regexp.MustCompile(`"([^"]+)"`)
-
-// parseSearchQuery extracts phrases and tokens from a search query string.
-//
-// Phrases are text enclosed in matching double quotes (e.g., "exact phrase").
-// Only properly matched quote pairs are recognized as phrases; unmatched quotes
-// are treated as regular characters and will be included in the token search.
-//
-// For example:
-//   - Query: 'search "exact match"' -> phrases: ["exact match"], tokens: ["search"]
-//   - Query: 'search "unclosed' -> phrases: [], tokens: ["search", "unclosed"]
-//   - Query: 'one "two" three "four' -> phrases: ["two"], tokens: ["one", "three", "four"]
-//
-// Tokens are individual words (whitespace-separated) extracted from the query
-// after removing matched quoted phrases. Single-character tokens are ignored.
-// All text is converted to lowercase for case-insensitive matching.
-func parseSearchQuery(query string) (phrases []string, tokens []string) {
-	matches := quoteRe.FindAllStringSubmatch(query, -1)
-	for _, match := range matches {
-		if len(match) > 1 {
-			phrases = append(phrases, strings.ToLower(match[1]))
-		}
-	}
-
-	stripped := quoteRe.ReplaceAllString(query, " ")
-	for _, token := range strings.Fields(strings.ToLower(stripped)) {
-		if len(token) < 2 {
+// MentionBlocks returns up to limit blocks containing one of names as a
+// phrase, with highlighted fragments. Links in the content are indexed as
+// text, so hits still need to be checked for plain-text mentions.
+func (s *bleveSearch) MentionBlocks(names []string, limit int) ([]bleveBlockHit, error) {
+	if s == nil || s.index == nil || !s.current {
+		return nil, nil
+	}
+
+	disj := bleve.NewDisjunctionQuery()
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		phraseQ := bleve.NewMatchPhraseQuery(name)
+		phraseQ.SetField(bleveFieldContent)
+		disj.AddQuery(phraseQ)
+	}
+	if len(disj.Disjuncts) == 0 {
+		return []bleveBlockHit{}, nil
+	}
+	kindQ := bleve.NewTermQuery(bleveKindBlock)
+	kindQ.SetField(bleveFieldKind)
+
+	searchRequest := bleve.NewSearchRequestOptions(bleve.NewConjunctionQuery(kindQ, disj), limit, 0, false)
+	searchRequest.Fields = []string{}
+	searchRequest.Highlight = bleve.NewHighlightWithStyle(html.Name)
+	searchRequest.Highlight.AddField(bleveFieldContent)
+	searchResult, err := s.index.Search(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+	return blockHits(searchResult), nil
+}
+
+// SimilarDocs returns up to limit documents sharing the most frequent
+// terms of text, best first, with the score of the document docID itself.
+// A document scores highest against its own terms, so scores divided by
+// it compare across documents. Outdated indexes return no documents.
+func (s *bleveSearch) SimilarDocs(docID string, text string, limit int) ([]bleveDocHit, float64, error) {
+	if s == nil || s.index == nil || !s.current {
+		return nil, 0, nil
+	}
+
+	counts := make(map[string]int)
+	for _, term := range s.terms(strings.ToLower(text)) {
+		if utf8.RuneCountInString(term) >= minSimilarTermLength {
+			counts[term]++
+		}
+	}
+	terms := make([]string, 0, len(counts))
+	for term := range counts {
+		terms = append(terms, term)
+	}
+	if len(terms) == 0 {
+		return []bleveDocHit{}, 0, nil
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if counts[terms[i]] != counts[terms[j]] {
+			return counts[terms[i]] > counts[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+	terms = terms[:min(len(terms), maxSimilarTerms)]
+
+	disj := bleve.NewDisjunctionQuery()
+	for _, term := range terms {
+		termQ := bleve.NewTermQuery(term)
+		termQ.SetField(bleveFieldContent)
+		disj.AddQuery(termQ)
+	}
+	kindQ := bleve.NewTermQuery(bleveKindDoc)
+	kindQ.SetField(bleveFieldKind)
+
+	searchRequest := bleve.NewSearchRequestOptions(bleve.NewConjunctionQuery(kindQ, disj), limit+1, 0, false)
+	searchRequest.Fields = []string{bleveFieldTitle, bleveFieldDate}
+	searchResult, err := s.index.Search(searchRequest)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	selfScore := searchResult.MaxScore
+	hits := make([]bleveDocHit, 0, len(searchResult.Hits))
+	for _, hit := range searchResult.Hits {
+		if hit.ID == docID {
+			selfScore = hit.Score
+			continue
+		}
+		id, err := uuid.Parse(hit.ID)
+		if err != nil {
 			continue
 		}
-		tokens = append(tokens, token)
+		title, _ := hit.Fields[bleveFieldTitle].(string)
+		stored, _ := hit.Fields[bleveFieldDate].(string)
+		date, _ := time.Parse(time.RFC3339, stored)
+		hits = append(hits, bleveDocHit{ID: id, Title: title, Date: date, Score: hit.Score})
+	}
+	return hits[:min(len(hits), limit)], selfScore, nil
+}
+
+// TaggedDocs returns up to limit documents per tag having any of tags,
+// with the tags each has.
+func (s *bleveSearch) TaggedDocs(tags []string, limit int) (map[uuid.UUID][]string, error) {
+	docs := make(map[uuid.UUID][]string)
+	if s == nil || s.index == nil || !s.current {
+		return docs, nil
 	}
 
-	return phrases, tokens
+	for _, tag := range tags {
+		tagQ := bleve.NewTermQuery(strings.ToLower(tag))
+		tagQ.SetField(bleveFieldTags)
+		kindQ := bleve.NewTermQuery(bleveKindDoc)
+		kindQ.SetField(bleveFieldKind)
+
+		searchRequest := bleve.NewSearchRequestOptions(bleve.NewConjunctionQuery(kindQ, tagQ), limit, 0, false)
+		searchRequest.Fields = []string{}
+		searchResult, err := s.index.Search(searchRequest)
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range searchResult.Hits {
+			if id, err := uuid.Parse(hit.ID); err == nil {
+				docs[id] = append(docs[id], tag)
+			}
+		}
+	}
+	return docs, nil
+}
+
+// blockHits converts the hits on block entries of a search result.
+func blockHits(searchResult *bleve.SearchResult) []bleveBlockHit {
+	hits := make([]bleveBlockHit, 0, len(searchResult.Hits))
+	for _, hit := range searchResult.Hits {
+		docPart, blockPart, ok := strings.Cut(hit.ID, "/")
+		if !ok {
+			continue
+		}
+		docID, err := uuid.Parse(docPart)
+		if err != nil {
+			continue
+		}
+		blockID, err := uuid.Parse(blockPart)
+		if err != nil {
+			continue
+		}
+
+		fragments := hit.Fragments[bleveFieldContent]
+		if len(fragments) > maxBlockFragments {
+			fragments = fragments[:maxBlockFragments]
+		}
+		hits = append(hits, bleveBlockHit{DocID: docID, BlockID: blockID, Score: hit.Score, Fragments: fragments})
+	}
+	return hits
 }
 
 func bleveIndexPath(boltPath string) string {
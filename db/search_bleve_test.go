@@ -0,0 +1,263 @@
+package db
+
+import (
+	"errors"
+	"glog/domain"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestSearchBlocks(t *testing.T) {
+	store, err := NewDocumentStore("./testsearchblocks.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove("./testsearchblocks.db")
+		_ = os.RemoveAll("./testsearchblocks.db.bleve")
+	}()
+
+	match := domain.BlockID(uuid.New())
+	other := domain.BlockID(uuid.New())
+	doc := &domain.Document{
+		ID:    domain.DocumentID(uuid.New()),
+		Title: "Garden",
+		Date:  time.Now(),
+		Blocks: []*domain.Block{
+			{ID: domain.BlockID(uuid.New()), Content: "Spring\nplanting plan", Indent: 0},
+			{ID: domain.BlockID(uuid.New()), Content: "Vegetables", Indent: 1},
+			{ID: match, Content: "Tomatoes need <full> sun", Indent: 2},
+			{ID: other, Content: "Tomatoes in the shade", Indent: 0},
+		},
+	}
+	if err := store.Save(doc); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	hits, err := store.SearchBlocks("tomatoes sun", 10)
+	if err != nil {
+		t.Fatalf("SearchBlocks failed: %v", err)
+	}
+	if len(hits) != 1 || hits[0].BlockID != match || hits[0].DocID != doc.ID || hits[0].Title != "Garden" {
+		t.Fatalf("Expected only the block with both terms, got %+v", hits)
+	}
+	if got := strings.Join(hits[0].Breadcrumb, " > "); got != "Spring > Vegetables" {
+		t.Errorf("Expected the breadcrumb of the ancestors, got %q", got)
+	}
+	if len(hits[0].Fragments) == 0 || !strings.Contains(hits[0].Fragments[0], "<mark>sun</mark>") || !strings.Contains(hits[0].Fragments[0], "&lt;full&gt;") {
+		t.Errorf("Expected an escaped, highlighted fragment, got %v", hits[0].Fragments)
+	}
+
+	// Deleted blocks are removed from the index on save
+	doc.Blocks = doc.Blocks[:3]
+	if err := store.Save(doc); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if hits, _ := store.SearchBlocks("shade", 10); len(hits) != 0 {
+		t.Errorf("Expected no hit for a deleted block, got %+v", hits)
+	}
+
+	if err := store.Delete(uuid.UUID(doc.ID)); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if hits, _ := store.SearchBlocks("tomatoes", 10); len(hits) != 0 {
+		t.Errorf("Expected no hit for a deleted document, got %+v", hits)
+	}
+	if ids, _ := store.search.blockEntries(doc.ID.String()); len(ids) != 0 {
+		t.Errorf("Expected the block entries to be deleted, got %v", ids)
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	// Journals are stored at UTC midnight; west of UTC that is the
+	// previous local day
+	local := time.Local
+	time.Local = time.FixedZone("PDT", -7*60*60)
+	defer func() { time.Local = local }()
+
+	store, err := NewDocumentStore("./testsearchfilters.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove("./testsearchfilters.db")
+		_ = os.RemoveAll("./testsearchfilters.db.bleve")
+	}()
+
+	newDoc := func(title string, date time.Time, journal bool, contents ...string) domain.DocumentID {
+		doc := testDoc(title, contents...)
+		doc.Date, doc.IsJournal = date, journal
+		if err := store.Save(doc); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		return doc.ID
+	}
+
+	september := newDoc("Sep 30th, 2026", JournalDay(time.Date(2026, 9, 30, 12, 0, 0, 0, time.UTC)), true, "Garden work on [[Roses]]")
+	first := newDoc("Oct 1st, 2026", JournalDay(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)), true, "Water the garden")
+	october := newDoc("Oct 2nd, 2026", JournalDay(time.Date(2026, 10, 2, 12, 0, 0, 0, time.UTC)), true, "Prune the garden /scheduled 2026-10-05")
+	page := newDoc("Garden Plan", time.Date(2026, 8, 1, 12, 0, 0, 0, time.Local), false, "Orchard layout")
+
+	tests := []struct {
+		query string
+		want  []domain.DocumentID
+	}{
+		{"garden is:journal", []domain.DocumentID{september, first, october}},
+		{"garden is:page", []domain.DocumentID{page}},
+		{"title:garden", []domain.DocumentID{page}},
+		{"garden -prune", []domain.DocumentID{september, first, page}},
+		{"garden has:task", []domain.DocumentID{october}},
+		{"links:[[roses]]", []domain.DocumentID{september}},
+		{"garden before:2026-10-01", []domain.DocumentID{september, page}},
+		{"garden after:2026-09-30", []domain.DocumentID{first, october}},
+		{"garden after:2026-09-30 before:2026-10-02", []domain.DocumentID{first}},
+		{"garden after:2026-10-01", []domain.DocumentID{october}},
+		{"orchard OR roses", []domain.DocumentID{september, page}},
+		{"exact:gardn", nil},
+	}
+
+	for _, tt := range tests {
+		ids, err := store.Search(tt.query)
+		if err != nil {
+			t.Errorf("Search(%q) failed: %v", tt.query, err)
+			continue
+		}
+		got := make(map[domain.DocumentID]bool)
+		for _, id := range ids {
+			got[id] = true
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, ids, tt.want)
+			continue
+		}
+		for _, id := range tt.want {
+			if !got[id] {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, ids, tt.want)
+				break
+			}
+		}
+	}
+
+	// Block hits only match tasks and links in the block itself
+	hits, err := store.SearchBlocks("garden has:task", 10)
+	if err != nil {
+		t.Fatalf("SearchBlocks failed: %v", err)
+	}
+	if len(hits) != 1 || hits[0].DocID != october {
+		t.Errorf("Expected the task block, got %+v", hits)
+	}
+}
+
+func TestOutdatedSearchIndexIsRebuilt(t *testing.T) {
+	store, err := NewDocumentStore("./testoutdated.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = os.Remove("./testoutdated.db")
+		_ = os.RemoveAll("./testoutdated.db.bleve")
+	}()
+
+	doc := &domain.Document{
+		ID:     domain.DocumentID(uuid.New()),
+		Title:  "Reading list",
+		Date:   time.Now(),
+		Blocks: []*domain.Block{{ID: domain.BlockID(uuid.New()), Content: "Moby Dick"}},
+	}
+	if err := store.Save(doc); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := store.search.index.SetInternal(bleveMappingVersionKey, []byte("1")); err != nil {
+		t.Fatalf("SetInternal failed: %v", err)
+	}
+	_ = store.Close()
+
+	store, err = NewDocumentStore("./testoutdated.db")
+	if err != nil {
+		t.Fatalf("Failed to reopen DocumentStore: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	// Searches wait for the rebuild
+	hits, err := store.SearchBlocks("moby", 10)
+	if err != nil || len(hits) != 1 {
+		t.Errorf("Expected the rebuilt index to find the block, got %+v, %v", hits, err)
+	}
+	if health := store.GetIndexHealth(); health.RequiresReindex {
+		t.Errorf("Expected no reindex required after the rebuild, got %+v", health)
+	}
+}
+
+func TestSearchPage(t *testing.T) {
+	store, err := NewDocumentStore("./testsearchpage.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove("./testsearchpage.db")
+		_ = os.RemoveAll("./testsearchpage.db.bleve")
+	}()
+
+	// Titles in reverse date order
+	var ids []domain.DocumentID
+	for i, title := range []string{"echo", "Delta", "charlie", "Bravo", "alpha"} {
+		doc := &domain.Document{
+			ID:     domain.DocumentID(uuid.New()),
+			Title:  title,
+			Date:   time.Date(2026, 10, 1+i, 0, 0, 0, 0, time.UTC),
+			Blocks: []*domain.Block{{ID: domain.BlockID(uuid.New()), Content: "shared notes"}},
+		}
+		if err := store.Save(doc); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		ids = append(ids, doc.ID)
+	}
+
+	titles := func(results *SearchResults) []string {
+		var got []string
+		for _, hit := range results.Hits {
+			got = append(got, hit.Title)
+		}
+		return got
+	}
+
+	results, err := store.SearchPage("shared", 1, 2, SortTitle)
+	if err != nil {
+		t.Fatalf("SearchPage failed: %v", err)
+	}
+	if results.Total != 5 || strings.Join(titles(results), ",") != "Bravo,charlie" {
+		t.Errorf("Expected the second page by title of 5, got %d %v", results.Total, titles(results))
+	}
+
+	results, _ = store.SearchPage("shared", 0, 2, SortDate)
+	if strings.Join(titles(results), ",") != "alpha,Bravo" || !results.Hits[0].Date.Equal(time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the newest first with their dates, got %+v", results.Hits)
+	}
+
+	// A document missing from the database is skipped but still counted
+	err = store.bolt.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(store.bucketDocs).Delete([]byte(ids[4].String()))
+	})
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	results, err = store.SearchPage("shared", 0, 10, SortRelevance)
+	if err != nil {
+		t.Fatalf("SearchPage failed: %v", err)
+	}
+	if results.Total != 5 || len(results.Hits) != 4 {
+		t.Errorf("Expected 4 of 5 hits without the stale one, got %d %v", results.Total, titles(results))
+	}
+
+	if _, err := store.SearchPage("shared", 0, 10, "size"); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Expected ErrInvalidQuery for an unknown sort, got %v", err)
+	}
+}
@@ -0,0 +1,325 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+var ErrInvalidQuery = errors.New("invalid search query")
+
+type clauseKind int
+
+const (
+	clauseText    clauseKind = iota // words or a "phrase"
+	clauseTitle                     // title:word or title:"phrase"
+	clauseJournal                   // is:journal
+	clausePage                      // is:page
+	clauseTask                      // has:task
+	clauseLinks                     // links:[[Page]]
+	clauseBefore                    // before:YYYY-MM-DD
+	clauseAfter                     // after:YYYY-MM-DD
+)
+
+type searchClause struct {
+	kind   clauseKind
+	text   string    // lowercased; the title for clauseLinks
+	phrase bool      // quoted text
+	exact  bool      // exact: prefix, no fuzziness
+	date   time.Time // start of the UTC day for clauseBefore and clauseAfter
+}
+
+// searchQuery is a parsed search query. An entry matches when it matches
+// at least one clause of every group and none of the excluded clauses.
+type searchQuery struct {
+	groups   [][]searchClause
+	excluded []searchClause
+}
+
+func (q searchQuery) empty() bool {
+	return len(q.groups) == 0 && len(q.excluded) == 0
+}
+
+// parseSearchQuery parses the search query language:
+//
+//   - word              fuzzy match in the title or content
+//   - "exact phrase"    phrase match; unmatched quotes are ignored
+//   - exact:word        match without fuzziness
+//   - title:word        match in the title only, also title:"a phrase"
+//   - -word             exclude matches, works with any clause: -is:journal
+//   - a OR b            match either clause; terms are otherwise all required
+//   - is:journal        journal pages only; is:page for other pages
+//   - has:task          containing a /scheduled task
+//   - links:[[Page]]    linking to Page
+//   - before:2026-01-31 dated before that day
+//   - after:2026-01-31  dated after that day
+//
+// Text is matched case-insensitively, single-character words are ignored,
+// and words with an unknown prefix such as http: are searched as text.
+// Unknown is: or has: values and invalid dates fail with ErrInvalidQuery.
+func parseSearchQuery(input string) (searchQuery, error) {
+	var q searchQuery
+	or := false
+	for _, token := range splitSearchQuery(input) {
+		if token == "OR" {
+			or = len(q.groups) > 0
+			continue
+		}
+
+		negate := false
+		if len(token) > 1 && token[0] == '-' {
+			negate = true
+			token = token[1:]
+		}
+
+		clause, ok, err := parseSearchClause(token)
+		if err != nil {
+			return searchQuery{}, err
+		}
+		if !ok {
+			continue
+		}
+
+		switch {
+		case negate:
+			q.excluded = append(q.excluded, clause)
+		case or:
+			last := len(q.groups) - 1
+			q.groups[last] = append(q.groups[last], clause)
+		default:
+			q.groups = append(q.groups, []searchClause{clause})
+		}
+		or = false
+	}
+	return q, nil
+}
+
+// splitSearchQuery splits a query on whitespace, keeping quoted phrases
+// and [[links]] in one token.
+func splitSearchQuery(input string) []string {
+	var tokens []string
+	i := 0
+	for i < len(input) {
+		if isQuerySpace(input[i]) {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(input) && !isQuerySpace(input[i]) {
+			if input[i] == '"' {
+				if end := strings.IndexByte(input[i+1:], '"'); end >= 0 {
+					i += end + 2
+					continue
+				}
+			} else if strings.HasPrefix(input[i:], "[[") {
+				if end := strings.Index(input[i+2:], "]]"); end >= 0 {
+					i += end + 4
+					continue
+				}
+			}
+			i++
+		}
+		tokens = append(tokens, input[start:i])
+	}
+	return tokens
+}
+
+func isQuerySpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// parseSearchClause parses a single token; ok is false for tokens that
+// are ignored.
+func parseSearchClause(token string) (clause searchClause, ok bool, err error) {
+	key, value, found := strings.Cut(token, ":")
+	if found && value != "" {
+		switch strings.ToLower(key) {
+		case "title":
+			clause, ok = textClause(value)
+			clause.kind = clauseTitle
+			return clause, ok, nil
+		case "exact":
+			clause, ok = textClause(value)
+			clause.exact = true
+			return clause, ok, nil
+		case "is":
+			switch strings.ToLower(value) {
+			case "journal":
+				return searchClause{kind: clauseJournal}, true, nil
+			case "page":
+				return searchClause{kind: clausePage}, true, nil
+			}
+			return clause, false, fmt.Errorf("%w: unknown is:%s, use is:journal or is:page", ErrInvalidQuery, value)
+		case "has":
+			if strings.ToLower(value) == "task" {
+				return searchClause{kind: clauseTask}, true, nil
+			}
+			return clause, false, fmt.Errorf("%w: unknown has:%s, use has:task", ErrInvalidQuery, value)
+		case "links":
+			title := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(value, "[["), "]]"))
+			if title == "" {
+				return clause, false, fmt.Errorf("%w: links: needs a page, as in links:[[Page]]", ErrInvalidQuery)
+			}
+			return searchClause{kind: clauseLinks, text: strings.ToLower(title)}, true, nil
+		case "before", "after":
+			// Journals are dated by their UTC day (JournalDay), so days
+			// start at UTC midnight
+			date, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return clause, false, fmt.Errorf("%w: %s:%s is not a YYYY-MM-DD date", ErrInvalidQuery, key, value)
+			}
+			if strings.ToLower(key) == "before" {
+				return searchClause{kind: clauseBefore, date: date}, true, nil
+			}
+			return searchClause{kind: clauseAfter, date: date}, true, nil
+		}
+	}
+
+	clause, ok = textClause(token)
+	return clause, ok, nil
+}
+
+func textClause(value string) (searchClause, bool) {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		phrase := strings.ToLower(strings.TrimSpace(value[1 : len(value)-1]))
+		return searchClause{kind: clauseText, text: phrase, phrase: true}, phrase != ""
+	}
+	// Unmatched quotes are not part of the word
+	value = strings.Trim(value, `"`)
+	if len(value) < 2 {
+		return searchClause{}, false
+	}
+	return searchClause{kind: clauseText, text: strings.ToLower(value)}, true
+}
+
+// analyze rewrites the text of q with terms, the analysis of the index,
+// since fuzzy and phrase queries are not analyzed by Bleve. A word that
+// becomes several terms, like CJK bigrams, is matched as a phrase. Exact
+// clauses use match queries, which Bleve analyzes itself.
+func (q searchQuery) analyze(terms func(text string) []string) searchQuery {
+	for _, group := range q.groups {
+		for i := range group {
+			group[i] = group[i].analyze(terms)
+		}
+	}
+	for i := range q.excluded {
+		q.excluded[i] = q.excluded[i].analyze(terms)
+	}
+	return q
+}
+
+func (c searchClause) analyze(terms func(text string) []string) searchClause {
+	if (c.kind != clauseText && c.kind != clauseTitle) || c.exact {
+		return c
+	}
+	words := terms(c.text)
+	if len(words) > 1 {
+		c.phrase = true
+	}
+	c.text = strings.Join(words, " ")
+	return c
+}
+
+// bleveQuery returns the Bleve query matching q against the entries of the
+// given kind, bleveKindDoc or bleveKindBlock. Plain text matches the title
+// and content of documents, and the content of blocks. An empty kind
+// matches documents of indexes without kinds.
+func (q searchQuery) bleveQuery(kind string) query.Query {
+	must := bleve.NewConjunctionQuery()
+	if kind != "" {
+		kindQ := bleve.NewTermQuery(kind)
+		kindQ.SetField(bleveFieldKind)
+		must.AddQuery(kindQ)
+	}
+	for _, group := range q.groups {
+		if len(group) == 1 {
+			must.AddQuery(group[0].bleveQuery(kind))
+			continue
+		}
+		disj := bleve.NewDisjunctionQuery()
+		for _, clause := range group {
+			disj.AddQuery(clause.bleveQuery(kind))
+		}
+		must.AddQuery(disj)
+	}
+
+	boolean := bleve.NewBooleanQuery()
+	boolean.AddMust(must)
+	for _, clause := range q.excluded {
+		boolean.AddMustNot(clause.bleveQuery(kind))
+	}
+	return boolean
+}
+
+func (c searchClause) bleveQuery(kind string) query.Query {
+	switch c.kind {
+	case clauseTitle:
+		return c.textQuery(bleveFieldTitle)
+	case clauseJournal, clausePage:
+		q := bleve.NewBoolFieldQuery(c.kind == clauseJournal)
+		q.SetField(bleveFieldJournal)
+		return q
+	case clauseTask:
+		q := bleve.NewBoolFieldQuery(true)
+		q.SetField(bleveFieldTask)
+		return q
+	case clauseLinks:
+		q := bleve.NewTermQuery(c.text)
+		q.SetField(bleveFieldLinks)
+		return q
+	case clauseBefore:
+		q := bleve.NewDateRangeQuery(time.Time{}, c.date)
+		q.SetField(bleveFieldDate)
+		return q
+	case clauseAfter:
+		q := bleve.NewDateRangeQuery(c.date.AddDate(0, 0, 1), time.Time{})
+		q.SetField(bleveFieldDate)
+		return q
+	}
+
+	if kind == bleveKindBlock {
+		return c.textQuery(bleveFieldContent)
+	}
+	titleQ := c.textQuery(bleveFieldTitle)
+	titleQ.(query.BoostableQuery).SetBoost(titleBoost)
+	return bleve.NewDisjunctionQuery(titleQ, c.textQuery(bleveFieldContent))
+}
+
+func (c searchClause) textQuery(field string) query.Query {
+	switch {
+	case c.phrase && c.exact:
+		// Not rewritten by analyze, so Bleve analyzes the phrase
+		q := bleve.NewMatchPhraseQuery(c.text)
+		q.SetField(field)
+		return q
+	case c.phrase:
+		return phraseQuery(c.text, field)
+	case c.exact:
+		q := bleve.NewMatchQuery(c.text)
+		q.SetField(field)
+		return q
+	}
+	return fuzzyQuery(c.text, field)
+}
+
+func phraseQuery(phrase string, field string) *query.PhraseQuery {
+	return bleve.NewPhraseQuery(strings.Fields(phrase), field)
+}
+
+func fuzzyQuery(token string, field string) *query.FuzzyQuery {
+	fuzziness := 1
+	if utf8.RuneCountInString(token) >= 5 {
+		fuzziness = 2
+	}
+
+	q := bleve.NewFuzzyQuery(token)
+	q.SetField(field)
+	q.SetFuzziness(fuzziness)
+	return q
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		query    string
+		groups   [][]searchClause
+		excluded []searchClause
+	}{
+		{
+			query:  `search "Exact Match" a`,
+			groups: [][]searchClause{{{text: "search"}}, {{text: "exact match", phrase: true}}},
+		},
+		{
+			query:  `search "unclosed`,
+			groups: [][]searchClause{{{text: "search"}}, {{text: "unclosed"}}},
+		},
+		{
+			query:    `title:"Weekly Review" exact:Go -draft -is:journal`,
+			groups:   [][]searchClause{{{kind: clauseTitle, text: "weekly review", phrase: true}}, {{text: "go", exact: true}}},
+			excluded: []searchClause{{text: "draft"}, {kind: clauseJournal}},
+		},
+		{
+			query:  `garden OR orchard OR farm is:page`,
+			groups: [][]searchClause{{{text: "garden"}, {text: "orchard"}, {text: "farm"}}, {{kind: clausePage}}},
+		},
+		{
+			query:  `OR has:task links:[[Project X]] after:2026-10-01 before:2026-10-01`,
+			groups: [][]searchClause{{{kind: clauseTask}}, {{kind: clauseLinks, text: "project x"}}, {{kind: clauseAfter, date: day}}, {{kind: clauseBefore, date: day}}},
+		},
+		{
+			query:  `http://example.com`,
+			groups: [][]searchClause{{{text: "http://example.com"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		q, err := parseSearchQuery(tt.query)
+		if err != nil {
+			t.Errorf("parseSearchQuery(%q) failed: %v", tt.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(q.groups, tt.groups) || !reflect.DeepEqual(q.excluded, tt.excluded) {
+			t.Errorf("parseSearchQuery(%q) = %+v, want groups %+v excluded %+v", tt.query, q, tt.groups, tt.excluded)
+		}
+	}
+
+	for _, query := range []string{"is:draft", "has:link", "before:yesterday", "links:[[]]"} {
+		if _, err := parseSearchQuery(query); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("parseSearchQuery(%q) = %v, want ErrInvalidQuery", query, err)
+		}
+	}
+}
+
+func TestAnalyzeSearchQuery(t *testing.T) {
+	terms := func(text string) []string {
+		if text == "東京都" {
+			return []string{"東京", "京都"}
+		}
+		return []string{strings.TrimSuffix(text, "ing")}
+	}
+
+	q, err := parseSearchQuery(`running title:東京都 exact:running -walking links:[[Running]]`)
+	if err != nil {
+		t.Fatalf("parseSearchQuery failed: %v", err)
+	}
+	q = q.analyze(terms)
+
+	groups := [][]searchClause{
+		{{text: "runn"}},
+		{{kind: clauseTitle, text: "東京 京都", phrase: true}},
+		{{text: "running", exact: true}},
+		{{kind: clauseLinks, text: "running"}},
+	}
+	if !reflect.DeepEqual(q.groups, groups) || !reflect.DeepEqual(q.excluded, []searchClause{{text: "walk"}}) {
+		t.Errorf("analyze = %+v, want groups %+v", q, groups)
+	}
+}
@@ -0,0 +1,238 @@
+package db
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"glog/domain"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+var ErrReindexRequired = errors.New("search index requires a full reindex")
+
+// verifyPageSize is the number of search entries read per request when
+// VerifySearchIndex walks the index.
+const verifyPageSize = 1000
+
+// IndexVerification reports the documents whose search entries disagree
+// with the database, see VerifySearchIndex.
+type IndexVerification struct {
+	Documents int                 // documents in the database
+	Entries   int                 // search entries checked, including block entries
+	Missing   []domain.DocumentID // documents without a search entry
+	Stale     []domain.DocumentID // documents whose entries differ from their content
+	Orphaned  []domain.DocumentID // search entries of documents that no longer exist
+	Repaired  int                 // documents reindexed, 0 when read-only
+}
+
+// Consistent reports whether the index matched the database.
+func (v *IndexVerification) Consistent() bool {
+	return len(v.Missing) == 0 && len(v.Stale) == 0 && len(v.Orphaned) == 0
+}
+
+// searchHash returns a hash of everything batchIndex derives the search
+// entries of doc from.
+func searchHash(doc *DocDb) string {
+	h := sha256.New()
+	write := func(s string) {
+		h.Write([]byte(strconv.Itoa(len(s))))
+		h.Write([]byte{':'})
+		h.Write([]byte(s))
+	}
+	write(doc.Title)
+	write(doc.Date)
+	write(strconv.FormatBool(doc.IsJournal))
+	for _, block := range doc.Blocks {
+		if block == nil {
+			continue
+		}
+		write(block.ID.String())
+		write(block.Content)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// bleveEntries are the search entries of an index by document.
+type bleveEntries struct {
+	count  int
+	hashes map[uuid.UUID]string          // stored hashes of the document entries
+	blocks map[uuid.UUID]map[string]bool // IDs of the block entries
+}
+
+// entries walks every entry of the index in pages of verifyPageSize.
+func (s *bleveSearch) entries() (*bleveEntries, error) {
+	entries := &bleveEntries{
+		hashes: make(map[uuid.UUID]string),
+		blocks: make(map[uuid.UUID]map[string]bool),
+	}
+	if s == nil || s.index == nil {
+		return entries, nil
+	}
+
+	var after []string
+	for {
+		req := bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), verifyPageSize, 0, false)
+		req.Fields = []string{bleveFieldHash}
+		req.SortBy([]string{"_id"})
+		req.SearchAfter = after
+		result, err := s.index.Search(req)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, hit := range result.Hits {
+			entries.count++
+			docPart, _, isBlock := strings.Cut(hit.ID, "/")
+			docID, err := uuid.Parse(docPart)
+			if err != nil {
+				continue
+			}
+			if isBlock {
+				if entries.blocks[docID] == nil {
+					entries.blocks[docID] = make(map[string]bool)
+				}
+				entries.blocks[docID][hit.ID] = true
+				continue
+			}
+			hash, _ := hit.Fields[bleveFieldHash].(string)
+			entries.hashes[docID] = hash
+		}
+		if len(result.Hits) < verifyPageSize {
+			return entries, nil
+		}
+		after = []string{result.Hits[len(result.Hits)-1].ID}
+	}
+}
+
+// VerifySearchIndex compares the search index with the database: every
+// document needs an entry with its current hash and one entry per
+// non-empty block, and every entry a document. The documents that differ
+// are reindexed through the indexing outbox, so saves and searches go on
+// meanwhile; a read-only store only reports them. Indexes that need a
+// full rebuild, see GetIndexHealth, return ErrReindexRequired.
+func (store *DocumentStore) VerifySearchIndex() (*IndexVerification, error) {
+	store.waitIndexed()
+
+	store.searchMu.RLock()
+	if store.searchOutdated() {
+		store.searchMu.RUnlock()
+		return nil, ErrReindexRequired
+	}
+	entries, err := store.search.entries()
+	store.searchMu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &IndexVerification{Entries: entries.count}
+	var repair []uuid.UUID
+	err = store.bolt.View(func(tx *bolt.Tx) error {
+		// Documents waiting in the outbox are indexed anyway
+		pending := func(id uuid.UUID) bool {
+			bucket := tx.Bucket(indexOutboxBucket)
+			return bucket != nil && bucket.Get([]byte(id.String())) != nil
+		}
+
+		bucket := tx.Bucket(store.bucketDocs)
+		err := bucket.ForEach(func(k, v []byte) error {
+			var docDb DocDb
+			if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&docDb); err != nil {
+				return err
+			}
+			result.Documents++
+			if pending(docDb.ID) {
+				return nil
+			}
+
+			hash, indexed := entries.hashes[docDb.ID]
+			if !indexed {
+				result.Missing = append(result.Missing, domain.DocumentID(docDb.ID))
+				repair = append(repair, docDb.ID)
+				return nil
+			}
+			if hash != searchHash(&docDb) || !sameBlockEntries(&docDb, entries.blocks[docDb.ID]) {
+				result.Stale = append(result.Stale, domain.DocumentID(docDb.ID))
+				repair = append(repair, docDb.ID)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		orphaned := make(map[uuid.UUID]bool)
+		for id := range entries.hashes {
+			orphaned[id] = true
+		}
+		for id := range entries.blocks {
+			orphaned[id] = true
+		}
+		for id := range orphaned {
+			if bucket.Get([]byte(id.String())) != nil || pending(id) {
+				continue
+			}
+			result.Orphaned = append(result.Orphaned, domain.DocumentID(id))
+			repair = append(repair, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, ids := range [][]domain.DocumentID{result.Missing, result.Stale, result.Orphaned} {
+		sort.Slice(ids, func(i, j int) bool {
+			return uuid.UUID(ids[i]).String() < uuid.UUID(ids[j]).String()
+		})
+	}
+
+	if len(repair) == 0 || store.readOnly {
+		return result, nil
+	}
+	err = store.bolt.Update(func(tx *bolt.Tx) error {
+		for _, id := range repair {
+			if err := enqueueIndex(tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	store.notifyIndexer()
+	store.indexQueue.wait(true)
+
+	store.failedIndexesMu.Lock()
+	defer store.failedIndexesMu.Unlock()
+	for _, id := range repair {
+		if _, failed := store.failedIndexes[id.String()]; !failed {
+			result.Repaired++
+		}
+	}
+	return result, nil
+}
+
+// sameBlockEntries reports whether indexed holds exactly the block entries
+// batchIndex makes for doc.
+func sameBlockEntries(doc *DocDb, indexed map[string]bool) bool {
+	expected := make(map[string]bool, len(doc.Blocks))
+	for _, block := range doc.Blocks {
+		if block == nil || strings.TrimSpace(block.Content) == "" {
+			continue
+		}
+		id := blockEntryID(doc.ID, block.ID)
+		if !indexed[id] {
+			return false
+		}
+		expected[id] = true
+	}
+	return len(expected) == len(indexed)
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"glog/domain"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestVerifySearchIndex(t *testing.T) {
+	store, err := NewDocumentStore("./testverify.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = os.Remove("./testverify.db")
+		_ = os.RemoveAll("./testverify.db.bleve")
+	}()
+
+	missing := saveTestDoc(t, store, "Missing", "narwhal")
+	stale := saveTestDoc(t, store, "Stale", "pangolin")
+	lostBlock := saveTestDoc(t, store, "Lost block", "axolotl", "capybara")
+	saveTestDoc(t, store, "Intact", "okapi")
+
+	result, err := store.VerifySearchIndex()
+	if err != nil {
+		t.Fatalf("VerifySearchIndex failed: %v", err)
+	}
+	if !result.Consistent() || result.Documents != 4 || result.Entries != 9 || result.Repaired != 0 {
+		t.Errorf("Expected a consistent index of 4 documents and 9 entries, got %+v", result)
+	}
+
+	// Damage the index behind the store's back
+	if err := store.search.DeleteDoc(uuid.UUID(missing.ID).String()); err != nil {
+		t.Fatalf("DeleteDoc failed: %v", err)
+	}
+	if err := store.search.IndexDoc(&DocDb{
+		ID:     uuid.UUID(stale.ID),
+		Title:  stale.Title,
+		Date:   stale.Date.Format(time.RFC3339),
+		Blocks: []*BlockDb{{ID: uuid.UUID(stale.Blocks[0].ID), Content: "outdated"}},
+	}); err != nil {
+		t.Fatalf("IndexDoc failed: %v", err)
+	}
+	if err := store.search.index.Delete(blockEntryID(uuid.UUID(lostBlock.ID), uuid.UUID(lostBlock.Blocks[1].ID))); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	orphan := uuid.New()
+	if err := store.search.IndexDoc(&DocDb{
+		ID:     orphan,
+		Title:  "Orphan",
+		Date:   time.Now().Format(time.RFC3339),
+		Blocks: []*BlockDb{{ID: uuid.New(), Content: "dodo"}},
+	}); err != nil {
+		t.Fatalf("IndexDoc failed: %v", err)
+	}
+
+	result, err = store.VerifySearchIndex()
+	if err != nil {
+		t.Fatalf("VerifySearchIndex failed: %v", err)
+	}
+	if len(result.Missing) != 1 || result.Missing[0] != missing.ID {
+		t.Errorf("Expected %v missing, got %v", missing.ID, result.Missing)
+	}
+	if len(result.Stale) != 2 {
+		t.Errorf("Expected the stale document and the one with a lost block, got %v", result.Stale)
+	}
+	if len(result.Orphaned) != 1 || result.Orphaned[0] != domain.DocumentID(orphan) {
+		t.Errorf("Expected %v orphaned, got %v", orphan, result.Orphaned)
+	}
+	if result.Repaired != 4 {
+		t.Errorf("Expected 4 repaired documents, got %d", result.Repaired)
+	}
+
+	// Only the damaged entries were reindexed
+	for query, want := range map[string]int{"narwhal": 1, "pangolin": 1, "outdated": 0, "dodo": 0} {
+		if ids, _ := store.Search(query); len(ids) != want {
+			t.Errorf("Search(%q) = %v, want %d results", query, ids, want)
+		}
+	}
+	if hits, _ := store.SearchBlocks("capybara", 10); len(hits) != 1 {
+		t.Errorf("Expected the lost block entry to be restored, got %v", hits)
+	}
+	if result, _ := store.VerifySearchIndex(); result == nil || !result.Consistent() {
+		t.Errorf("Expected a consistent index after the repair, got %+v", result)
+	}
+	_ = store.Close()
+
+	// Read-only stores only report
+	store, err = NewDocumentStore("./testverify.db")
+	if err != nil {
+		t.Fatalf("Failed to reopen DocumentStore: %v", err)
+	}
+	if err := store.search.DeleteDoc(uuid.UUID(missing.ID).String()); err != nil {
+		t.Fatalf("DeleteDoc failed: %v", err)
+	}
+	_ = store.Close()
+
+	store, err = NewDocumentStoreWithOptions("./testverify.db", Options{ReadOnly: true, Timeout: DefaultLockTimeout})
+	if err != nil {
+		t.Fatalf("Failed to reopen DocumentStore: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	result, err = store.VerifySearchIndex()
+	if err != nil {
+		t.Fatalf("VerifySearchIndex failed: %v", err)
+	}
+	if len(result.Missing) != 1 || result.Repaired != 0 {
+		t.Errorf("Expected one missing document left unrepaired, got %+v", result)
+	}
+}
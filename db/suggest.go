@@ -0,0 +1,168 @@
+package db
+
+import (
+	"bytes"
+	"glog/domain"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+// maxPrefixCandidates bounds the titles read from the title index for one
+// SuggestTitles call, so short prefixes stay fast on large graphs.
+const maxPrefixCandidates = 500
+
+// TitleSuggestion is a page title suggested for a [[link]].
+type TitleSuggestion struct {
+	ID        domain.DocumentID
+	Title     string
+	Backlinks int  // documents linking to the title
+	Fuzzy     bool // found by the search index rather than by prefix
+}
+
+type titleCandidate struct {
+	key       string // lowercased title
+	id        uuid.UUID
+	recent    int // position in the recents, or -1
+	backlinks int
+}
+
+// SuggestTitles returns up to limit titles starting with prefix, ignoring
+// case, for link autocomplete. They are read from the title index with a
+// cursor seek and ranked with the exact match first, then recently opened
+// titles, then by backlink count. When fewer than limit titles start with
+// prefix, the rest are filled with fuzzy and inner-word matches from the
+// search index.
+func (store *DocumentStore) SuggestTitles(prefix string, limit int) ([]TitleSuggestion, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+
+	var suggestions []TitleSuggestion
+	err := store.bolt.View(func(tx *bolt.Tx) error {
+		recent := make(map[uuid.UUID]int)
+		if data := tx.Bucket(store.recentsDocs.recentsBucket).Get([]byte("recents_list")); data != nil {
+			ids, err := deserializeRecents(data)
+			if err != nil {
+				return err
+			}
+			for i, id := range ids {
+				recent[id] = i
+			}
+		}
+
+		references := tx.Bucket(store.referencesIndex.referenceIndex)
+		var candidates []titleCandidate
+		c := tx.Bucket(store.bucketTitleIndex).Cursor()
+		for k, v := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = c.Next() {
+			if len(candidates) == maxPrefixCandidates {
+				break
+			}
+			id, err := uuid.Parse(string(v))
+			if err != nil {
+				continue
+			}
+			candidate := titleCandidate{key: string(k), id: id, recent: -1}
+			if i, ok := recent[id]; ok {
+				candidate.recent = i
+			}
+			if data := references.Get(k); data != nil {
+				candidate.backlinks = len(decodeUUIDSet(data))
+			}
+			candidates = append(candidates, candidate)
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool {
+			a, b := candidates[i], candidates[j]
+			if (a.key == prefix) != (b.key == prefix) {
+				return a.key == prefix
+			}
+			if (a.recent >= 0) != (b.recent >= 0) {
+				return a.recent >= 0
+			}
+			if a.recent != b.recent {
+				return a.recent < b.recent
+			}
+			if a.backlinks != b.backlinks {
+				return a.backlinks > b.backlinks
+			}
+			return a.key < b.key
+		})
+
+		for _, candidate := range candidates {
+			if len(suggestions) == limit {
+				break
+			}
+			docDb, err := store.getDocDb(tx, domain.DocumentID(candidate.id))
+			if err != nil {
+				continue
+			}
+			suggestions = append(suggestions, TitleSuggestion{
+				ID:        domain.DocumentID(candidate.id),
+				Title:     docDb.Title,
+				Backlinks: candidate.backlinks,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(suggestions) >= limit || prefix == "" {
+		return suggestions, nil
+	}
+
+	store.waitIndexed()
+	store.searchMu.RLock()
+	hits, err := store.search.SuggestTitles(prefix, limit+len(suggestions))
+	store.searchMu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.bolt.View(func(tx *bolt.Tx) error {
+		seen := make(map[domain.DocumentID]bool, len(suggestions))
+		for _, s := range suggestions {
+			seen[s.ID] = true
+		}
+
+		docs := tx.Bucket(store.bucketDocs)
+		references := tx.Bucket(store.referencesIndex.referenceIndex)
+		for _, hit := range hits {
+			id := domain.DocumentID(hit.ID)
+			if len(suggestions) == limit {
+				break
+			}
+			if seen[id] {
+				continue
+			}
+
+			// Skips stale hits; older indexes do not store titles
+			title := hit.Title
+			if title == "" {
+				docDb, err := store.getDocDb(tx, id)
+				if err != nil {
+					continue
+				}
+				title = docDb.Title
+			} else if docs.Get([]byte(id.String())) == nil {
+				continue
+			}
+			suggestions = append(suggestions, TitleSuggestion{
+				ID:        id,
+				Title:     title,
+				Backlinks: len(decodeUUIDSet(references.Get([]byte(strings.ToLower(title))))),
+				Fuzzy:     true,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSuggestTitles(t *testing.T) {
+	store, err := NewDocumentStore("./testsuggest.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove("./testsuggest.db")
+		_ = os.RemoveAll("./testsuggest.db.bleve")
+	}()
+
+	alpha := saveTestDoc(t, store, "Project Alpha").ID
+	saveTestDoc(t, store, "Project Beta")
+	saveTestDoc(t, store, "project")
+	saveTestDoc(t, store, "Prototype")
+	saveTestDoc(t, store, "My Projects")
+	saveTestDoc(t, store, "Weekly 1", "Reviewed [[Project Beta]]")
+	saveTestDoc(t, store, "Weekly 2", "More on [[project beta]]")
+
+	if _, err := store.LoadDocument(alpha); err != nil {
+		t.Fatalf("LoadDocument failed: %v", err)
+	}
+
+	titles := func(suggestions []TitleSuggestion) []string {
+		var got []string
+		for _, s := range suggestions {
+			got = append(got, s.Title)
+		}
+		return got
+	}
+
+	suggestions, err := store.SuggestTitles("Proj", 10)
+	if err != nil {
+		t.Fatalf("SuggestTitles failed: %v", err)
+	}
+	want := []string{"Project Alpha", "Project Beta", "project", "My Projects"}
+	if got := titles(suggestions); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] || got[3] != want[3] {
+		t.Fatalf("SuggestTitles = %v, want %v", got, want)
+	}
+	if suggestions[1].Backlinks != 2 || suggestions[1].Fuzzy || !suggestions[3].Fuzzy {
+		t.Errorf("Expected backlinks and the fuzzy fallback to be reported, got %+v", suggestions)
+	}
+
+	if got := titles(mustSuggest(t, store, "project", 1)); len(got) != 1 || got[0] != "project" {
+		t.Errorf("Expected the exact match first, got %v", got)
+	}
+	if got := titles(mustSuggest(t, store, "protoype", 5)); len(got) != 1 || got[0] != "Prototype" {
+		t.Errorf("Expected a fuzzy match for a typo, got %v", got)
+	}
+}
+
+func mustSuggest(t *testing.T, store *DocumentStore, prefix string, limit int) []TitleSuggestion {
+	t.Helper()
+	suggestions, err := store.SuggestTitles(prefix, limit)
+	if err != nil {
+		t.Fatalf("SuggestTitles(%q) failed: %v", prefix, err)
+	}
+	return suggestions
+}
@@ -0,0 +1,236 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"glog/domain"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+// maxMentionHits bounds the blocks read from the search index per name.
+const maxMentionHits = 1000
+
+// UnlinkedReference is a block mentioning a page by its title or one of
+// its aliases in plain text, in a document that does not link the page.
+type UnlinkedReference struct {
+	DocID      domain.DocumentID
+	Title      string // title of the document
+	BlockID    domain.BlockID
+	Content    string
+	Breadcrumb []string // first lines of the block's ancestors, outermost first
+	Mention    string   // the title or alias as written in the block
+	Fragments  []string // HTML-escaped, with matches in <mark> tags
+}
+
+// GetUnlinkedReferences returns the blocks that mention title, or an
+// "alias::" of the page titled title, in plain text. Documents that link
+// the page, and the page itself, are left out. Matches are found with a
+// phrase search and checked against the block content, so mentions inside
+// other [[links]] or within longer words do not count. References are
+// ordered by document title, then by block position.
+func (store *DocumentStore) GetUnlinkedReferences(title string) ([]UnlinkedReference, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return nil, fmt.Errorf("%w: empty title", ErrInvalidQuery)
+	}
+
+	names := []string{title}
+	var pageID domain.DocumentID
+	err := store.bolt.View(func(tx *bolt.Tx) error {
+		page, err := store.loadDocumentByTitle(tx, title)
+		if err == nil {
+			pageID = page.ID
+			names = append(names, DocumentAliases(page)...)
+		} else if !errors.Is(err, ErrDocumentNotFound) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	store.waitIndexed()
+	store.searchMu.RLock()
+	hits, err := store.search.MentionBlocks(names, maxMentionHits)
+	store.searchMu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	type position struct {
+		ref   UnlinkedReference
+		index int
+	}
+	var found []position
+	err = store.bolt.View(func(tx *bolt.Tx) error {
+		references := tx.Bucket(store.referencesIndex.referenceIndex)
+		linking := make(map[uuid.UUID]struct{})
+		for _, name := range names {
+			for id := range decodeUUIDSet(references.Get([]byte(strings.ToLower(name)))) {
+				linking[id] = struct{}{}
+			}
+		}
+
+		docs := make(map[uuid.UUID]*domain.Document)
+		for _, hit := range hits {
+			if _, ok := linking[hit.DocID]; ok || domain.DocumentID(hit.DocID) == pageID {
+				continue
+			}
+			doc, ok := docs[hit.DocID]
+			if !ok {
+				var err error
+				doc, err = store.loadDocument(tx, domain.DocumentID(hit.DocID))
+				if err != nil && !errors.Is(err, ErrDocumentNotFound) {
+					return err
+				}
+				docs[hit.DocID] = doc
+			}
+			if doc == nil {
+				continue
+			}
+
+			i := findBlock(doc.Blocks, domain.BlockID(hit.BlockID))
+			if i < 0 {
+				continue
+			}
+			start, end := findMention(doc.Blocks[i].Content, names)
+			if start < 0 {
+				continue
+			}
+			found = append(found, position{index: i, ref: UnlinkedReference{
+				DocID:      doc.ID,
+				Title:      doc.Title,
+				BlockID:    doc.Blocks[i].ID,
+				Content:    doc.Blocks[i].Content,
+				Breadcrumb: Breadcrumb(doc.Blocks, i),
+				Mention:    doc.Blocks[i].Content[start:end],
+				Fragments:  hit.Fragments,
+			}})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.ref.DocID != b.ref.DocID {
+			return strings.ToLower(a.ref.Title) < strings.ToLower(b.ref.Title)
+		}
+		return a.index < b.index
+	})
+	refs := make([]UnlinkedReference, len(found))
+	for i, f := range found {
+		refs[i] = f.ref
+	}
+	return refs, nil
+}
+
+// LinkReference turns the first plain-text mention of title, or of an
+// alias of the page titled title, in a block into a [[title]] link and
+// saves the document. It fails with ErrInvalidOp when the block has no
+// such mention.
+func (store *DocumentStore) LinkReference(docID domain.DocumentID, blockID domain.BlockID, title string) (*domain.Document, error) {
+	if store.readOnly {
+		return nil, ErrReadOnly
+	}
+	title = strings.TrimSpace(title)
+	if title == "" || strings.ContainsAny(title, "[]") {
+		return nil, fmt.Errorf("%w: invalid page title %q", ErrInvalidOp, title)
+	}
+
+	var doc *domain.Document
+	err := store.bolt.Update(func(tx *bolt.Tx) error {
+		names := []string{title}
+		if page, err := store.loadDocumentByTitle(tx, title); err == nil {
+			names = append(names, DocumentAliases(page)...)
+		} else if !errors.Is(err, ErrDocumentNotFound) {
+			return err
+		}
+
+		var err error
+		doc, err = store.loadDocument(tx, docID)
+		if err != nil {
+			return err
+		}
+		i := findBlock(doc.Blocks, blockID)
+		if i < 0 {
+			return fmt.Errorf("%w: block %s not found", ErrInvalidOp, blockID)
+		}
+
+		block := doc.Blocks[i]
+		start, end := findMention(block.Content, names)
+		if start < 0 {
+			return fmt.Errorf("%w: block %s does not mention %q", ErrInvalidOp, blockID, title)
+		}
+		block.Content = block.Content[:start] + "[[" + title + "]]" + block.Content[end:]
+
+		_, err = store.saveTx(tx, doc)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	store.notifyIndexer()
+	return doc, nil
+}
+
+// findMention returns the byte range of the first mention of one of names
+// in content, ignoring case, that is a whole word sequence outside of
+// [[links]], or -1, -1.
+func findMention(content string, names []string) (int, int) {
+	links := referenceRegex.FindAllStringIndex(content, -1)
+	inLink := func(start, end int) bool {
+		for _, link := range links {
+			if start < link[1] && end > link[0] {
+				return true
+			}
+		}
+		return false
+	}
+
+	bestStart, bestEnd := -1, -1
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		re, err := regexp.Compile(`(?i)` + regexp.QuoteMeta(strings.TrimSpace(name)))
+		if err != nil {
+			continue
+		}
+		for _, match := range re.FindAllStringIndex(content, -1) {
+			start, end := match[0], match[1]
+			if inLink(start, end) || !wordBoundary(content, start, end) {
+				continue
+			}
+			if bestStart < 0 || start < bestStart {
+				bestStart, bestEnd = start, end
+			}
+			break
+		}
+	}
+	return bestStart, bestEnd
+}
+
+// wordBoundary reports whether content[start:end] is not part of a longer
+// word.
+func wordBoundary(content string, start int, end int) bool {
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	if before, _ := utf8.DecodeLastRuneInString(content[:start]); start > 0 && isWord(before) {
+		return false
+	}
+	if after, _ := utf8.DecodeRuneInString(content[end:]); end < len(content) && isWord(after) {
+		return false
+	}
+	return true
+}
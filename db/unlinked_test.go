@@ -0,0 +1,81 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestUnlinkedReferences(t *testing.T) {
+	store, err := NewDocumentStore("./testunlinked.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove("./testunlinked.db")
+		_ = os.RemoveAll("./testunlinked.db.bleve")
+	}()
+
+	saveTestDoc(t, store, "Machine Learning", "alias:: ML", "Notes on machine learning")
+	notes := saveTestDoc(t, store, "Reading notes", "Intro", "A book on machine learning basics", "Also [[Machine Learning Ops]] and mlops")
+	meeting := saveTestDoc(t, store, "Meeting", "Talked about ML today")
+	saveTestDoc(t, store, "Linked", "See [[machine learning]], more machine learning")
+	saveTestDoc(t, store, "Aliased", "See [[ML]] and machine learning")
+
+	refs, err := store.GetUnlinkedReferences("Machine Learning")
+	if err != nil {
+		t.Fatalf("GetUnlinkedReferences failed: %v", err)
+	}
+	if len(refs) != 2 {
+		t.Fatalf("Expected a mention by title and one by alias, got %+v", refs)
+	}
+	if refs[0].DocID != meeting.ID || refs[0].Mention != "ML" {
+		t.Errorf("Expected the alias mention in Meeting first, got %+v", refs[0])
+	}
+	if refs[1].DocID != notes.ID || refs[1].BlockID != notes.Blocks[1].ID || refs[1].Mention != "machine learning" || len(refs[1].Fragments) == 0 {
+		t.Errorf("Expected the title mention in Reading notes, got %+v", refs[1])
+	}
+
+	doc, err := store.LinkReference(notes.ID, notes.Blocks[1].ID, "Machine Learning")
+	if err != nil {
+		t.Fatalf("LinkReference failed: %v", err)
+	}
+	if got := doc.Blocks[1].Content; got != "A book on [[Machine Learning]] basics" {
+		t.Errorf("Expected the mention to be linked in place, got %q", got)
+	}
+
+	refs, _ = store.GetUnlinkedReferences("Machine Learning")
+	if len(refs) != 1 || refs[0].DocID != meeting.ID {
+		t.Errorf("Expected the linked document to be excluded, got %+v", refs)
+	}
+
+	if _, err := store.LinkReference(notes.ID, notes.Blocks[2].ID, "Machine Learning"); !errors.Is(err, ErrInvalidOp) {
+		t.Errorf("Expected ErrInvalidOp for a block without a plain mention, got %v", err)
+	}
+}
+
+func TestFindMention(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"Go and go", "Go"},
+		{"gopher, then go.", "go"},
+		{"[[Go]] only", ""},
+		{"über-go", "go"},
+		{"ago, gophers", ""},
+		{"use Golang or GO", "Golang"},
+	}
+
+	for _, tt := range tests {
+		start, end := findMention(tt.content, []string{"go", "golang"})
+		got := ""
+		if start >= 0 {
+			got = tt.content[start:end]
+		}
+		if got != tt.want {
+			t.Errorf("findMention(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
@@ -24,6 +24,7 @@ type Document struct {
 	Date      time.Time // RFC 3339 format
 	IsJournal bool
 	Blocks    []*Block
+	Revision  uint64 // set by the store on load and save
 }
 
 type Block struct {
@@ -0,0 +1,208 @@
+package site
+
+import (
+	"fmt"
+	"glog/domain"
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// wikiLinkRegex matches [[Title]] and [[Title|label]] on HTML-escaped text
+	wikiLinkRegex = regexp.MustCompile(`\[\[([^\[\]|]+)(?:\|([^\[\]]+))?\]\]`)
+
+	// imageRegex and linkRegex match markdown images and links on HTML-escaped text
+	imageRegex = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+	linkRegex  = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+
+	codeRegex   = regexp.MustCompile("`([^`]+)`")
+	boldRegex   = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	italicRegex = regexp.MustCompile(`(^|[^*])\*([^*\s][^*]*)\*`)
+
+	// headingRegex matches a markdown heading at the start of a block
+	headingRegex = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+
+	// assetRegex matches references to files in the assets directory
+	assetRegex = regexp.MustCompile(`\./assets/([^)\s"'<>]+)`)
+
+	taskMarkerRegex = regexp.MustCompile(`\s*/(TODO|DONE)\b`)
+)
+
+// linkResolver returns the relative URL of the page with the given title,
+// or false if that page is not part of the export.
+type linkResolver func(title string) (string, bool)
+
+// renderInline renders a block's content as HTML. The content is escaped
+// first, then [[links]], markdown links and images, inline code and
+// emphasis are turned into markup. Links and images with URLs other than
+// those allowed by safeURL are rendered as their text.
+func renderInline(content string, resolve linkResolver) template.HTML {
+	escaped := html.EscapeString(content)
+
+	// Protect inline code from further formatting
+	var codes []string
+	escaped = codeRegex.ReplaceAllStringFunc(escaped, func(match string) string {
+		codes = append(codes, "<code>"+match[1:len(match)-1]+"</code>")
+		return fmt.Sprintf("\x00%d\x00", len(codes)-1)
+	})
+
+	escaped = imageRegex.ReplaceAllStringFunc(escaped, func(match string) string {
+		parts := imageRegex.FindStringSubmatch(match)
+		if !safeURL(parts[2]) {
+			return parts[1]
+		}
+		return fmt.Sprintf(`<img src="%s" alt="%s">`, parts[2], parts[1])
+	})
+	escaped = linkRegex.ReplaceAllStringFunc(escaped, func(match string) string {
+		parts := linkRegex.FindStringSubmatch(match)
+		if !safeURL(parts[2]) {
+			return parts[1]
+		}
+		return fmt.Sprintf(`<a href="%s">%s</a>`, parts[2], parts[1])
+	})
+	escaped = wikiLinkRegex.ReplaceAllStringFunc(escaped, func(match string) string {
+		parts := wikiLinkRegex.FindStringSubmatch(match)
+		title := html.UnescapeString(strings.TrimSpace(parts[1]))
+		label := parts[1]
+		if parts[2] != "" {
+			label = parts[2]
+		}
+		if url, ok := resolve(title); ok {
+			return fmt.Sprintf(`<a class="page-link" href="%s">%s</a>`, html.EscapeString(url), label)
+		}
+		return fmt.Sprintf(`<span class="page-link missing">%s</span>`, label)
+	})
+	escaped = boldRegex.ReplaceAllString(escaped, "<strong>$1</strong>")
+	escaped = italicRegex.ReplaceAllString(escaped, "$1<em>$2</em>")
+
+	for i, code := range codes {
+		escaped = strings.Replace(escaped, fmt.Sprintf("\x00%d\x00", i), code, 1)
+	}
+
+	escaped = strings.ReplaceAll(escaped, "\n", "<br>")
+	return template.HTML(escaped)
+}
+
+// safeURL reports whether an HTML-escaped link or image URL may be put in
+// an attribute: only http, https, mailto and relative URLs are allowed.
+func safeURL(escaped string) bool {
+	u, err := url.Parse(html.UnescapeString(escaped))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// renderedBlock is a block prepared for the page template.
+type renderedBlock struct {
+	HTML     template.HTML
+	Heading  int // markdown heading level, 0 for regular blocks
+	Done     bool
+	Todo     bool
+	Children []*renderedBlock
+}
+
+// renderOutline nests the flat block list by indent: a block becomes a
+// child of the closest preceding block with a smaller indent.
+func renderOutline(blocks []*domain.Block, resolve linkResolver) []*renderedBlock {
+	var roots []*renderedBlock
+	type frame struct {
+		indent int
+		block  *renderedBlock
+	}
+	var stack []frame
+
+	for _, block := range blocks {
+		if block == nil {
+			continue
+		}
+		for len(stack) > 0 && stack[len(stack)-1].indent >= block.Indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		rb := renderBlock(block.Content, resolve)
+		if len(stack) == 0 {
+			roots = append(roots, rb)
+		} else {
+			parent := stack[len(stack)-1].block
+			parent.Children = append(parent.Children, rb)
+		}
+		stack = append(stack, frame{indent: block.Indent, block: rb})
+	}
+
+	return roots
+}
+
+func renderBlock(content string, resolve linkResolver) *renderedBlock {
+	rb := &renderedBlock{}
+	for _, match := range taskMarkerRegex.FindAllStringSubmatch(content, -1) {
+		if match[1] == "DONE" {
+			rb.Done = true
+		} else {
+			rb.Todo = true
+		}
+	}
+	content = strings.TrimSpace(taskMarkerRegex.ReplaceAllString(content, ""))
+
+	if match := headingRegex.FindStringSubmatch(content); match != nil {
+		rb.Heading = len(match[1])
+		content = match[2]
+	}
+
+	rb.HTML = renderInline(content, resolve)
+	return rb
+}
+
+// plainText returns the searchable text of a document for the search index.
+func plainText(doc *domain.Document) string {
+	parts := make([]string, 0, len(doc.Blocks))
+	for _, block := range doc.Blocks {
+		if block == nil || strings.TrimSpace(block.Content) == "" {
+			continue
+		}
+		parts = append(parts, taskMarkerRegex.ReplaceAllString(block.Content, ""))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// referencedAssets returns the asset file names referenced by a document.
+func referencedAssets(doc *domain.Document) []string {
+	var assets []string
+	for _, block := range doc.Blocks {
+		if block == nil {
+			continue
+		}
+		for _, match := range assetRegex.FindAllStringSubmatch(block.Content, -1) {
+			assets = append(assets, match[1])
+		}
+	}
+	return assets
+}
+
+// slugify turns a title into a file name friendly slug.
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		slug = "page"
+	}
+	return slug
+}
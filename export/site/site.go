@@ -0,0 +1,325 @@
+// Package site exports glog documents as a static, read-only HTML site.
+//
+// Every exported document becomes one HTML page with its block outline,
+// [[links]] between exported pages resolve to relative URLs, and each page
+// gets a backlinks section built from the references index. The export
+// also writes an index page, a search-index.json file for client-side
+// search, and copies the assets referenced by the exported pages.
+package site
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"glog/db"
+	"glog/domain"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Options configures the site export.
+type Options struct {
+	OutDir     string // Directory the site is written to
+	AssetsDir  string // Directory holding glog assets (default: "assets")
+	SiteTitle  string // Title of the index page (default: "glog")
+	PublicOnly bool   // If true, only export pages with a "public:: true" property
+	Tag        string // If set, only export pages with this tag
+	Journals   bool   // If true, include journals; they are skipped by default
+}
+
+// Result contains the results of a site export.
+type Result struct {
+	Pages  int
+	Assets int
+	Errors []error
+}
+
+// SearchEntry is one entry of the generated search-index.json.
+type SearchEntry struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Date    string `json:"date"`
+	Content string `json:"content"`
+}
+
+// page is an exported document with its output file name.
+type page struct {
+	doc  *domain.Document
+	file string
+}
+
+// Include reports whether a document passes the export filters.
+func (opts Options) Include(doc *domain.Document) bool {
+	if doc.IsJournal && !opts.Journals {
+		return false
+	}
+	if opts.PublicOnly && !strings.EqualFold(db.PageProperties(doc)["public"], "true") {
+		return false
+	}
+	if opts.Tag != "" && !db.HasTag(doc, opts.Tag) {
+		return false
+	}
+	return true
+}
+
+// Store is the part of a DocumentStore the export reads from.
+type Store interface {
+	ForEachDocument(fn func(doc *domain.Document) error) error
+	GetReferences(title string) ([]domain.DocumentID, error)
+}
+
+// Export writes the static site for the documents in store that pass the
+// filters in opts.
+func Export(store Store, opts Options) (*Result, error) {
+	if opts.OutDir == "" {
+		return nil, errors.New("output directory is required")
+	}
+	if opts.AssetsDir == "" {
+		opts.AssetsDir = "assets"
+	}
+	if opts.SiteTitle == "" {
+		opts.SiteTitle = "glog"
+	}
+
+	var pages []*page
+	err := store.ForEachDocument(func(doc *domain.Document) error {
+		if opts.Include(doc) {
+			pages = append(pages, &page{doc: doc})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(pages, func(i, j int) bool {
+		return strings.ToLower(pages[i].doc.Title) < strings.ToLower(pages[j].doc.Title)
+	})
+
+	byTitle := make(map[string]*page, len(pages))
+	byID := make(map[domain.DocumentID]*page, len(pages))
+	usedFiles := make(map[string]struct{}, len(pages))
+	for _, p := range pages {
+		p.file = uniqueFileName(p.doc, usedFiles)
+		byTitle[strings.ToLower(p.doc.Title)] = p
+		byID[p.doc.ID] = p
+		for _, alias := range db.DocumentAliases(p.doc) {
+			if _, exists := byTitle[strings.ToLower(alias)]; !exists {
+				byTitle[strings.ToLower(alias)] = p
+			}
+		}
+	}
+
+	if err := os.MkdirAll(opts.OutDir, 0755); err != nil {
+		return nil, err
+	}
+
+	resolve := func(title string) (string, bool) {
+		p, ok := byTitle[strings.ToLower(title)]
+		if !ok {
+			return "", false
+		}
+		return p.file, true
+	}
+
+	result := &Result{}
+	assets := make(map[string]struct{})
+	search := make([]SearchEntry, 0, len(pages))
+
+	for _, p := range pages {
+		backlinks, err := backlinksFor(store, p.doc, byID)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("%s: backlinks: %w", p.doc.Title, err))
+		}
+
+		if err := writePage(opts, p, backlinks, resolve); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("%s: %w", p.doc.Title, err))
+			continue
+		}
+		result.Pages++
+
+		for _, asset := range referencedAssets(p.doc) {
+			assets[asset] = struct{}{}
+		}
+
+		search = append(search, SearchEntry{
+			Title:   p.doc.Title,
+			URL:     p.file,
+			Date:    p.doc.Date.Format(time.RFC3339),
+			Content: plainText(p.doc),
+		})
+	}
+
+	if err := writeJSON(filepath.Join(opts.OutDir, "search-index.json"), search); err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("search index: %w", err))
+	}
+
+	if err := writeIndex(opts, pages); err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("index page: %w", err))
+	}
+
+	for _, asset := range sortedSet(assets) {
+		if err := copyAsset(opts, asset); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("asset %s: %w", asset, err))
+			continue
+		}
+		result.Assets++
+	}
+
+	return result, nil
+}
+
+// uniqueFileName picks the output file name of a document. Journals are
+// named after their date, pages after their title.
+func uniqueFileName(doc *domain.Document, used map[string]struct{}) string {
+	base := slugify(doc.Title)
+	if doc.IsJournal {
+		base = doc.Date.UTC().Format("2006-01-02")
+	}
+	if base == "index" || base == "search-index" {
+		base += "-page"
+	}
+
+	name := base + ".html"
+	for suffix := 2; ; suffix++ {
+		if _, taken := used[name]; !taken {
+			used[name] = struct{}{}
+			return name
+		}
+		name = fmt.Sprintf("%s-%d.html", base, suffix)
+	}
+}
+
+// backlink is a page linking to the page being rendered.
+type backlink struct {
+	Title string
+	URL   string
+}
+
+// backlinksFor returns the exported pages that reference doc by its title.
+// Pages that are not part of the export are left out.
+func backlinksFor(store Store, doc *domain.Document, byID map[domain.DocumentID]*page) ([]backlink, error) {
+	ids, err := store.GetReferences(doc.Title)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []backlink
+	for _, id := range ids {
+		p, ok := byID[id]
+		if !ok || id == doc.ID {
+			continue
+		}
+		links = append(links, backlink{Title: p.doc.Title, URL: p.file})
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		return strings.ToLower(links[i].Title) < strings.ToLower(links[j].Title)
+	})
+	return links, nil
+}
+
+func writePage(opts Options, p *page, backlinks []backlink, resolve linkResolver) error {
+	f, err := os.Create(filepath.Join(opts.OutDir, p.file))
+	if err != nil {
+		return err
+	}
+
+	err = pageTemplate.Execute(f, struct {
+		SiteTitle string
+		Title     string
+		Date      string
+		Blocks    []*renderedBlock
+		Backlinks []backlink
+	}{
+		SiteTitle: opts.SiteTitle,
+		Title:     p.doc.Title,
+		Date:      p.doc.Date.Format("January 2, 2006"),
+		Blocks:    renderOutline(p.doc.Blocks, resolve),
+		Backlinks: backlinks,
+	})
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeIndex(opts Options, pages []*page) error {
+	f, err := os.Create(filepath.Join(opts.OutDir, "index.html"))
+	if err != nil {
+		return err
+	}
+
+	links := make([]backlink, len(pages))
+	for i, p := range pages {
+		links[i] = backlink{Title: p.doc.Title, URL: p.file}
+	}
+
+	err = indexTemplate.Execute(f, struct {
+		SiteTitle string
+		Pages     []backlink
+	}{
+		SiteTitle: opts.SiteTitle,
+		Pages:     links,
+	})
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// copyAsset copies a referenced asset into the site's assets directory.
+// References that would escape the assets directory are rejected.
+func copyAsset(opts Options, name string) error {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("invalid asset path")
+	}
+
+	src, err := os.Open(filepath.Join(opts.AssetsDir, clean))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("referenced asset not found")
+		}
+		return err
+	}
+	defer src.Close()
+
+	dstPath := filepath.Join(opts.OutDir, "assets", clean)
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return err
+	}
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+func sortedSet(set map[string]struct{}) []string {
+	items := make([]string, 0, len(set))
+	for item := range set {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	return items
+}
@@ -0,0 +1,164 @@
+package site
+
+import (
+	"encoding/json"
+	"glog/db"
+	"glog/domain"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func saveDoc(t *testing.T, store *db.DocumentStore, title string, contents ...string) *domain.Document {
+	t.Helper()
+	doc := &domain.Document{
+		ID:    domain.DocumentID(uuid.New()),
+		Title: title,
+		Date:  time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+	}
+	for i, content := range contents {
+		indent := 0
+		if i > 0 && strings.HasPrefix(content, "  ") {
+			indent = 1
+		}
+		doc.Blocks = append(doc.Blocks, &domain.Block{
+			ID:      domain.BlockID(uuid.New()),
+			Content: strings.TrimSpace(content),
+			Indent:  indent,
+		})
+	}
+	if err := store.Save(doc); err != nil {
+		t.Fatalf("Failed to save %s: %v", title, err)
+	}
+	return doc
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	store, err := db.NewDocumentStore(filepath.Join(dir, "glog.db"))
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer store.Close()
+
+	assetsDir := filepath.Join(dir, "assets")
+	if err := os.MkdirAll(assetsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(assetsDir, "diagram.png"), []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	saveDoc(t, store, "Handbook", "public:: true", "See [[Oncall]] and [[Secret Plans]]", "  ![diagram](./assets/diagram.png) <script>")
+	saveDoc(t, store, "Oncall", "public:: true", "Back to [[Handbook]]")
+	saveDoc(t, store, "Secret Plans", "Links to [[Handbook]]")
+
+	outDir := filepath.Join(dir, "site")
+	result, err := Export(store, Options{OutDir: outDir, AssetsDir: assetsDir, PublicOnly: true})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("Export() errors = %v", result.Errors)
+	}
+	if result.Pages != 2 || result.Assets != 1 {
+		t.Errorf("exported %d pages and %d assets, want 2 and 1", result.Pages, result.Assets)
+	}
+
+	handbook, err := os.ReadFile(filepath.Join(outDir, "handbook.html"))
+	if err != nil {
+		t.Fatalf("handbook page not written: %v", err)
+	}
+	html := string(handbook)
+	if !strings.Contains(html, `<a class="page-link" href="oncall.html">Oncall</a>`) {
+		t.Error("Expected link to exported page to resolve to a relative URL")
+	}
+	if !strings.Contains(html, `<span class="page-link missing">Secret Plans</span>`) {
+		t.Error("Expected link to unexported page to be rendered as plain text")
+	}
+	if strings.Contains(html, "<script>") && !strings.Contains(html, "&lt;script&gt;") {
+		t.Error("Expected block content to be escaped")
+	}
+	if !strings.Contains(html, `<a href="oncall.html">Oncall</a>`) {
+		t.Error("Expected backlink from Oncall")
+	}
+	if strings.Contains(html, "secret-plans.html") {
+		t.Error("Expected backlinks from unexported pages to be left out")
+	}
+
+	if _, err := os.Stat(filepath.Join(outDir, "assets", "diagram.png")); err != nil {
+		t.Errorf("asset not copied: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "search-index.json"))
+	if err != nil {
+		t.Fatalf("search index not written: %v", err)
+	}
+	var entries []SearchEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("invalid search index: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Title != "Handbook" || entries[0].URL != "handbook.html" {
+		t.Errorf("unexpected search index: %+v", entries)
+	}
+}
+
+func TestOptionsInclude(t *testing.T) {
+	tagged := &domain.Document{Title: "A", Blocks: []*domain.Block{{Content: "notes #publish"}}}
+	journal := &domain.Document{Title: "Monday, January 15, 2024", IsJournal: true, Blocks: []*domain.Block{{Content: "#publish"}}}
+
+	opts := Options{Tag: "publish"}
+	if !opts.Include(tagged) {
+		t.Error("Expected tagged page to be included")
+	}
+	if opts.Include(journal) {
+		t.Error("Expected journals to be excluded unless requested")
+	}
+	opts.Journals = true
+	if !opts.Include(journal) {
+		t.Error("Expected journal to be included when requested")
+	}
+	if (Options{Tag: "other"}).Include(tagged) {
+		t.Error("Expected page without the tag to be excluded")
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Project X":         "project-x",
+		"  Ünïcode & More ": "ünïcode-more",
+		"???":               "page",
+	}
+	for input, want := range tests {
+		if got := slugify(input); got != want {
+			t.Errorf("slugify(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestRenderInlineURLs(t *testing.T) {
+	none := func(string) (string, bool) { return "", false }
+	tests := map[string]string{
+		"[site](https://example.com/a?b=1&c=2)": `<a href="https://example.com/a?b=1&amp;c=2">site</a>`,
+		"[mail](mailto:me@example.com)":         `<a href="mailto:me@example.com">mail</a>`,
+		"[up](../other.html#top)":               `<a href="../other.html#top">up</a>`,
+		"![diagram](./assets/diagram.png)":      `<img src="./assets/diagram.png" alt="diagram">`,
+		"![remote](http://example.com/x.png)":   `<img src="http://example.com/x.png" alt="remote">`,
+		"[x](javascript:void0)":                 "x",
+		"[x](JavaScript:void0)":                 "x",
+		"[x](vbscript:msgbox)":                  "x",
+		"[x](data:text/html,hi)":                "x",
+		"![x](javascript:void0)":                "x",
+		"![x](data:image/svg+xml,hi)":           "x",
+		"![x](file:///etc/passwd)":              "x",
+	}
+	for input, want := range tests {
+		if got := string(renderInline(input, none)); got != want {
+			t.Errorf("renderInline(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
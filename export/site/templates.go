@@ -0,0 +1,92 @@
+package site
+
+import "html/template"
+
+const styles = `
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 52rem; margin: 2rem auto; padding: 0 1rem; color: #1b2636; line-height: 1.5; }
+a { color: #2f6fb2; }
+header a { text-decoration: none; color: inherit; }
+ul.outline { padding-left: 1.25rem; }
+li.done > .block { text-decoration: line-through; opacity: 0.6; }
+.page-link.missing { color: #888; }
+.todo::before { content: "☐ "; }
+.done-marker::before { content: "☑ "; }
+.date { color: #666; font-size: 0.9rem; }
+section.backlinks { border-top: 1px solid #ddd; margin-top: 2rem; }
+img { max-width: 100%; }
+#results li { margin: 0.25rem 0; }
+`
+
+var funcs = template.FuncMap{
+	"styles": func() template.CSS { return template.CSS(styles) },
+}
+
+var pageTemplate = template.Must(template.New("page").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} - {{.SiteTitle}}</title>
+<style>{{styles}}</style>
+</head>
+<body>
+<header><a href="index.html">{{.SiteTitle}}</a></header>
+<h1>{{.Title}}</h1>
+<div class="date">{{.Date}}</div>
+{{template "outline" .Blocks}}
+{{if .Backlinks}}
+<section class="backlinks">
+<h2>Linked references</h2>
+<ul>
+{{range .Backlinks}}<li><a href="{{.URL}}">{{.Title}}</a></li>
+{{end}}</ul>
+</section>
+{{end}}
+</body>
+</html>
+{{define "outline"}}{{if .}}<ul class="outline">
+{{range .}}<li{{if .Done}} class="done"{{end}}><div class="block{{if .Todo}} todo{{end}}{{if .Done}} done-marker{{end}}">{{if .Heading}}<strong>{{.HTML}}</strong>{{else}}{{.HTML}}{{end}}</div>{{template "outline" .Children}}</li>
+{{end}}</ul>{{end}}{{end}}
+`))
+
+var indexTemplate = template.Must(template.New("index").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.SiteTitle}}</title>
+<style>{{styles}}</style>
+</head>
+<body>
+<h1>{{.SiteTitle}}</h1>
+<input id="search" type="search" placeholder="Search pages..." autofocus>
+<ul id="results"></ul>
+<h2>All pages</h2>
+<ul>
+{{range .Pages}}<li><a href="{{.URL}}">{{.Title}}</a></li>
+{{end}}</ul>
+<script>
+(function () {
+  var input = document.getElementById("search");
+  var results = document.getElementById("results");
+  var entries = [];
+  fetch("search-index.json").then(function (r) { return r.json(); }).then(function (data) { entries = data; });
+  input.addEventListener("input", function () {
+    var terms = input.value.toLowerCase().split(/\s+/).filter(Boolean);
+    results.innerHTML = "";
+    if (terms.length === 0) return;
+    entries.filter(function (e) {
+      var text = (e.title + "\n" + e.content).toLowerCase();
+      return terms.every(function (t) { return text.indexOf(t) !== -1; });
+    }).slice(0, 50).forEach(function (e) {
+      var li = document.createElement("li");
+      var a = document.createElement("a");
+      a.href = e.url;
+      a.textContent = e.title;
+      li.appendChild(a);
+      results.appendChild(li);
+    });
+  });
+})();
+</script>
+</body>
+</html>
+`))
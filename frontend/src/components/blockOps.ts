@@ -0,0 +1,49 @@
+import type { api } from '../../wailsjs/go/models';
+
+export type BlockSnapshot = { id: string; content: string; indent: number };
+
+// snapshot copies the blocks as they were saved, to diff later edits against.
+export function snapshot(blocks: api.BlockDto[]): BlockSnapshot[] {
+    return blocks.map(b => ({ id: b.id, content: b.content, indent: b.indent }));
+}
+
+// diffBlocks returns the ApplyOps operations that turn the saved blocks
+// into the current ones: deletes first, then one pass in document order
+// inserting, moving, updating and indenting blocks.
+export function diffBlocks(saved: BlockSnapshot[], current: api.BlockDto[]): api.BlockOpDto[] {
+    const ops: api.BlockOpDto[] = [];
+    const currentIds = new Set(current.map(b => b.id));
+
+    const working = saved.filter(b => {
+        if (!currentIds.has(b.id)) {
+            ops.push({ type: 'delete', block_id: b.id });
+            return false;
+        }
+        return true;
+    });
+
+    let after = '';
+    current.forEach((block, i) => {
+        const index = working.findIndex(b => b.id === block.id);
+        if (index === -1) {
+            ops.push({ type: 'insert', block_id: block.id, after, content: block.content, indent: block.indent });
+            working.splice(i, 0, { id: block.id, content: block.content, indent: block.indent });
+        } else {
+            const old = working[index];
+            if (index !== i) {
+                ops.push({ type: 'move', block_id: block.id, after });
+                working.splice(index, 1);
+                working.splice(i, 0, old);
+            }
+            if (old.content !== block.content) {
+                ops.push({ type: 'update', block_id: block.id, content: block.content });
+            }
+            if (old.indent !== block.indent) {
+                ops.push({ type: 'indent', block_id: block.id, indent: block.indent });
+            }
+        }
+        after = block.id;
+    });
+
+    return ops;
+}
@@ -3,5 +3,8 @@ export function replaceLinks(body: string): string {
         // URL-encode the title to handle slashes and other special characters
         const encodedTitle = encodeURIComponent(p1);
         return `<a href="#/doc-title/${encodedTitle}">${p1}</a>`;
+    }).replace(/\(\(([0-9a-f-]{36})\)\)/g, (match, id) => {
+        // Block references left by moves open the document holding the block
+        return `<a class="block-ref" href="#/block/${id}">((${id.slice(0, 8)}))</a>`;
     });
 }
\ No newline at end of file
@@ -1,33 +1,92 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {api} from '../models';
 import {main} from '../models';
 
+export function ApplyOps(arg1:string,arg2:number,arg3:Array<api.BlockOpDto>):Promise<api.DocumentDto>;
+
+export function CopyBlocks(arg1:string,arg2:string,arg3:string,arg4:number,arg5:number):Promise<api.MoveBlocksDto>;
+
 export function DeleteDocument(arg1:string):Promise<void>;
 
-export function GetDocumentList():Promise<Array<main.DocumentSummaryDto>>;
+export function DeleteSavedSearch(arg1:string):Promise<void>;
+
+export function ExportOPML(arg1:string):Promise<string>;
+
+export function ExtractToPage(arg1:string,arg2:string,arg3:string):Promise<api.MoveBlocksDto>;
+
+export function GetAPIServer():Promise<api.APIServerDto>;
+
+export function GetChanges(arg1:number,arg2:number):Promise<Array<api.ChangeEventDto>>;
+
+export function GetDocumentList():Promise<Array<api.DocumentSummaryDto>>;
+
+export function GetHookFailures():Promise<Array<main.HookFailureDto>>;
+
+export function GetIndexHealth():Promise<api.IndexHealthDto>;
+
+export function GetRecentDocuments(arg1:number):Promise<Array<api.DocumentSummaryDto>>;
+
+export function GetReferences(arg1:string):Promise<Array<api.DocumentReferenceDto>>;
+
+export function GetRelatedDocuments(arg1:string,arg2:number):Promise<Array<api.RelatedDocumentDto>>;
+
+export function GetSavedSearch(arg1:string):Promise<api.SavedSearchDto>;
+
+export function GetScheduledTasks():Promise<Array<api.ScheduledTaskDto>>;
+
+export function GetSearchAnalysis():Promise<api.SearchAnalysisDto>;
 
-export function GetIndexHealth():Promise<main.IndexHealthDto>;
+export function GetSearchLanguages():Promise<Array<string>>;
 
-export function GetRecentDocuments(arg1:number):Promise<Array<main.DocumentSummaryDto>>;
+export function GetUnlinkedReferences(arg1:string):Promise<Array<api.UnlinkedReferenceDto>>;
 
-export function GetReferences(arg1:string):Promise<Array<main.DocumentReferenceDto>>;
+export function ImportOPML(arg1:string):Promise<api.DocumentDto>;
 
-export function GetScheduledTasks():Promise<Array<main.ScheduledTaskDto>>;
+export function LinkReference(arg1:string,arg2:string,arg3:string):Promise<api.DocumentDto>;
 
-export function LoadJournalToday():Promise<main.DocumentDto>;
+export function ListSavedSearches():Promise<Array<api.SavedSearchDto>>;
 
-export function LoadJournals(arg1:string,arg2:string):Promise<Array<main.DocumentDto>>;
+export function LoadJournalToday():Promise<api.DocumentDto>;
 
-export function OpenDocument(arg1:string):Promise<main.DocumentDto>;
+export function LoadJournals(arg1:string,arg2:string):Promise<Array<api.DocumentDto>>;
 
-export function OpenDocumentByTitle(arg1:string):Promise<main.DocumentDto>;
+export function MergeDocuments(arg1:string,arg2:string):Promise<api.MergePlanDto>;
+
+export function MoveBlocks(arg1:string,arg2:string,arg3:string,arg4:number,arg5:number,arg6:boolean):Promise<api.MoveBlocksDto>;
+
+export function OpenBlockDocument(arg1:string):Promise<api.DocumentDto>;
+
+export function OpenDocument(arg1:string):Promise<api.DocumentDto>;
+
+export function OpenDocumentByTitle(arg1:string):Promise<api.DocumentDto>;
+
+export function PreviewMerge(arg1:string,arg2:string):Promise<api.MergePlanDto>;
 
 export function ReindexSearch():Promise<void>;
 
 export function RetryFailedIndexing():Promise<number>;
 
+export function RunSavedSearch(arg1:string,arg2:number,arg3:number,arg4:string):Promise<api.SearchResultsDto>;
+
 export function SaveAsset(arg1:string):Promise<string>;
 
-export function SaveDocument(arg1:main.DocumentDto):Promise<void>;
+export function SaveDocument(arg1:api.DocumentDto):Promise<api.DocumentDto>;
+
+export function SaveSearch(arg1:api.SavedSearchDto):Promise<api.SavedSearchDto>;
+
+export function Search(arg1:string,arg2:number,arg3:number,arg4:string):Promise<api.SearchResultsDto>;
+
+export function SearchBlocks(arg1:string,arg2:number):Promise<Array<api.BlockSearchHitDto>>;
+
+export function SearchDocuments(arg1:string):Promise<Array<api.DocumentSummaryDto>>;
+
+export function SetSearchAnalysis(arg1:api.SearchAnalysisDto):Promise<void>;
+
+export function StartAPIServer(arg1:string,arg2:string):Promise<api.APIServerDto>;
+
+export function StopAPIServer():Promise<void>;
+
+export function SuggestTitles(arg1:string,arg2:number):Promise<Array<api.TitleSuggestionDto>>;
 
-export function SearchDocuments(arg1:string):Promise<Array<main.DocumentSummaryDto>>;
+export function VerifySearchIndex():Promise<api.IndexVerificationDto>;
@@ -2,14 +2,46 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function ApplyOps(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ApplyOps'](arg1, arg2, arg3);
+}
+
+export function CopyBlocks(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['CopyBlocks'](arg1, arg2, arg3, arg4, arg5);
+}
+
 export function DeleteDocument(arg1) {
   return window['go']['main']['App']['DeleteDocument'](arg1);
 }
 
+export function DeleteSavedSearch(arg1) {
+  return window['go']['main']['App']['DeleteSavedSearch'](arg1);
+}
+
+export function ExportOPML(arg1) {
+  return window['go']['main']['App']['ExportOPML'](arg1);
+}
+
+export function ExtractToPage(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ExtractToPage'](arg1, arg2, arg3);
+}
+
+export function GetAPIServer() {
+  return window['go']['main']['App']['GetAPIServer']();
+}
+
+export function GetChanges(arg1, arg2) {
+  return window['go']['main']['App']['GetChanges'](arg1, arg2);
+}
+
 export function GetDocumentList() {
   return window['go']['main']['App']['GetDocumentList']();
 }
 
+export function GetHookFailures() {
+  return window['go']['main']['App']['GetHookFailures']();
+}
+
 export function GetIndexHealth() {
   return window['go']['main']['App']['GetIndexHealth']();
 }
@@ -22,10 +54,42 @@ export function GetReferences(arg1) {
   return window['go']['main']['App']['GetReferences'](arg1);
 }
 
+export function GetRelatedDocuments(arg1, arg2) {
+  return window['go']['main']['App']['GetRelatedDocuments'](arg1, arg2);
+}
+
+export function GetSavedSearch(arg1) {
+  return window['go']['main']['App']['GetSavedSearch'](arg1);
+}
+
 export function GetScheduledTasks() {
   return window['go']['main']['App']['GetScheduledTasks']();
 }
 
+export function GetSearchAnalysis() {
+  return window['go']['main']['App']['GetSearchAnalysis']();
+}
+
+export function GetSearchLanguages() {
+  return window['go']['main']['App']['GetSearchLanguages']();
+}
+
+export function GetUnlinkedReferences(arg1) {
+  return window['go']['main']['App']['GetUnlinkedReferences'](arg1);
+}
+
+export function ImportOPML(arg1) {
+  return window['go']['main']['App']['ImportOPML'](arg1);
+}
+
+export function LinkReference(arg1, arg2, arg3) {
+  return window['go']['main']['App']['LinkReference'](arg1, arg2, arg3);
+}
+
+export function ListSavedSearches() {
+  return window['go']['main']['App']['ListSavedSearches']();
+}
+
 export function LoadJournalToday() {
   return window['go']['main']['App']['LoadJournalToday']();
 }
@@ -34,6 +98,18 @@ export function LoadJournals(arg1, arg2) {
   return window['go']['main']['App']['LoadJournals'](arg1, arg2);
 }
 
+export function MergeDocuments(arg1, arg2) {
+  return window['go']['main']['App']['MergeDocuments'](arg1, arg2);
+}
+
+export function MoveBlocks(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['MoveBlocks'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function OpenBlockDocument(arg1) {
+  return window['go']['main']['App']['OpenBlockDocument'](arg1);
+}
+
 export function OpenDocument(arg1) {
   return window['go']['main']['App']['OpenDocument'](arg1);
 }
@@ -42,6 +118,10 @@ export function OpenDocumentByTitle(arg1) {
   return window['go']['main']['App']['OpenDocumentByTitle'](arg1);
 }
 
+export function PreviewMerge(arg1, arg2) {
+  return window['go']['main']['App']['PreviewMerge'](arg1, arg2);
+}
+
 export function ReindexSearch() {
   return window['go']['main']['App']['ReindexSearch']();
 }
@@ -50,6 +130,10 @@ export function RetryFailedIndexing() {
   return window['go']['main']['App']['RetryFailedIndexing']();
 }
 
+export function RunSavedSearch(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['RunSavedSearch'](arg1, arg2, arg3, arg4);
+}
+
 export function SaveAsset(arg1) {
   return window['go']['main']['App']['SaveAsset'](arg1);
 }
@@ -58,6 +142,38 @@ export function SaveDocument(arg1) {
   return window['go']['main']['App']['SaveDocument'](arg1);
 }
 
+export function SaveSearch(arg1) {
+  return window['go']['main']['App']['SaveSearch'](arg1);
+}
+
+export function Search(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['Search'](arg1, arg2, arg3, arg4);
+}
+
+export function SearchBlocks(arg1, arg2) {
+  return window['go']['main']['App']['SearchBlocks'](arg1, arg2);
+}
+
 export function SearchDocuments(arg1) {
   return window['go']['main']['App']['SearchDocuments'](arg1);
 }
+
+export function SetSearchAnalysis(arg1) {
+  return window['go']['main']['App']['SetSearchAnalysis'](arg1);
+}
+
+export function StartAPIServer(arg1, arg2) {
+  return window['go']['main']['App']['StartAPIServer'](arg1, arg2);
+}
+
+export function StopAPIServer() {
+  return window['go']['main']['App']['StopAPIServer']();
+}
+
+export function SuggestTitles(arg1, arg2) {
+  return window['go']['main']['App']['SuggestTitles'](arg1, arg2);
+}
+
+export function VerifySearchIndex() {
+  return window['go']['main']['App']['VerifySearchIndex']();
+}
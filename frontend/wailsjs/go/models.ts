@@ -1,5 +1,19 @@
-export namespace main {
+export namespace api {
+	
+	export class APIServerDto {
+	    url: string;
+	    token: string;
 	
+	    static createFrom(source: any = {}) {
+	        return new APIServerDto(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.token = source["token"];
+	    }
+	}
 	export class BlockDto {
 	    id: string;
 	    content: string;
@@ -16,6 +30,26 @@ export namespace main {
 	        this.indent = source["indent"];
 	    }
 	}
+	export class BlockOpDto {
+	    type: string;
+	    block_id: string;
+	    after?: string;
+	    content?: string;
+	    indent?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BlockOpDto(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.block_id = source["block_id"];
+	        this.after = source["after"];
+	        this.content = source["content"];
+	        this.indent = source["indent"];
+	    }
+	}
 	export class BlockReferenceDto {
 	    Id: string;
 	    Content: string;
@@ -32,12 +66,69 @@ export namespace main {
 	        this.Indent = source["Indent"];
 	    }
 	}
+	export class BlockSearchHitDto {
+	    doc_id: string;
+	    title: string;
+	    block_id: string;
+	    content: string;
+	    breadcrumb: string[];
+	    fragments: string[];
+	    score: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BlockSearchHitDto(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.doc_id = source["doc_id"];
+	        this.title = source["title"];
+	        this.block_id = source["block_id"];
+	        this.content = source["content"];
+	        this.breadcrumb = source["breadcrumb"];
+	        this.fragments = source["fragments"];
+	        this.score = source["score"];
+	    }
+	}
+	export class ChangeEventDto {
+	    seq: number;
+	    type: string;
+	    time: string;
+	    doc_id: string;
+	    title: string;
+	    is_journal: boolean;
+	    block_id?: string;
+	    date?: string;
+	    error?: string;
+	    search_id?: string;
+	    search?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ChangeEventDto(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.seq = source["seq"];
+	        this.type = source["type"];
+	        this.time = source["time"];
+	        this.doc_id = source["doc_id"];
+	        this.title = source["title"];
+	        this.is_journal = source["is_journal"];
+	        this.block_id = source["block_id"];
+	        this.date = source["date"];
+	        this.error = source["error"];
+	        this.search_id = source["search_id"];
+	        this.search = source["search"];
+	    }
+	}
 	export class DocumentDto {
 	    id: string;
 	    title: string;
 	    blocks: BlockDto[];
 	    date: string;
 	    is_journal: boolean;
+	    revision: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new DocumentDto(source);
@@ -50,6 +141,7 @@ export namespace main {
 	        this.blocks = this.convertValues(source["blocks"], BlockDto);
 	        this.date = source["date"];
 	        this.is_journal = source["is_journal"];
+	        this.revision = source["revision"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -126,6 +218,8 @@ export namespace main {
 	    lastHealthCheck: string;
 	    requiresReindex: boolean;
 	    healthCheckMessage: string;
+	    queueDepth: number;
+	    queueLagMs: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new IndexHealthDto(source);
@@ -138,6 +232,148 @@ export namespace main {
 	        this.lastHealthCheck = source["lastHealthCheck"];
 	        this.requiresReindex = source["requiresReindex"];
 	        this.healthCheckMessage = source["healthCheckMessage"];
+	        this.queueDepth = source["queueDepth"];
+	        this.queueLagMs = source["queueLagMs"];
+	    }
+	}
+	export class IndexVerificationDto {
+	    documents: number;
+	    entries: number;
+	    missing: string[];
+	    stale: string[];
+	    orphaned: string[];
+	    repaired: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new IndexVerificationDto(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.documents = source["documents"];
+	        this.entries = source["entries"];
+	        this.missing = source["missing"];
+	        this.stale = source["stale"];
+	        this.orphaned = source["orphaned"];
+	        this.repaired = source["repaired"];
+	    }
+	}
+	export class MergePlanDto {
+	    keep: DocumentSummaryDto;
+	    merge: DocumentSummaryDto;
+	    blocks: number;
+	    relinked: DocumentSummaryDto[];
+	    documents: DocumentSummaryDto[];
+	    dry_run: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new MergePlanDto(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.keep = this.convertValues(source["keep"], DocumentSummaryDto);
+	        this.merge = this.convertValues(source["merge"], DocumentSummaryDto);
+	        this.blocks = source["blocks"];
+	        this.relinked = this.convertValues(source["relinked"], DocumentSummaryDto);
+	        this.documents = this.convertValues(source["documents"], DocumentSummaryDto);
+	        this.dry_run = source["dry_run"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MoveBlocksDto {
+	    source: DocumentDto;
+	    destination: DocumentDto;
+	
+	    static createFrom(source: any = {}) {
+	        return new MoveBlocksDto(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.source = this.convertValues(source["source"], DocumentDto);
+	        this.destination = this.convertValues(source["destination"], DocumentDto);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RelatedDocumentDto {
+	    id: string;
+	    title: string;
+	    date: string;
+	    score: number;
+	    reason: string;
+	    shared_links: string[];
+	    shared_tags: string[];
+	    similarity: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RelatedDocumentDto(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.title = source["title"];
+	        this.date = source["date"];
+	        this.score = source["score"];
+	        this.reason = source["reason"];
+	        this.shared_links = source["shared_links"];
+	        this.shared_tags = source["shared_tags"];
+	        this.similarity = source["similarity"];
+	    }
+	}
+	export class SavedSearchDto {
+	    id: string;
+	    name: string;
+	    query: string;
+	    notify: boolean;
+	    created?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SavedSearchDto(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.query = source["query"];
+	        this.notify = source["notify"];
+	        this.created = source["created"];
 	    }
 	}
 	export class ScheduledTaskDto {
@@ -162,6 +398,127 @@ export namespace main {
 	        this.doc_id = source["doc_id"];
 	    }
 	}
+	export class SearchAnalysisDto {
+	    languages: string[];
+	    foldAccents: boolean;
+	    cjk: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchAnalysisDto(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.languages = source["languages"];
+	        this.foldAccents = source["foldAccents"];
+	        this.cjk = source["cjk"];
+	    }
+	}
+	export class SearchResultsDto {
+	    total: number;
+	    offset: number;
+	    hits: DocumentSummaryDto[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchResultsDto(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.total = source["total"];
+	        this.offset = source["offset"];
+	        this.hits = this.convertValues(source["hits"], DocumentSummaryDto);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TitleSuggestionDto {
+	    id: string;
+	    title: string;
+	    backlinks: number;
+	    fuzzy: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TitleSuggestionDto(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.title = source["title"];
+	        this.backlinks = source["backlinks"];
+	        this.fuzzy = source["fuzzy"];
+	    }
+	}
+	export class UnlinkedReferenceDto {
+	    doc_id: string;
+	    title: string;
+	    block_id: string;
+	    content: string;
+	    breadcrumb: string[];
+	    mention: string;
+	    fragments: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new UnlinkedReferenceDto(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.doc_id = source["doc_id"];
+	        this.title = source["title"];
+	        this.block_id = source["block_id"];
+	        this.content = source["content"];
+	        this.breadcrumb = source["breadcrumb"];
+	        this.mention = source["mention"];
+	        this.fragments = source["fragments"];
+	    }
+	}
+
+}
+
+export namespace main {
+	
+	export class HookFailureDto {
+	    hook: string;
+	    event: string;
+	    doc_id: string;
+	    title: string;
+	    time: string;
+	    attempts: number;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new HookFailureDto(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hook = source["hook"];
+	        this.event = source["event"];
+	        this.doc_id = source["doc_id"];
+	        this.title = source["title"];
+	        this.time = source["time"];
+	        this.attempts = source["attempts"];
+	        this.error = source["error"];
+	    }
+	}
 
 }
 
@@ -0,0 +1,145 @@
+// Package hooks runs user-configured actions after documents are saved
+// or deleted: a local executable receiving the document JSON on stdin, or
+// an HTTP POST of the same JSON to a localhost URL.
+//
+// Hooks are configured in a JSON file next to the database (see
+// ConfigPath):
+//
+//	{
+//	  "hooks": [
+//	    {"name": "git backup", "command": "/home/me/bin/backup-note", "on": ["save"], "tag": "work"},
+//	    {"name": "webhook", "url": "http://127.0.0.1:9000/glog", "journal_only": true, "retries": 3}
+//	  ]
+//	}
+package hooks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"time"
+)
+
+const (
+	// DefaultTimeout bounds a single attempt of a hook.
+	DefaultTimeout = 10 * time.Second
+	// DefaultRetries is the number of attempts after the first one.
+	DefaultRetries = 2
+)
+
+// Event names used in Hook.On and in the payload.
+const (
+	EventSave   = "save"
+	EventDelete = "delete"
+)
+
+// Hook is one configured action.
+type Hook struct {
+	Name    string   `json:"name"`
+	Command string   `json:"command,omitempty"` // executable, receives the payload on stdin
+	Args    []string `json:"args,omitempty"`
+	URL     string   `json:"url,omitempty"` // localhost URL the payload is POSTed to
+
+	On           []string `json:"on,omitempty"`            // "save", "delete"; empty for both
+	JournalOnly  bool     `json:"journal_only,omitempty"`  // only journals
+	TitlePattern string   `json:"title_pattern,omitempty"` // regular expression the title must match
+	Tag          string   `json:"tag,omitempty"`           // only documents with this tag
+
+	TimeoutSeconds int  `json:"timeout_seconds,omitempty"` // default DefaultTimeout
+	Retries        *int `json:"retries,omitempty"`         // default DefaultRetries
+
+	titleRegex *regexp.Regexp
+}
+
+// Config is the content of the hooks file.
+type Config struct {
+	Hooks []Hook `json:"hooks"`
+}
+
+// ConfigPath returns the hooks file of the database at dbPath.
+func ConfigPath(dbPath string) string {
+	return dbPath + ".hooks.json"
+}
+
+// LoadConfig reads and validates the hooks file at path. A missing file
+// means no hooks.
+func LoadConfig(path string) ([]Hook, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid hooks file %s: %w", path, err)
+	}
+
+	for i := range cfg.Hooks {
+		if err := cfg.Hooks[i].validate(); err != nil {
+			return nil, fmt.Errorf("hook %d (%s): %w", i+1, cfg.Hooks[i].Name, err)
+		}
+	}
+	return cfg.Hooks, nil
+}
+
+func (h *Hook) validate() error {
+	if (h.Command == "") == (h.URL == "") {
+		return errors.New("exactly one of command and url is required")
+	}
+
+	if h.URL != "" {
+		u, err := url.Parse(h.URL)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("unsupported url scheme %q", u.Scheme)
+		}
+		if ip := net.ParseIP(u.Hostname()); u.Hostname() != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return fmt.Errorf("url must point to localhost, got %s", u.Host)
+		}
+	}
+
+	for _, on := range h.On {
+		if on != EventSave && on != EventDelete {
+			return fmt.Errorf("unknown event %q (expected save or delete)", on)
+		}
+	}
+
+	if h.TitlePattern != "" {
+		re, err := regexp.Compile(h.TitlePattern)
+		if err != nil {
+			return fmt.Errorf("invalid title_pattern: %w", err)
+		}
+		h.titleRegex = re
+	}
+
+	if h.TimeoutSeconds < 0 {
+		return errors.New("timeout_seconds cannot be negative")
+	}
+	if h.Retries != nil && *h.Retries < 0 {
+		return errors.New("retries cannot be negative")
+	}
+	return nil
+}
+
+func (h *Hook) timeout() time.Duration {
+	if h.TimeoutSeconds == 0 {
+		return DefaultTimeout
+	}
+	return time.Duration(h.TimeoutSeconds) * time.Second
+}
+
+func (h *Hook) retries() int {
+	if h.Retries == nil {
+		return DefaultRetries
+	}
+	return *h.Retries
+}
@@ -0,0 +1,272 @@
+package hooks
+
+import (
+	"encoding/json"
+	"glog/db"
+	"glog/domain"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTestStore(t *testing.T) *db.DocumentStore {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	store, err := db.NewDocumentStore(path)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func newDoc(title string, journal bool, content ...string) *domain.Document {
+	doc := &domain.Document{
+		ID:        domain.DocumentID(uuid.New()),
+		Title:     title,
+		Date:      time.Now().UTC(),
+		IsJournal: journal,
+	}
+	for _, c := range content {
+		doc.Blocks = append(doc.Blocks, &domain.Block{ID: domain.BlockID(uuid.New()), Content: c})
+	}
+	return doc
+}
+
+func writeConfig(t *testing.T, cfg string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "glog.db.hooks.json")
+	if err := os.WriteFile(path, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	if hooks, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json")); err != nil || hooks != nil {
+		t.Fatalf("missing file: got %v, %v", hooks, err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     string
+		wantErr string
+	}{
+		{"command", `{"hooks":[{"name":"a","command":"true"}]}`, ""},
+		{"localhost url", `{"hooks":[{"name":"a","url":"http://localhost:9000/x"}]}`, ""},
+		{"loopback url", `{"hooks":[{"name":"a","url":"http://127.0.0.1:9000/x"}]}`, ""},
+		{"remote url", `{"hooks":[{"name":"a","url":"http://example.com/x"}]}`, "localhost"},
+		{"both", `{"hooks":[{"name":"a","command":"true","url":"http://localhost/"}]}`, "exactly one"},
+		{"neither", `{"hooks":[{"name":"a"}]}`, "exactly one"},
+		{"bad event", `{"hooks":[{"name":"a","command":"true","on":["update"]}]}`, "unknown event"},
+		{"bad pattern", `{"hooks":[{"name":"a","command":"true","title_pattern":"("}]}`, "title_pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, tt.cfg))
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	hooks, err := LoadConfig(writeConfig(t, `{"hooks":[
+		{"name":"all","command":"true"},
+		{"name":"delete","command":"true","on":["delete"]},
+		{"name":"journal","command":"true","journal_only":true},
+		{"name":"pattern","command":"true","title_pattern":"^Project "},
+		{"name":"tag","command":"true","tag":"work"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := newDoc("Project X", false, "tags:: work")
+	journal := newDoc("Jan 2nd, 2026", true, "hello")
+
+	tests := []struct {
+		hook  int
+		event string
+		doc   *domain.Document
+		want  bool
+	}{
+		{0, EventSave, journal, true},
+		{1, EventSave, page, false},
+		{1, EventDelete, page, true},
+		{2, EventSave, page, false},
+		{2, EventSave, journal, true},
+		{3, EventSave, page, true},
+		{3, EventSave, journal, false},
+		{4, EventSave, page, true},
+		{4, EventSave, journal, false},
+	}
+	for _, tt := range tests {
+		if got := hooks[tt.hook].matches(tt.event, tt.doc); got != tt.want {
+			t.Errorf("%s on %s %q: got %v, want %v", hooks[tt.hook].Name, tt.event, tt.doc.Title, got, tt.want)
+		}
+	}
+}
+
+func TestRunnerCommand(t *testing.T) {
+	store := newTestStore(t)
+	out := filepath.Join(t.TempDir(), "payload.json")
+	t.Setenv("OUT", out)
+
+	runner := Start(store, []Hook{{
+		Name:    "capture",
+		Command: "sh",
+		Args:    []string{"-c", `cat > "$OUT"; echo "$GLOG_EVENT" >> "$OUT.event"`},
+	}})
+
+	doc := newDoc("Hooked", false, "content")
+	if err := store.Save(doc); err != nil {
+		t.Fatal(err)
+	}
+	runner.Stop()
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	var payload Payload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("invalid payload %s: %v", data, err)
+	}
+	if payload.Event != EventSave || payload.Document.Title != "Hooked" || payload.Document.ID != doc.ID.String() {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+	if event, _ := os.ReadFile(out + ".event"); strings.TrimSpace(string(event)) != EventSave {
+		t.Errorf("GLOG_EVENT = %q", event)
+	}
+	if failures := runner.Failures(); len(failures) != 0 {
+		t.Errorf("unexpected failures: %+v", failures)
+	}
+}
+
+func TestRunnerWebhook(t *testing.T) {
+	store := newTestStore(t)
+
+	received := make(chan Payload, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload Payload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- payload
+	}))
+	defer server.Close()
+
+	retries := 0
+	runner := Start(store, []Hook{{Name: "webhook", URL: server.URL, On: []string{EventDelete}, Retries: &retries}})
+
+	doc := newDoc("Webhooked", false, "content")
+	if err := store.Save(doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(uuid.UUID(doc.ID)); err != nil {
+		t.Fatal(err)
+	}
+	runner.Stop()
+
+	if len(received) != 1 {
+		t.Fatalf("expected one delete notification, got %d", len(received))
+	}
+	payload := <-received
+	if payload.Event != EventDelete || payload.Document.Title != "Webhooked" {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+}
+
+func TestRunnerFailureLog(t *testing.T) {
+	store := newTestStore(t)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	retries := 1
+	runner := Start(store, []Hook{{Name: "broken", URL: server.URL, Retries: &retries}})
+
+	doc := newDoc("Failing", false, "content")
+	if err := store.Save(doc); err != nil {
+		t.Fatal(err)
+	}
+	runner.Stop()
+
+	if calls != 2 {
+		t.Errorf("expected 2 attempts, got %d", calls)
+	}
+	failures := runner.Failures()
+	if len(failures) != 1 {
+		t.Fatalf("expected one failure, got %+v", failures)
+	}
+	f := failures[0]
+	if f.Hook != "broken" || f.DocID != doc.ID || f.Attempts != 2 || !strings.Contains(f.Error, "500") {
+		t.Errorf("unexpected failure: %+v", f)
+	}
+}
+
+// smallBufferSource drops events as soon as a hook is running.
+type smallBufferSource struct {
+	*db.DocumentStore
+}
+
+func (s smallBufferSource) Subscribe(int) *db.Subscription {
+	return s.DocumentStore.Subscribe(1)
+}
+
+func TestRunnerCatchesUpDroppedEvents(t *testing.T) {
+	store := newTestStore(t)
+	out := filepath.Join(t.TempDir(), "titles")
+	t.Setenv("OUT", out)
+
+	runner := Start(smallBufferSource{store}, []Hook{{
+		Name:    "slow",
+		Command: "sh",
+		Args:    []string{"-c", `sleep 0.05; echo "$GLOG_EVENT $GLOG_DOC_TITLE" >> "$OUT"`},
+	}})
+
+	var docs []*domain.Document
+	for _, title := range []string{"One", "Two", "Three", "Four", "Five"} {
+		doc := newDoc(title, false, "content")
+		if err := store.Save(doc); err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, doc)
+	}
+	if err := store.Delete(uuid.UUID(docs[0].ID)); err != nil {
+		t.Fatal(err)
+	}
+	runner.Stop()
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hooks did not run: %v", err)
+	}
+	// One was deleted before its dropped save event was handled
+	want := "save Two\nsave Three\nsave Four\nsave Five\ndelete One\n"
+	if string(data) != want && string(data) != "save One\n"+want {
+		t.Errorf("expected every event to run the hook, got:\n%s", data)
+	}
+	if failures := runner.Failures(); len(failures) != 0 {
+		t.Errorf("unexpected failures: %+v", failures)
+	}
+}
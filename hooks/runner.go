@@ -0,0 +1,337 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"glog/db"
+	"glog/domain"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	// maxFailures is the number of failures kept in the failure log.
+	maxFailures = 100
+
+	// changesBatch is the number of change log events read at a time.
+	changesBatch = 256
+)
+
+// Payload is the JSON a hook receives on stdin or as the request body.
+type Payload struct {
+	Event    string           `json:"event"` // "save" or "delete"
+	Document *db.DumpDocument `json:"document"`
+}
+
+// Failure is a hook run that failed after all its attempts.
+type Failure struct {
+	Hook     string
+	Event    string
+	DocID    domain.DocumentID
+	Title    string
+	Time     time.Time
+	Attempts int
+	Error    string
+}
+
+// Source is where the runner receives store events from.
+type Source interface {
+	Subscribe(buffer int) *db.Subscription
+	Unsubscribe(sub *db.Subscription)
+	Changes(after uint64, limit int) ([]db.Event, error)
+	LastChangeSeq() (uint64, error)
+	GetDocument(id domain.DocumentID) (*domain.Document, error)
+}
+
+// Runner runs hooks for the events of a store, one at a time and in
+// event order. It reads the events from the change log, so events the
+// subscription drops while a hook is slow still run their hooks.
+type Runner struct {
+	hooks  []Hook
+	source Source
+	sub    *db.Subscription
+	done   chan struct{}
+	client *http.Client
+
+	cursor uint64                      // Seq of the last event handled
+	docs   map[uint64]*domain.Document // documents of received events not handled yet
+
+	failuresMu sync.Mutex
+	failures   []Failure
+}
+
+// Start subscribes to the events of source and runs the matching hooks
+// for the events from now on in a background goroutine until Stop is
+// called.
+func Start(source Source, hooks []Hook) *Runner {
+	r := &Runner{
+		hooks:  hooks,
+		source: source,
+		sub:    source.Subscribe(256),
+		done:   make(chan struct{}),
+		client: &http.Client{},
+		docs:   make(map[uint64]*domain.Document),
+	}
+
+	// Subscribed first, so no event falls between the cursor and the
+	// subscription
+	cursor, err := source.LastChangeSeq()
+	if err != nil {
+		// Start from the first event received instead of the whole log
+		log.Errorf("Reading the change log position for hooks failed: %v", err)
+	}
+	r.cursor = cursor
+
+	go func() {
+		defer close(r.done)
+		for ev := range r.sub.C {
+			if err != nil && r.cursor == 0 {
+				r.cursor = ev.Seq - 1
+			}
+			if ev.Seq <= r.cursor {
+				continue
+			}
+			if doc := ev.Document(); doc != nil {
+				r.docs[ev.Seq] = doc
+			}
+			// Only up to this event: the later ones carry their documents
+			// once they are received
+			r.catchUp(ev.Seq)
+		}
+		// Events after the last one received, which were dropped
+		r.catchUp(0)
+	}()
+	return r
+}
+
+// catchUp handles the events of the change log after the cursor, up to
+// the event upTo, or all of them when upTo is 0.
+func (r *Runner) catchUp(upTo uint64) {
+	for upTo == 0 || r.cursor < upTo {
+		limit := changesBatch
+		if upTo != 0 {
+			limit = min(limit, int(upTo-r.cursor))
+		}
+		events, err := r.source.Changes(r.cursor, limit)
+		if err != nil {
+			log.Errorf("Reading the change log for hooks failed: %v", err)
+			return
+		}
+		if len(events) > 0 && events[0].Seq > r.cursor+1 {
+			log.Warnf("Hooks missed events %d to %d, trimmed from the change log", r.cursor+1, events[0].Seq-1)
+			for seq := range r.docs {
+				if seq < events[0].Seq {
+					delete(r.docs, seq)
+				}
+			}
+		}
+		for _, ev := range events {
+			doc := r.docs[ev.Seq]
+			delete(r.docs, ev.Seq)
+			r.handle(ev, doc)
+			r.cursor = ev.Seq
+		}
+		if len(events) < limit {
+			return
+		}
+	}
+}
+
+// Stop unsubscribes from the store, runs the hooks of the events not
+// handled yet and waits for the running hook to finish.
+func (r *Runner) Stop() {
+	r.source.Unsubscribe(r.sub)
+	<-r.done
+}
+
+// Failures returns the failure log, most recent first.
+func (r *Runner) Failures() []Failure {
+	r.failuresMu.Lock()
+	defer r.failuresMu.Unlock()
+
+	failures := make([]Failure, len(r.failures))
+	for i, f := range r.failures {
+		failures[len(r.failures)-1-i] = f
+	}
+	return failures
+}
+
+// handle runs the hooks matching ev. doc is the document received with
+// the event, or nil when the subscription dropped it: saved documents are
+// then loaded as they are now, and deleted ones only have the title.
+func (r *Runner) handle(ev db.Event, doc *domain.Document) {
+	var event string
+	switch ev.Type {
+	case db.EventDocumentSaved:
+		event = EventSave
+	case db.EventDocumentDeleted:
+		event = EventDelete
+	default:
+		return
+	}
+
+	if doc == nil && event == EventDelete {
+		doc = &domain.Document{ID: ev.DocID, Title: ev.Title, IsJournal: ev.IsJournal, Date: ev.Time}
+	}
+	if doc == nil {
+		var err error
+		doc, err = r.source.GetDocument(ev.DocID)
+		if errors.Is(err, db.ErrDocumentNotFound) {
+			// Deleted since; its delete event runs the hooks
+			return
+		}
+		if err != nil {
+			r.recordFailure(Failure{
+				Hook:  "*",
+				Event: event,
+				DocID: ev.DocID,
+				Title: ev.Title,
+				Time:  time.Now(),
+				Error: fmt.Sprintf("loading the document: %v", err),
+			})
+			return
+		}
+	}
+
+	var body []byte
+	for i := range r.hooks {
+		hook := &r.hooks[i]
+		if !hook.matches(event, doc) {
+			continue
+		}
+
+		if body == nil {
+			var err error
+			body, err = json.Marshal(Payload{Event: event, Document: db.NewDumpDocument(doc)})
+			if err != nil {
+				log.Errorf("Failed to encode hook payload for document %s: %v", doc.ID, err)
+				return
+			}
+		}
+
+		attempts, err := r.runWithRetry(hook, event, doc, body)
+		if err != nil {
+			r.recordFailure(Failure{
+				Hook:     hook.Name,
+				Event:    event,
+				DocID:    doc.ID,
+				Title:    doc.Title,
+				Time:     time.Now(),
+				Attempts: attempts,
+				Error:    err.Error(),
+			})
+		}
+	}
+}
+
+// matches reports whether the hook's filters accept the event.
+func (h *Hook) matches(event string, doc *domain.Document) bool {
+	if len(h.On) > 0 {
+		found := false
+		for _, on := range h.On {
+			if on == event {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if h.JournalOnly && !doc.IsJournal {
+		return false
+	}
+	if h.titleRegex != nil && !h.titleRegex.MatchString(doc.Title) {
+		return false
+	}
+	if h.Tag != "" && !db.HasTag(doc, h.Tag) {
+		return false
+	}
+	return true
+}
+
+// runWithRetry runs a hook with the same backoff as search indexing and
+// returns the number of attempts made.
+func (r *Runner) runWithRetry(hook *Hook, event string, doc *domain.Document, body []byte) (int, error) {
+	maxAttempts := hook.retries() + 1
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = r.run(hook, event, doc, body)
+		if err == nil {
+			return attempt, nil
+		}
+
+		log.Warnf("Hook %q attempt %d/%d failed for document %s: %v", hook.Name, attempt, maxAttempts, doc.ID, err)
+
+		if attempt < maxAttempts {
+			backoff := time.Duration(attempt*attempt) * 100 * time.Millisecond
+			time.Sleep(backoff)
+		}
+	}
+	return maxAttempts, err
+}
+
+func (r *Runner) run(hook *Hook, event string, doc *domain.Document, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hook.timeout())
+	defer cancel()
+
+	if hook.URL != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Glog-Event", event)
+
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, hook.Command, hook.Args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"GLOG_EVENT="+event,
+		"GLOG_DOC_ID="+doc.ID.String(),
+		"GLOG_DOC_TITLE="+doc.Title,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out after %s", hook.timeout())
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *Runner) recordFailure(f Failure) {
+	r.failuresMu.Lock()
+	defer r.failuresMu.Unlock()
+
+	r.failures = append(r.failures, f)
+	if len(r.failures) > maxFailures {
+		r.failures = r.failures[len(r.failures)-maxFailures:]
+	}
+}
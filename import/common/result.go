@@ -0,0 +1,60 @@
+// Package common contains the pieces shared by the glog importers: the
+// result reporting types and the rename-on-conflict title lookup.
+package common
+
+import (
+	"errors"
+	"fmt"
+	"glog/db"
+)
+
+// RenameInfo tracks when a document was renamed due to duplicate title.
+type RenameInfo struct {
+	OriginalTitle string
+	NewTitle      string
+}
+
+// ImportResult contains the results of an import operation.
+type ImportResult struct {
+	JournalsImported int
+	PagesImported    int
+	AssetsCopied     int
+	Skipped          int
+	Renamed          []RenameInfo
+	Errors           []error
+}
+
+// NewImportResult returns an empty ImportResult ready to be filled in.
+func NewImportResult() *ImportResult {
+	return &ImportResult{
+		Renamed: make([]RenameInfo, 0),
+		Errors:  make([]error, 0),
+	}
+}
+
+// FindUniqueTitle returns a unique title by appending (2), (3), etc. if needed.
+func FindUniqueTitle(store Store, baseTitle string) string {
+	title := baseTitle
+	suffix := 2
+
+	for {
+		_, err := store.GetDocumentByTitle(title)
+		if errors.Is(err, db.ErrDocumentNotFound) {
+			// Title is available
+			return title
+		}
+		if err != nil {
+			// Some other error - just use the title and let save handle it
+			return title
+		}
+
+		// Title exists, try with suffix
+		title = fmt.Sprintf("%s (%d)", baseTitle, suffix)
+		suffix++
+
+		// Safety limit to prevent infinite loop
+		if suffix > 1000 {
+			return title
+		}
+	}
+}
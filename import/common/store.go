@@ -0,0 +1,20 @@
+package common
+
+import (
+	"glog/db"
+	"glog/domain"
+	"glog/ipc"
+)
+
+// Store is the part of a DocumentStore importers write through.
+type Store interface {
+	Save(doc *domain.Document) error
+	GetDocumentByTitle(title string) (*domain.Document, error)
+	Close() error
+}
+
+// OpenStore opens the database at path for an import. While the glog app
+// holds the database the documents are saved through the app instead.
+func OpenStore(path string) (Store, error) {
+	return ipc.Open(path, db.Options{Timeout: db.DefaultLockTimeout})
+}
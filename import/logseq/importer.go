@@ -3,7 +3,7 @@ package logseq
 import (
 	"errors"
 	"fmt"
-	"glog/db"
+	"glog/import/common"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,35 +20,23 @@ type ImportOptions struct {
 }
 
 // RenameInfo tracks when a document was renamed due to duplicate title.
-type RenameInfo struct {
-	OriginalTitle string
-	NewTitle      string
-}
+type RenameInfo = common.RenameInfo
 
 // ImportResult contains the results of an import operation.
-type ImportResult struct {
-	JournalsImported int
-	PagesImported    int
-	Skipped          int
-	Renamed          []RenameInfo
-	Errors           []error
-}
+type ImportResult = common.ImportResult
 
 // Importer handles importing Logseq data into glog.
 type Importer struct {
 	opts   ImportOptions
-	store  *db.DocumentStore
+	store  common.Store
 	result *ImportResult
 }
 
 // NewImporter creates a new Importer with the given options.
 func NewImporter(opts ImportOptions) *Importer {
 	return &Importer{
-		opts: opts,
-		result: &ImportResult{
-			Renamed: make([]RenameInfo, 0),
-			Errors:  make([]error, 0),
-		},
+		opts:   opts,
+		result: common.NewImportResult(),
 	}
 }
 
@@ -61,7 +49,7 @@ func (imp *Importer) Import() (*ImportResult, error) {
 
 	// Open database (unless dry run)
 	if !imp.opts.DryRun {
-		store, err := db.NewDocumentStore(imp.opts.DBPath)
+		store, err := common.OpenStore(imp.opts.DBPath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to open database: %w", err)
 		}
@@ -251,29 +239,7 @@ func (imp *Importer) importFile(filePath string, isJournal bool) error {
 
 // findUniqueTitle returns a unique title by appending (2), (3), etc. if needed.
 func (imp *Importer) findUniqueTitle(baseTitle string) string {
-	title := baseTitle
-	suffix := 2
-
-	for {
-		_, err := imp.store.LoadDocumentByTitle(title)
-		if errors.Is(err, db.ErrDocumentNotFound) {
-			// Title is available
-			return title
-		}
-		if err != nil {
-			// Some other error - just use the title and let save handle it
-			return title
-		}
-
-		// Title exists, try with suffix
-		title = fmt.Sprintf("%s (%d)", baseTitle, suffix)
-		suffix++
-
-		// Safety limit to prevent infinite loop
-		if suffix > 1000 {
-			return title
-		}
-	}
+	return common.FindUniqueTitle(imp.store, baseTitle)
 }
 
 // Import is a convenience function that creates an Importer and runs the import.
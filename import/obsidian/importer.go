@@ -0,0 +1,365 @@
+package obsidian
+
+import (
+	"errors"
+	"fmt"
+	"glog/domain"
+	"glog/import/common"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImportOptions configures the import behavior.
+type ImportOptions struct {
+	VaultPath        string // Path to Obsidian vault directory
+	DBPath           string // Path to glog database file
+	AssetsDir        string // Directory attachments are copied to and linked from (default: "assets" next to the database)
+	DailyNoteFormat  string // Go time layout of daily note filenames (default: "2006-01-02")
+	DailyNotesFolder string // Vault-relative folder holding daily notes; empty matches notes anywhere
+	JournalsOnly     bool   // If true, only import daily notes
+	PagesOnly        bool   // If true, only import regular notes
+	DryRun           bool   // If true, don't actually write to database or copy attachments
+	Verbose          bool   // If true, print detailed progress
+}
+
+// RenameInfo tracks when a document was renamed due to duplicate title.
+type RenameInfo = common.RenameInfo
+
+// ImportResult contains the results of an import operation.
+type ImportResult = common.ImportResult
+
+// Importer handles importing an Obsidian vault into glog.
+type Importer struct {
+	opts   ImportOptions
+	store  common.Store
+	result *ImportResult
+
+	notes       []string          // vault-relative paths of markdown notes
+	attachments map[string]string // lower-cased name and vault-relative path -> vault-relative path
+	copied      map[string]string // vault-relative attachment path -> asset link
+}
+
+// NewImporter creates a new Importer with the given options.
+func NewImporter(opts ImportOptions) *Importer {
+	if opts.DailyNoteFormat == "" {
+		opts.DailyNoteFormat = DefaultDailyNoteFormat
+	}
+	if opts.AssetsDir == "" {
+		opts.AssetsDir = filepath.Join(filepath.Dir(opts.DBPath), "assets")
+	}
+
+	return &Importer{
+		opts:        opts,
+		result:      common.NewImportResult(),
+		attachments: make(map[string]string),
+		copied:      make(map[string]string),
+	}
+}
+
+// Import performs the import operation.
+func (imp *Importer) Import() (*ImportResult, error) {
+	// Validate vault path
+	if err := imp.validateVaultPath(); err != nil {
+		return nil, fmt.Errorf("invalid Obsidian vault path: %w", err)
+	}
+
+	if err := imp.scanVault(); err != nil {
+		return nil, fmt.Errorf("failed to scan vault: %w", err)
+	}
+
+	// Open database (unless dry run)
+	if !imp.opts.DryRun {
+		store, err := common.OpenStore(imp.opts.DBPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+		defer store.Close()
+		imp.store = store
+	}
+
+	if imp.opts.Verbose {
+		fmt.Printf("Found %d notes and %d attachments\n", len(imp.notes), len(imp.uniqueAttachments()))
+	}
+
+	for _, notePath := range imp.notes {
+		date, isJournal := imp.dailyNoteDate(notePath)
+		if (isJournal && imp.opts.PagesOnly) || (!isJournal && imp.opts.JournalsOnly) {
+			imp.result.Skipped++
+			continue
+		}
+
+		if err := imp.importNote(notePath, date, isJournal); err != nil {
+			imp.result.Errors = append(imp.result.Errors, fmt.Errorf("note %s: %w", notePath, err))
+			if imp.opts.Verbose {
+				fmt.Printf("  Error importing %s: %v\n", notePath, err)
+			}
+		}
+	}
+
+	return imp.result, nil
+}
+
+// validateVaultPath checks that the vault path exists and is a directory.
+func (imp *Importer) validateVaultPath() error {
+	info, err := os.Stat(imp.opts.VaultPath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("path is not a directory")
+	}
+	return nil
+}
+
+// scanVault collects the notes and attachments of the vault, skipping hidden
+// directories such as .obsidian and .trash.
+func (imp *Importer) scanVault() error {
+	root := imp.opts.VaultPath
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := d.Name()
+		if p != root && strings.HasPrefix(name, ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if strings.EqualFold(path.Ext(name), ".md") {
+			imp.notes = append(imp.notes, rel)
+			return nil
+		}
+
+		// Attachments can be referenced by name or by vault-relative path.
+		// The first file wins when several share a name, like Obsidian's
+		// shortest-path resolution for unambiguous vaults.
+		if _, exists := imp.attachments[strings.ToLower(name)]; !exists {
+			imp.attachments[strings.ToLower(name)] = rel
+		}
+		imp.attachments[strings.ToLower(rel)] = rel
+		return nil
+	})
+}
+
+func (imp *Importer) uniqueAttachments() map[string]struct{} {
+	unique := make(map[string]struct{})
+	for _, rel := range imp.attachments {
+		unique[rel] = struct{}{}
+	}
+	return unique
+}
+
+// dailyNoteDate reports whether a note is a daily note and returns its date.
+func (imp *Importer) dailyNoteDate(notePath string) (time.Time, bool) {
+	if folder := strings.Trim(filepath.ToSlash(imp.opts.DailyNotesFolder), "/"); folder != "" {
+		if !strings.EqualFold(path.Dir(notePath), folder) {
+			return time.Time{}, false
+		}
+	}
+
+	date, err := ParseDailyNoteFilename(path.Base(notePath), imp.opts.DailyNoteFormat)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// importNote imports a single Obsidian note.
+func (imp *Importer) importNote(notePath string, date time.Time, isJournal bool) error {
+	fullPath := filepath.Join(imp.opts.VaultPath, filepath.FromSlash(notePath))
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return err
+	}
+
+	noteDir := path.Dir(notePath)
+	note, err := ParseNote(string(content), func(target string) (string, bool) {
+		return imp.resolveAttachment(noteDir, target)
+	})
+	if err != nil {
+		return err
+	}
+
+	doc := &domain.Document{
+		ID:        domain.DocumentID(uuid.New()),
+		IsJournal: isJournal,
+		Blocks:    note.Blocks,
+	}
+	if isJournal {
+		doc.Date = date
+		doc.Title = JournalTitleFromDate(date)
+	} else {
+		doc.Title = ParseNoteFilename(notePath)
+		if info, err := os.Stat(fullPath); err == nil {
+			doc.Date = info.ModTime()
+		}
+	}
+
+	originalTitle := doc.Title
+
+	// Check for duplicates and find unique title
+	if imp.store != nil {
+		doc.Title = common.FindUniqueTitle(imp.store, doc.Title)
+		if doc.Title != originalTitle {
+			imp.result.Renamed = append(imp.result.Renamed, RenameInfo{
+				OriginalTitle: originalTitle,
+				NewTitle:      doc.Title,
+			})
+		}
+	}
+
+	if imp.opts.Verbose {
+		if doc.Title != originalTitle {
+			fmt.Printf("  Importing: %s -> %s\n", originalTitle, doc.Title)
+		} else {
+			fmt.Printf("  Importing: %s\n", doc.Title)
+		}
+	}
+
+	// Save to database (unless dry run)
+	if !imp.opts.DryRun && imp.store != nil {
+		if err := imp.store.Save(doc); err != nil {
+			return fmt.Errorf("failed to save: %w", err)
+		}
+	}
+
+	// Update counts
+	if isJournal {
+		imp.result.JournalsImported++
+	} else {
+		imp.result.PagesImported++
+	}
+
+	return nil
+}
+
+// resolveAttachment finds an attachment referenced from a note in noteDir and
+// copies it into the assets directory on first use, returning its asset link.
+func (imp *Importer) resolveAttachment(noteDir string, target string) (string, bool) {
+	target = strings.TrimPrefix(filepath.ToSlash(target), "./")
+
+	rel, ok := imp.attachments[strings.ToLower(path.Join(noteDir, target))]
+	if !ok {
+		rel, ok = imp.attachments[strings.ToLower(target)]
+	}
+	if !ok {
+		rel, ok = imp.attachments[strings.ToLower(path.Base(target))]
+	}
+	if !ok {
+		return "", false
+	}
+
+	if link, done := imp.copied[rel]; done {
+		return link, true
+	}
+
+	name, err := imp.copyAttachment(rel)
+	if err != nil {
+		imp.result.Errors = append(imp.result.Errors, fmt.Errorf("attachment %s: %w", rel, err))
+		return "", false
+	}
+
+	link := imp.assetLink(name)
+	imp.copied[rel] = link
+	imp.result.AssetsCopied++
+	if imp.opts.Verbose {
+		fmt.Printf("  Copying attachment: %s -> %s\n", rel, link)
+	}
+	return link, true
+}
+
+// assetLink returns the link to the asset copied as name. Assets below the
+// database directory are linked relative to it, like the app serves them;
+// others keep the absolute path of the assets directory.
+func (imp *Importer) assetLink(name string) string {
+	dir, err := filepath.Abs(imp.opts.AssetsDir)
+	if err != nil {
+		dir = imp.opts.AssetsDir
+	}
+	base, err := filepath.Abs(filepath.Dir(imp.opts.DBPath))
+	if err == nil {
+		rel, err := filepath.Rel(base, dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "./" + path.Join(filepath.ToSlash(rel), name)
+		}
+	}
+	return path.Join(filepath.ToSlash(dir), name)
+}
+
+// copyAttachment copies a vault attachment into the assets directory and
+// returns its file name there. Existing files with the same name are never
+// overwritten; a numeric suffix is added instead.
+func (imp *Importer) copyAttachment(rel string) (string, error) {
+	base := path.Base(rel)
+	name := imp.uniqueAssetName(base)
+	if imp.opts.DryRun {
+		return name, nil
+	}
+
+	if err := os.MkdirAll(imp.opts.AssetsDir, 0755); err != nil {
+		return "", err
+	}
+
+	src, err := os.Open(filepath.Join(imp.opts.VaultPath, filepath.FromSlash(rel)))
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(filepath.Join(imp.opts.AssetsDir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return "", err
+	}
+	return name, dst.Close()
+}
+
+// uniqueAssetName returns a file name that is not yet used in the assets
+// directory or by an attachment copied during this import.
+func (imp *Importer) uniqueAssetName(base string) string {
+	taken := make(map[string]struct{}, len(imp.copied))
+	for _, link := range imp.copied {
+		taken[strings.ToLower(path.Base(link))] = struct{}{}
+	}
+
+	ext := path.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	name := base
+	for suffix := 2; ; suffix++ {
+		_, usedNow := taken[strings.ToLower(name)]
+		_, statErr := os.Stat(filepath.Join(imp.opts.AssetsDir, name))
+		if !usedNow && errors.Is(statErr, fs.ErrNotExist) {
+			return name
+		}
+		name = fmt.Sprintf("%s-%d%s", stem, suffix, ext)
+	}
+}
+
+// Import is a convenience function that creates an Importer and runs the import.
+func Import(opts ImportOptions) (*ImportResult, error) {
+	importer := NewImporter(opts)
+	return importer.Import()
+}
@@ -0,0 +1,160 @@
+package obsidian
+
+import (
+	"glog/db"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeVaultFile(t *testing.T, root string, rel string, content string) {
+	t.Helper()
+	full := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	vault := t.TempDir()
+	target := t.TempDir()
+	dbPath := filepath.Join(target, "glog.db")
+
+	writeVaultFile(t, vault, "Daily/2024-01-15.md", "- Met with [[Project X|PX team]]\n\t- ![[whiteboard.png]]")
+	writeVaultFile(t, vault, "Project X.md", "---\ntags: [work]\n---\n# Goals\n- Ship it")
+	writeVaultFile(t, vault, "Attachments/whiteboard.png", "png-bytes")
+	writeVaultFile(t, vault, ".obsidian/app.json", "{}")
+	writeVaultFile(t, vault, ".obsidian/ignored.md", "not a note")
+
+	result, err := Import(ImportOptions{
+		VaultPath:        vault,
+		DBPath:           dbPath,
+		DailyNotesFolder: "Daily",
+	})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("Import() errors = %v", result.Errors)
+	}
+	if result.JournalsImported != 1 || result.PagesImported != 1 {
+		t.Errorf("imported %d journals and %d pages, want 1 and 1", result.JournalsImported, result.PagesImported)
+	}
+	if result.AssetsCopied != 1 {
+		t.Errorf("AssetsCopied = %d, want 1", result.AssetsCopied)
+	}
+
+	copied, err := os.ReadFile(filepath.Join(target, "assets", "whiteboard.png"))
+	if err != nil || string(copied) != "png-bytes" {
+		t.Errorf("attachment not copied: %v", err)
+	}
+
+	store, err := db.NewDocumentStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open DocumentStore: %v", err)
+	}
+	defer store.Close()
+
+	journal, err := store.LoadDocumentByTitle("Monday, January 15, 2024")
+	if err != nil {
+		t.Fatalf("journal not imported: %v", err)
+	}
+	if !journal.IsJournal {
+		t.Error("Expected daily note to be imported as a journal")
+	}
+	if len(journal.Blocks) != 2 || journal.Blocks[1].Indent != 1 {
+		t.Fatalf("unexpected journal blocks: %+v", journal.Blocks)
+	}
+	if journal.Blocks[0].Content != "Met with PX team ([[Project X]])" {
+		t.Errorf("alias link not rewritten: %q", journal.Blocks[0].Content)
+	}
+	if refs, err := store.GetReferences("Project X"); err != nil || len(refs) != 1 || refs[0] != journal.ID {
+		t.Errorf("Expected the journal to reference Project X, got %v (err %v)", refs, err)
+	}
+	if journal.Blocks[1].Content != "![whiteboard.png](./assets/whiteboard.png)" {
+		t.Errorf("attachment embed not rewritten: %q", journal.Blocks[1].Content)
+	}
+
+	page, err := store.LoadDocumentByTitle("Project X")
+	if err != nil {
+		t.Fatalf("page not imported: %v", err)
+	}
+	if page.Blocks[0].Content != "tags:: work" {
+		t.Errorf("tags not preserved: %q", page.Blocks[0].Content)
+	}
+}
+
+func TestImportCustomAssetsDir(t *testing.T) {
+	outside := t.TempDir()
+	tests := []struct {
+		name      string
+		assetsDir func(target string) string
+		want      func(target string) string
+	}{
+		{
+			name:      "below the database",
+			assetsDir: func(target string) string { return filepath.Join(target, "media", "vault") },
+			want:      func(string) string { return "./media/vault/diagram.png" },
+		},
+		{
+			name:      "elsewhere",
+			assetsDir: func(string) string { return outside },
+			want:      func(string) string { return filepath.ToSlash(filepath.Join(outside, "diagram.png")) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault := t.TempDir()
+			target := t.TempDir()
+			dbPath := filepath.Join(target, "glog.db")
+			writeVaultFile(t, vault, "Notes.md", "![[diagram.png]]")
+			writeVaultFile(t, vault, "diagram.png", "png-bytes")
+
+			assetsDir := tt.assetsDir(target)
+			if _, err := Import(ImportOptions{VaultPath: vault, DBPath: dbPath, AssetsDir: assetsDir}); err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(assetsDir, "diagram.png")); err != nil {
+				t.Errorf("attachment not copied to %s: %v", assetsDir, err)
+			}
+
+			store, err := db.NewDocumentStore(dbPath)
+			if err != nil {
+				t.Fatalf("Failed to open DocumentStore: %v", err)
+			}
+			defer store.Close()
+
+			page, err := store.LoadDocumentByTitle("Notes")
+			if err != nil {
+				t.Fatalf("page not imported: %v", err)
+			}
+			if want := "![diagram.png](" + tt.want(target) + ")"; page.Blocks[0].Content != want {
+				t.Errorf("attachment link = %q, want %q", page.Blocks[0].Content, want)
+			}
+		})
+	}
+}
+
+func TestImportRenamesDuplicateTitles(t *testing.T) {
+	vault := t.TempDir()
+	dbPath := filepath.Join(t.TempDir(), "glog.db")
+	writeVaultFile(t, vault, "Notes.md", "first")
+
+	for i := 0; i < 2; i++ {
+		if _, err := Import(ImportOptions{VaultPath: vault, DBPath: dbPath}); err != nil {
+			t.Fatalf("Import() error = %v", err)
+		}
+	}
+
+	result, err := Import(ImportOptions{VaultPath: vault, DBPath: dbPath})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(result.Renamed) != 1 || result.Renamed[0].NewTitle != "Notes (3)" {
+		t.Errorf("Renamed = %+v, want Notes -> Notes (3)", result.Renamed)
+	}
+}
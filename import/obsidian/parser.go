@@ -0,0 +1,476 @@
+package obsidian
+
+import (
+	"bufio"
+	"fmt"
+	"glog/domain"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultDailyNoteFormat is the Go time layout matching Obsidian's default
+// daily note filename format (YYYY-MM-DD).
+const DefaultDailyNoteFormat = "2006-01-02"
+
+var (
+	// headingRegex matches ATX headings: "## Heading"
+	headingRegex = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+
+	// listItemRegex matches unordered ("- ", "* ", "+ ") and ordered ("1. ", "1) ") list items
+	listItemRegex = regexp.MustCompile(`^([ \t]*)([-*+]|\d+[.)])[ \t]+(.*)$`)
+
+	// taskRegex matches a task checkbox at the start of a list item: "[ ] ", "[x] "
+	taskRegex = regexp.MustCompile(`^\[([ xX])\]\s*(.*)$`)
+
+	// wikiLinkRegex matches Obsidian links and embeds: [[target#heading|alias]]
+	wikiLinkRegex = regexp.MustCompile(`!?\[\[([^\[\]|#]*)(?:#[^\[\]|]*)?(?:\|([^\[\]]*))?\]\]`)
+
+	// embedRegex matches Obsidian embeds: ![[target]] or ![[target|size]]
+	embedRegex = regexp.MustCompile(`!\[\[([^\[\]|#]+)(?:#[^\[\]|]*)?(?:\|[^\[\]]*)?\]\]`)
+
+	// imageRegex matches markdown images: ![alt](path)
+	imageRegex = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+
+	// commentRegex matches Obsidian comments: %%hidden%%
+	commentRegex = regexp.MustCompile(`(?s)%%.*?%%`)
+
+	// ruleRegex matches horizontal rules: ---, ***, ___
+	ruleRegex = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+)
+
+// AttachmentResolver maps an attachment referenced from a note (by name or
+// vault-relative path) to the link that should replace it in glog, such as
+// "./assets/image.png". It returns false if the attachment is unknown.
+type AttachmentResolver func(target string) (string, bool)
+
+// Frontmatter holds the frontmatter properties that are carried over to glog.
+type Frontmatter struct {
+	Tags    []string
+	Aliases []string
+}
+
+// Note is the parsed form of an Obsidian markdown file.
+type Note struct {
+	Frontmatter Frontmatter
+	Blocks      []*domain.Block
+}
+
+// ParseDailyNoteFilename extracts the date from a daily note filename using
+// the given Go time layout. An empty layout uses DefaultDailyNoteFormat.
+func ParseDailyNoteFilename(filename string, layout string) (time.Time, error) {
+	if layout == "" {
+		layout = DefaultDailyNoteFormat
+	}
+	name := strings.TrimSuffix(filename, filepath.Ext(filename))
+	return time.Parse(layout, name)
+}
+
+// ParseNoteFilename extracts the page title from a note filename.
+// Obsidian uses the filename (without extension) as the note title.
+func ParseNoteFilename(filename string) string {
+	return strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+}
+
+// JournalTitleFromDate formats a date into glog's journal title format.
+// Example: "Monday, January 2, 2006"
+func JournalTitleFromDate(date time.Time) string {
+	return date.Format("Monday, January 2, 2006")
+}
+
+// SplitFrontmatter separates a leading YAML frontmatter section ("---" fenced)
+// from the note body. If there is no frontmatter, yaml is empty.
+func SplitFrontmatter(content string) (yamlText string, body string) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return "", normalized
+	}
+
+	rest := normalized[len("---\n"):]
+	end := strings.Index(rest, "\n---")
+	if end == -1 {
+		return "", normalized
+	}
+
+	// The closing fence must be a line on its own
+	after := rest[end+len("\n---"):]
+	if after != "" && !strings.HasPrefix(after, "\n") {
+		return "", normalized
+	}
+
+	return rest[:end], strings.TrimPrefix(after, "\n")
+}
+
+// ParseFrontmatter extracts tags and aliases from YAML frontmatter.
+// Both the singular and plural keys are accepted, as either a list or a
+// comma-separated string. Leading '#' characters are removed from tags.
+func ParseFrontmatter(yamlText string) (Frontmatter, error) {
+	var fm Frontmatter
+	if strings.TrimSpace(yamlText) == "" {
+		return fm, nil
+	}
+
+	var props map[string]interface{}
+	if err := yaml.Unmarshal([]byte(yamlText), &props); err != nil {
+		return fm, fmt.Errorf("invalid frontmatter: %w", err)
+	}
+
+	for key, value := range props {
+		switch strings.ToLower(key) {
+		case "tags", "tag":
+			for _, tag := range frontmatterValues(value) {
+				tag = strings.TrimPrefix(tag, "#")
+				if tag != "" {
+					fm.Tags = append(fm.Tags, tag)
+				}
+			}
+		case "aliases", "alias":
+			fm.Aliases = append(fm.Aliases, frontmatterValues(value)...)
+		}
+	}
+
+	return fm, nil
+}
+
+// frontmatterValues flattens a YAML scalar or list into a list of strings.
+func frontmatterValues(value interface{}) []string {
+	var values []string
+	switch v := value.(type) {
+	case string:
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			values = append(values, frontmatterValues(item)...)
+		}
+	case nil:
+	default:
+		values = append(values, fmt.Sprint(v))
+	}
+	return values
+}
+
+// PropertiesBlockContent renders frontmatter as glog "key:: value" property
+// lines, suitable for the first block of a document. It returns an empty
+// string if there is nothing to carry over.
+func PropertiesBlockContent(fm Frontmatter) string {
+	var lines []string
+	if len(fm.Tags) > 0 {
+		lines = append(lines, "tags:: "+strings.Join(fm.Tags, ", "))
+	}
+	if len(fm.Aliases) > 0 {
+		lines = append(lines, "alias:: "+strings.Join(fm.Aliases, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ConvertWikiLinks rewrites links with an alias or a heading, which glog
+// would take for part of the page title, into plain [[Note]] links. The
+// alias is kept as text before the link ("alias ([[Note]])"); headings are
+// dropped. Links within the note ([[#heading]]) become plain text. Embeds
+// are left to ConvertAttachmentLinks.
+func ConvertWikiLinks(line string) string {
+	return wikiLinkRegex.ReplaceAllStringFunc(line, func(match string) string {
+		if strings.HasPrefix(match, "!") || !strings.ContainsAny(match, "#|") {
+			return match
+		}
+		parts := wikiLinkRegex.FindStringSubmatch(match)
+		target := strings.TrimSpace(parts[1])
+		alias := strings.TrimSpace(parts[2])
+		if target == "" {
+			if alias == "" {
+				alias = strings.TrimSpace(strings.Trim(match, "[]#"))
+			}
+			return alias
+		}
+		if alias == "" || strings.EqualFold(alias, target) {
+			return "[[" + target + "]]"
+		}
+		return alias + " ([[" + target + "]])"
+	})
+}
+
+// ConvertAttachmentLinks rewrites embeds and markdown images that point at
+// vault attachments into glog asset links. Embeds of other notes (![[Note]])
+// and links are kept unchanged.
+func ConvertAttachmentLinks(line string, resolve AttachmentResolver) string {
+	if resolve == nil {
+		return line
+	}
+
+	line = embedRegex.ReplaceAllStringFunc(line, func(match string) string {
+		target := strings.TrimSpace(embedRegex.FindStringSubmatch(match)[1])
+		if strings.EqualFold(path.Ext(target), ".md") || path.Ext(target) == "" {
+			return match
+		}
+		link, ok := resolve(target)
+		if !ok {
+			return match
+		}
+		return fmt.Sprintf("![%s](%s)", path.Base(target), link)
+	})
+
+	return imageRegex.ReplaceAllStringFunc(line, func(match string) string {
+		parts := imageRegex.FindStringSubmatch(match)
+		target := parts[2]
+		if strings.Contains(target, "://") || strings.HasPrefix(target, "data:") {
+			return match
+		}
+		link, ok := resolve(target)
+		if !ok {
+			return match
+		}
+		return fmt.Sprintf("![%s](%s)", parts[1], link)
+	})
+}
+
+// ParseContent parses an Obsidian note body into glog blocks.
+//
+// Headings become blocks and nest everything below them until a heading of
+// the same or a higher level. Paragraphs become one block each, list items
+// become one block per item indented by their list nesting, and fenced code
+// blocks are kept whole. Task checkboxes are converted to glog task markers
+// ("/TODO" or "/DONE").
+func ParseContent(content string, resolve AttachmentResolver) []*domain.Block {
+	p := &contentParser{resolve: resolve}
+	p.parse(commentRegex.ReplaceAllString(content, ""))
+
+	// If no blocks were created, create an empty one
+	if len(p.blocks) == 0 {
+		p.blocks = append(p.blocks, newBlock("", 0))
+	}
+
+	return p.blocks
+}
+
+// contentParser holds the state used while converting a note body to blocks.
+type contentParser struct {
+	resolve AttachmentResolver
+	blocks  []*domain.Block
+
+	headings []int // levels of the enclosing headings
+
+	paragraph []string // pending paragraph lines
+
+	listWidths []int         // indentation widths of the enclosing list items
+	listItem   *domain.Block // list item receiving continuation lines
+	listWidth  int           // indentation width of listItem's marker
+
+	fence       string        // opening fence of the current code block, if any
+	fenceBlock  *domain.Block // block receiving the code block lines
+	fenceIndent int           // indentation width of the opening fence
+}
+
+// sectionIndent is the indent of content below the current heading.
+func (p *contentParser) sectionIndent() int {
+	return len(p.headings)
+}
+
+func (p *contentParser) parse(content string) {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if p.fence != "" {
+			p.codeLine(line)
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		width := indentWidth(line)
+
+		switch {
+		case trimmed == "":
+			p.flushParagraph()
+			// A blank line ends lazy continuation but keeps the list open
+			// so that indented continuation paragraphs still attach.
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			p.openFence(line, trimmed, width)
+		case headingRegex.MatchString(line):
+			p.flushParagraph()
+			p.closeList()
+			match := headingRegex.FindStringSubmatch(line)
+			p.heading(len(match[1]), match[0])
+		case ruleRegex.MatchString(line) && !listItemRegex.MatchString(line):
+			p.flushParagraph()
+			p.closeList()
+		case listItemRegex.MatchString(line):
+			p.flushParagraph()
+			match := listItemRegex.FindStringSubmatch(line)
+			p.listItemLine(indentWidth(match[1]), match[2], match[3])
+		case p.listItem != nil && width > p.listWidth:
+			// Indented continuation of the current list item
+			p.listItem.Content += "\n" + p.convert(trimmed)
+		default:
+			p.closeList()
+			p.paragraph = append(p.paragraph, p.convert(trimmed))
+		}
+	}
+
+	if p.fence != "" {
+		p.closeFence()
+	}
+	p.flushParagraph()
+}
+
+func (p *contentParser) convert(text string) string {
+	return ConvertWikiLinks(ConvertAttachmentLinks(text, p.resolve))
+}
+
+func (p *contentParser) heading(level int, text string) {
+	for len(p.headings) > 0 && p.headings[len(p.headings)-1] >= level {
+		p.headings = p.headings[:len(p.headings)-1]
+	}
+	p.blocks = append(p.blocks, newBlock(p.convert(strings.TrimSpace(text)), p.sectionIndent()))
+	p.headings = append(p.headings, level)
+}
+
+func (p *contentParser) listItemLine(width int, marker string, text string) {
+	for len(p.listWidths) > 0 && p.listWidths[len(p.listWidths)-1] >= width {
+		p.listWidths = p.listWidths[:len(p.listWidths)-1]
+	}
+	depth := len(p.listWidths)
+	p.listWidths = append(p.listWidths, width)
+
+	// Keep the number of ordered list items, drop bullet markers
+	if marker[0] >= '0' && marker[0] <= '9' {
+		text = marker + " " + text
+	}
+
+	if match := taskRegex.FindStringSubmatch(text); match != nil {
+		marker := "/TODO"
+		if match[1] != " " {
+			marker = "/DONE"
+		}
+		text = strings.TrimSpace(match[2] + " " + marker)
+	}
+
+	block := newBlock(p.convert(strings.TrimSpace(text)), p.sectionIndent()+depth)
+	p.blocks = append(p.blocks, block)
+	p.listItem = block
+	p.listWidth = width
+}
+
+func (p *contentParser) closeList() {
+	p.listWidths = nil
+	p.listItem = nil
+}
+
+func (p *contentParser) flushParagraph() {
+	if len(p.paragraph) == 0 {
+		return
+	}
+	p.blocks = append(p.blocks, newBlock(strings.Join(p.paragraph, "\n"), p.sectionIndent()))
+	p.paragraph = nil
+}
+
+func (p *contentParser) openFence(line string, trimmed string, width int) {
+	p.flushParagraph()
+
+	p.fence = trimmed[:3]
+	p.fenceIndent = width
+
+	if p.listItem != nil && width > p.listWidth {
+		// Code block nested inside a list item belongs to that item
+		p.fenceBlock = p.listItem
+		p.fenceBlock.Content += "\n" + stripIndent(line, width)
+		return
+	}
+
+	p.closeList()
+	p.fenceBlock = newBlock(stripIndent(line, width), p.sectionIndent())
+	p.blocks = append(p.blocks, p.fenceBlock)
+}
+
+func (p *contentParser) codeLine(line string) {
+	p.fenceBlock.Content += "\n" + stripIndent(line, p.fenceIndent)
+	if strings.HasPrefix(strings.TrimSpace(line), p.fence) {
+		p.closeFence()
+	}
+}
+
+func (p *contentParser) closeFence() {
+	p.fence = ""
+	p.fenceBlock = nil
+}
+
+// indentWidth returns the width of the leading whitespace of a line,
+// counting a tab as four columns.
+func indentWidth(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// stripIndent removes up to width columns of leading whitespace from a line,
+// preserving any additional indentation.
+func stripIndent(line string, width int) string {
+	removed := 0
+	i := 0
+	for i < len(line) && removed < width {
+		switch line[i] {
+		case ' ':
+			removed++
+		case '\t':
+			removed += 4
+		default:
+			return line[i:]
+		}
+		i++
+	}
+	return line[i:]
+}
+
+func newBlock(content string, indent int) *domain.Block {
+	return &domain.Block{
+		ID:      domain.BlockID(uuid.New()),
+		Content: content,
+		Indent:  indent,
+	}
+}
+
+// ParseNote parses the full content of an Obsidian note, including its
+// frontmatter. Tags and aliases from the frontmatter are prepended as a
+// properties block.
+func ParseNote(content string, resolve AttachmentResolver) (*Note, error) {
+	yamlText, body := SplitFrontmatter(content)
+	fm, err := ParseFrontmatter(yamlText)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := ParseContent(body, resolve)
+	if props := PropertiesBlockContent(fm); props != "" {
+		propsBlock := newBlock(props, 0)
+		if len(blocks) == 1 && blocks[0].Content == "" {
+			blocks = []*domain.Block{propsBlock}
+		} else {
+			blocks = append([]*domain.Block{propsBlock}, blocks...)
+		}
+	}
+
+	return &Note{
+		Frontmatter: fm,
+		Blocks:      blocks,
+	}, nil
+}
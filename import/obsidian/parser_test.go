@@ -0,0 +1,314 @@
+package obsidian
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDailyNoteFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		layout   string
+		wantDate time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "default format",
+			filename: "2024-01-15.md",
+			wantDate: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "custom format",
+			filename: "15.01.2024.md",
+			layout:   "02.01.2006",
+			wantDate: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "not matching default format",
+			filename: "2024_01_15.md",
+			wantErr:  true,
+		},
+		{
+			name:     "regular note",
+			filename: "Project Notes.md",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDailyNoteFilename(tt.filename, tt.layout)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseDailyNoteFilename() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !got.Equal(tt.wantDate) {
+				t.Errorf("ParseDailyNoteFilename() = %v, want %v", got, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestParseNoteFilename(t *testing.T) {
+	if got := ParseNoteFilename("folder/Project Notes.md"); got != "Project Notes" {
+		t.Errorf("ParseNoteFilename() = %q, want %q", got, "Project Notes")
+	}
+}
+
+func TestParseFrontmatter(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantTags    []string
+		wantAliases []string
+		wantBody    string
+	}{
+		{
+			name:        "list values",
+			content:     "---\ntags:\n  - work\n  - \"#urgent\"\naliases: [PX, Project X]\n---\nBody text",
+			wantTags:    []string{"work", "urgent"},
+			wantAliases: []string{"PX", "Project X"},
+			wantBody:    "Body text",
+		},
+		{
+			name:        "comma separated values",
+			content:     "---\ntag: work, home\nalias: Home\n---\n",
+			wantTags:    []string{"work", "home"},
+			wantAliases: []string{"Home"},
+			wantBody:    "",
+		},
+		{
+			name:     "no frontmatter",
+			content:  "Just text\n---\nmore",
+			wantBody: "Just text\n---\nmore",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yamlText, body := SplitFrontmatter(tt.content)
+			if body != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+
+			fm, err := ParseFrontmatter(yamlText)
+			if err != nil {
+				t.Fatalf("ParseFrontmatter() error = %v", err)
+			}
+			if strings.Join(fm.Tags, "|") != strings.Join(tt.wantTags, "|") {
+				t.Errorf("Tags = %v, want %v", fm.Tags, tt.wantTags)
+			}
+			if strings.Join(fm.Aliases, "|") != strings.Join(tt.wantAliases, "|") {
+				t.Errorf("Aliases = %v, want %v", fm.Aliases, tt.wantAliases)
+			}
+		})
+	}
+}
+
+func TestParseContent(t *testing.T) {
+	type wantBlock struct {
+		content string
+		indent  int
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    []wantBlock
+	}{
+		{
+			name:    "empty content",
+			content: "",
+			want:    []wantBlock{{"", 0}},
+		},
+		{
+			name:    "paragraphs",
+			content: "First line\nsecond line\n\nAnother paragraph",
+			want: []wantBlock{
+				{"First line\nsecond line", 0},
+				{"Another paragraph", 0},
+			},
+		},
+		{
+			name:    "headings nest content",
+			content: "# Title\nIntro\n## Section\n- item\n# Other",
+			want: []wantBlock{
+				{"# Title", 0},
+				{"Intro", 1},
+				{"## Section", 1},
+				{"item", 2},
+				{"# Other", 0},
+			},
+		},
+		{
+			name:    "nested lists with tabs and spaces",
+			content: "- one\n\t- two\n\t\t- three\n- four\n  - five",
+			want: []wantBlock{
+				{"one", 0},
+				{"two", 1},
+				{"three", 2},
+				{"four", 0},
+				{"five", 1},
+			},
+		},
+		{
+			name:    "ordered list keeps numbers",
+			content: "1. first\n2. second",
+			want: []wantBlock{
+				{"1. first", 0},
+				{"2. second", 0},
+			},
+		},
+		{
+			name:    "tasks",
+			content: "- [ ] open task\n- [x] finished task",
+			want: []wantBlock{
+				{"open task /TODO", 0},
+				{"finished task /DONE", 0},
+			},
+		},
+		{
+			name:    "list item continuation",
+			content: "- item\n  more text\n- next",
+			want: []wantBlock{
+				{"item\nmore text", 0},
+				{"next", 0},
+			},
+		},
+		{
+			name:    "code block kept whole",
+			content: "```go\nfunc main() {\n\n\tfmt.Println(\"# not a heading\")\n}\n```\nAfter",
+			want: []wantBlock{
+				{"```go\nfunc main() {\n\n\tfmt.Println(\"# not a heading\")\n}\n```", 0},
+				{"After", 0},
+			},
+		},
+		{
+			name:    "aliased links rewritten, embeds kept",
+			content: "See [[Project X|the project]] and ![[Meeting Notes]]",
+			want: []wantBlock{
+				{"See the project ([[Project X]]) and ![[Meeting Notes]]", 0},
+			},
+		},
+		{
+			name:    "comments and rules removed",
+			content: "Visible %%hidden%% text\n\n---\n\nNext",
+			want: []wantBlock{
+				{"Visible  text", 0},
+				{"Next", 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := ParseContent(tt.content, nil)
+			if len(blocks) != len(tt.want) {
+				for i, b := range blocks {
+					t.Logf("block %d: indent=%d content=%q", i, b.Indent, b.Content)
+				}
+				t.Fatalf("got %d blocks, want %d", len(blocks), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if blocks[i].Content != want.content {
+					t.Errorf("block %d content = %q, want %q", i, blocks[i].Content, want.content)
+				}
+				if blocks[i].Indent != want.indent {
+					t.Errorf("block %d indent = %d, want %d", i, blocks[i].Indent, want.indent)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertAttachmentLinks(t *testing.T) {
+	resolve := func(target string) (string, bool) {
+		if strings.HasSuffix(target, "diagram.png") {
+			return "./assets/diagram.png", true
+		}
+		return "", false
+	}
+
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "attachment embed",
+			line: "Look ![[diagram.png|300]]",
+			want: "Look ![diagram.png](./assets/diagram.png)",
+		},
+		{
+			name: "markdown image",
+			line: "![A diagram](images/diagram.png)",
+			want: "![A diagram](./assets/diagram.png)",
+		},
+		{
+			name: "note embed kept",
+			line: "![[Some Note]]",
+			want: "![[Some Note]]",
+		},
+		{
+			name: "unknown attachment kept",
+			line: "![[missing.pdf]]",
+			want: "![[missing.pdf]]",
+		},
+		{
+			name: "remote image kept",
+			line: "![logo](https://example.com/diagram.png)",
+			want: "![logo](https://example.com/diagram.png)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConvertAttachmentLinks(tt.line, resolve); got != tt.want {
+				t.Errorf("ConvertAttachmentLinks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertWikiLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"plain link kept", "See [[Project X]]", "See [[Project X]]"},
+		{"alias", "See [[Project X|the project]]", "See the project ([[Project X]])"},
+		{"alias matching the title", "See [[Project X|project x]]", "See [[Project X]]"},
+		{"heading", "See [[Project X#Goals]]", "See [[Project X]]"},
+		{"heading and alias", "See [[Project X#Goals|our goals]]", "See our goals ([[Project X]])"},
+		{"heading in the note", "See [[#Goals]] and [[#Goals|below]]", "See Goals and below"},
+		{"embed kept", "![[diagram.png|300]]", "![[diagram.png|300]]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConvertWikiLinks(tt.line); got != tt.want {
+				t.Errorf("ConvertWikiLinks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNoteAddsPropertiesBlock(t *testing.T) {
+	note, err := ParseNote("---\ntags: [work]\naliases: [PX]\n---\n- item", nil)
+	if err != nil {
+		t.Fatalf("ParseNote() error = %v", err)
+	}
+
+	if len(note.Blocks) != 2 {
+		t.Fatalf("got %d blocks, want 2", len(note.Blocks))
+	}
+	if note.Blocks[0].Content != "tags:: work\nalias:: PX" {
+		t.Errorf("properties block = %q", note.Blocks[0].Content)
+	}
+	if note.Blocks[1].Content != "item" {
+		t.Errorf("second block = %q, want %q", note.Blocks[1].Content, "item")
+	}
+}
@@ -0,0 +1,99 @@
+package opml
+
+import (
+	"fmt"
+	"glog/import/common"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImportOptions configures the import behavior.
+type ImportOptions struct {
+	Paths   []string // OPML files to import, one document each
+	DBPath  string   // Path to glog database file
+	DryRun  bool     // If true, don't actually write to database
+	Verbose bool     // If true, print detailed progress
+}
+
+// RenameInfo tracks when a document was renamed due to duplicate title.
+type RenameInfo = common.RenameInfo
+
+// ImportResult contains the results of an import operation.
+type ImportResult = common.ImportResult
+
+// Import imports each OPML file as a single glog document. Files without a
+// head title are named after the file.
+func Import(opts ImportOptions) (*ImportResult, error) {
+	result := common.NewImportResult()
+
+	var store common.Store
+	if !opts.DryRun {
+		s, err := common.OpenStore(opts.DBPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+		defer s.Close()
+		store = s
+	}
+
+	for _, path := range opts.Paths {
+		if err := importFile(store, path, opts, result); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("%s: %w", path, err))
+			if opts.Verbose {
+				fmt.Printf("  Error importing %s: %v\n", path, err)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// importFile imports a single OPML file.
+func importFile(store common.Store, path string, opts ImportOptions, result *ImportResult) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	doc, err := Parse(f)
+	if err != nil {
+		return err
+	}
+	if doc.Title == "" {
+		doc.Title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	originalTitle := doc.Title
+
+	// Check for duplicates and find unique title
+	if store != nil {
+		doc.Title = common.FindUniqueTitle(store, doc.Title)
+		if doc.Title != originalTitle {
+			result.Renamed = append(result.Renamed, RenameInfo{
+				OriginalTitle: originalTitle,
+				NewTitle:      doc.Title,
+			})
+		}
+	}
+
+	if opts.Verbose {
+		fmt.Printf("  Importing: %s\n", doc.Title)
+	}
+
+	// Save to database (unless dry run)
+	if !opts.DryRun && store != nil {
+		if err := store.Save(doc); err != nil {
+			return fmt.Errorf("failed to save: %w", err)
+		}
+	}
+
+	if doc.IsJournal {
+		result.JournalsImported++
+	} else {
+		result.PagesImported++
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+// Package opml converts glog documents to and from OPML outlines.
+//
+// Glog documents are flat block lists where nesting is expressed by
+// Block.Indent; OPML nests <outline> elements. Each block maps to one
+// outline: the first line of the block content becomes the "text"
+// attribute and any further lines become the "_note" attribute, so
+// multi-line blocks survive a round trip.
+package opml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"glog/domain"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// journalTitleLayout is glog's journal title format.
+const journalTitleLayout = "Monday, January 2, 2006"
+
+// OPML is the root element of an OPML file.
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+// Head is the head element of an OPML file.
+type Head struct {
+	Title        string `xml:"title,omitempty"`
+	DateCreated  string `xml:"dateCreated,omitempty"`
+	DateModified string `xml:"dateModified,omitempty"`
+}
+
+// Body is the body element of an OPML file.
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+// Outline is a single, possibly nested, outline node.
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Note     string    `xml:"_note,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// Export renders a document as an OPML 2.0 file.
+func Export(doc *domain.Document) ([]byte, error) {
+	file := OPML{
+		Version: "2.0",
+		Head: Head{
+			Title:       doc.Title,
+			DateCreated: doc.Date.Format(time.RFC1123Z),
+		},
+		Body: Body{Outlines: buildOutlines(doc.Blocks)},
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(file); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// buildOutlines nests the flat block list into outlines. A block becomes a
+// child of the closest preceding block with a smaller indent, the same way
+// parents are computed for backlinks.
+func buildOutlines(blocks []*domain.Block) []Outline {
+	var roots []Outline
+	// stack holds the path from a root outline to the last added outline
+	type frame struct {
+		indent int
+		path   []int
+	}
+	var stack []frame
+
+	for _, block := range blocks {
+		if block == nil {
+			continue
+		}
+		for len(stack) > 0 && stack[len(stack)-1].indent >= block.Indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		text, note, _ := strings.Cut(block.Content, "\n")
+		outline := Outline{Text: text, Note: note}
+
+		var path []int
+		if len(stack) == 0 {
+			roots = append(roots, outline)
+			path = []int{len(roots) - 1}
+		} else {
+			parentPath := stack[len(stack)-1].path
+			parent := outlineAt(roots, parentPath)
+			parent.Outlines = append(parent.Outlines, outline)
+			path = append(append([]int(nil), parentPath...), len(parent.Outlines)-1)
+		}
+		stack = append(stack, frame{indent: block.Indent, path: path})
+	}
+
+	return roots
+}
+
+func outlineAt(roots []Outline, path []int) *Outline {
+	outline := &roots[path[0]]
+	for _, i := range path[1:] {
+		outline = &outline.Outlines[i]
+	}
+	return outline
+}
+
+// Parse reads an OPML file and converts it into a glog document with a new
+// ID. The title comes from the OPML head; titles in glog's journal format
+// ("Monday, January 2, 2006") produce journal documents for that date.
+// Nested outlines become blocks indented by their depth, and "_note"
+// attributes are appended to the block content as continuation lines.
+func Parse(r io.Reader) (*domain.Document, error) {
+	var file OPML
+	if err := xml.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	if file.XMLName.Local != "opml" {
+		return nil, errors.New("not an OPML document")
+	}
+
+	doc := &domain.Document{
+		ID:     domain.DocumentID(uuid.New()),
+		Title:  strings.TrimSpace(file.Head.Title),
+		Date:   time.Now(),
+		Blocks: FlattenOutlines(file.Body.Outlines),
+	}
+
+	if date, err := time.Parse(journalTitleLayout, doc.Title); err == nil {
+		doc.IsJournal = true
+		doc.Date = date
+	} else if date, err := time.Parse(time.RFC1123Z, file.Head.DateCreated); err == nil {
+		doc.Date = date
+	}
+
+	return doc, nil
+}
+
+// FlattenOutlines converts nested outlines into a flat block list with
+// Indent set to the outline depth.
+func FlattenOutlines(outlines []Outline) []*domain.Block {
+	var blocks []*domain.Block
+	var walk func(outlines []Outline, depth int)
+	walk = func(outlines []Outline, depth int) {
+		for _, outline := range outlines {
+			content := outline.Text
+			if outline.Note != "" {
+				content += "\n" + outline.Note
+			}
+			blocks = append(blocks, &domain.Block{
+				ID:      domain.BlockID(uuid.New()),
+				Content: content,
+				Indent:  depth,
+			})
+			walk(outline.Outlines, depth+1)
+		}
+	}
+	walk(outlines, 0)
+
+	// If no blocks were created, create an empty one
+	if len(blocks) == 0 {
+		blocks = append(blocks, &domain.Block{
+			ID:      domain.BlockID(uuid.New()),
+			Content: "",
+			Indent:  0,
+		})
+	}
+
+	return blocks
+}
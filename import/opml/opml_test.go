@@ -0,0 +1,132 @@
+package opml
+
+import (
+	"bytes"
+	"glog/domain"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newBlock(content string, indent int) *domain.Block {
+	return &domain.Block{
+		ID:      domain.BlockID(uuid.New()),
+		Content: content,
+		Indent:  indent,
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	doc := &domain.Document{
+		ID:    domain.DocumentID(uuid.New()),
+		Title: "Project & Plans",
+		Date:  time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+		Blocks: []*domain.Block{
+			newBlock("Goals", 0),
+			newBlock("Ship <v1>\nwith \"quotes\" and a second line", 1),
+			newBlock("Details", 2),
+			newBlock("Risks", 0),
+			newBlock("", 1),
+		},
+	}
+
+	data, err := Export(doc)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if !strings.Contains(string(data), `_note="with &#34;quotes&#34; and a second line"`) {
+		t.Errorf("Expected second line to be exported as _note, got:\n%s", data)
+	}
+
+	got, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if got.Title != doc.Title {
+		t.Errorf("Title = %q, want %q", got.Title, doc.Title)
+	}
+	if !got.Date.Equal(doc.Date) {
+		t.Errorf("Date = %v, want %v", got.Date, doc.Date)
+	}
+	if len(got.Blocks) != len(doc.Blocks) {
+		t.Fatalf("got %d blocks, want %d", len(got.Blocks), len(doc.Blocks))
+	}
+	for i, block := range doc.Blocks {
+		if got.Blocks[i].Content != block.Content {
+			t.Errorf("block %d content = %q, want %q", i, got.Blocks[i].Content, block.Content)
+		}
+		if got.Blocks[i].Indent != block.Indent {
+			t.Errorf("block %d indent = %d, want %d", i, got.Blocks[i].Indent, block.Indent)
+		}
+	}
+}
+
+func TestExportNormalizesIndentJumps(t *testing.T) {
+	doc := &domain.Document{
+		Title:  "Jumps",
+		Blocks: []*domain.Block{newBlock("root", 0), newBlock("deep", 3), newBlock("sibling", 3)},
+	}
+
+	data, err := Export(doc)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	got, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []int{0, 1, 1}
+	for i, indent := range want {
+		if got.Blocks[i].Indent != indent {
+			t.Errorf("block %d indent = %d, want %d", i, got.Blocks[i].Indent, indent)
+		}
+	}
+}
+
+func TestParseJournalTitle(t *testing.T) {
+	input := `<?xml version="1.0"?>
+<opml version="2.0"><head><title>Monday, January 15, 2024</title></head>
+<body><outline text="Standup"/></body></opml>`
+
+	doc, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !doc.IsJournal {
+		t.Error("Expected IsJournal to be true")
+	}
+	if !doc.Date.Equal(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Date = %v", doc.Date)
+	}
+}
+
+func TestParseRejectsOtherXML(t *testing.T) {
+	if _, err := Parse(strings.NewReader(`<rss><channel/></rss>`)); err == nil {
+		t.Error("Expected error for non-OPML input")
+	}
+}
+
+func TestImport(t *testing.T) {
+	tmpDir := t.TempDir()
+	opmlPath := filepath.Join(tmpDir, "Reading List.opml")
+	input := `<opml version="2.0"><head/><body>
+<outline text="Books"><outline text="Dune" _note="Frank Herbert"/></outline>
+</body></opml>`
+	if err := os.WriteFile(opmlPath, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Import(ImportOptions{Paths: []string{opmlPath}, DryRun: true})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(result.Errors) > 0 || result.PagesImported != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
@@ -0,0 +1,141 @@
+package roam
+
+import (
+	"errors"
+	"fmt"
+	"glog/domain"
+	"glog/import/common"
+	"os"
+)
+
+// ImportOptions configures the import behavior.
+type ImportOptions struct {
+	ExportPath   string // Path to the Roam JSON export file
+	DBPath       string // Path to glog database file
+	JournalsOnly bool   // If true, only import daily pages
+	PagesOnly    bool   // If true, only import regular pages
+	DryRun       bool   // If true, don't actually write to database
+	Verbose      bool   // If true, print detailed progress
+}
+
+// RenameInfo tracks when a document was renamed due to duplicate title.
+type RenameInfo = common.RenameInfo
+
+// ImportResult contains the results of an import operation.
+type ImportResult = common.ImportResult
+
+// Importer handles importing a Roam JSON export into glog.
+type Importer struct {
+	opts   ImportOptions
+	store  common.Store
+	result *ImportResult
+}
+
+// NewImporter creates a new Importer with the given options.
+func NewImporter(opts ImportOptions) *Importer {
+	return &Importer{
+		opts:   opts,
+		result: common.NewImportResult(),
+	}
+}
+
+// Import performs the import operation.
+func (imp *Importer) Import() (*ImportResult, error) {
+	info, err := os.Stat(imp.opts.ExportPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Roam export path: %w", err)
+	}
+	if info.IsDir() {
+		return nil, errors.New("invalid Roam export path: expected a JSON file, got a directory")
+	}
+
+	pages, err := ReadExport(imp.opts.ExportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Roam export: %w", err)
+	}
+
+	if imp.opts.Verbose {
+		fmt.Printf("Found %d pages in export\n", len(pages))
+	}
+
+	// Open database (unless dry run)
+	if !imp.opts.DryRun {
+		store, err := common.OpenStore(imp.opts.DBPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+		defer store.Close()
+		imp.store = store
+	}
+
+	// Block IDs are assigned up front so ((uid)) references can point at
+	// blocks on pages that are imported later.
+	blockIDs := AssignBlockIDs(pages)
+
+	for _, page := range pages {
+		if err := imp.importPage(page, blockIDs); err != nil {
+			imp.result.Errors = append(imp.result.Errors, fmt.Errorf("page %q: %w", page.Title, err))
+			if imp.opts.Verbose {
+				fmt.Printf("  Error importing %s: %v\n", page.Title, err)
+			}
+		}
+	}
+
+	return imp.result, nil
+}
+
+// importPage imports a single Roam page.
+func (imp *Importer) importPage(page Page, blockIDs map[string]domain.BlockID) error {
+	if page.Title == "" {
+		return errors.New("page has no title")
+	}
+
+	doc := ConvertPage(page, blockIDs)
+	if (doc.IsJournal && imp.opts.PagesOnly) || (!doc.IsJournal && imp.opts.JournalsOnly) {
+		imp.result.Skipped++
+		return nil
+	}
+
+	originalTitle := doc.Title
+
+	// Check for duplicates and find unique title
+	if imp.store != nil {
+		doc.Title = common.FindUniqueTitle(imp.store, doc.Title)
+		if doc.Title != originalTitle {
+			imp.result.Renamed = append(imp.result.Renamed, RenameInfo{
+				OriginalTitle: originalTitle,
+				NewTitle:      doc.Title,
+			})
+		}
+	}
+
+	if imp.opts.Verbose {
+		if doc.Title != originalTitle {
+			fmt.Printf("  Importing: %s -> %s\n", originalTitle, doc.Title)
+		} else {
+			fmt.Printf("  Importing: %s\n", doc.Title)
+		}
+	}
+
+	// Save to database (unless dry run)
+	if !imp.opts.DryRun && imp.store != nil {
+		if err := imp.store.Save(doc); err != nil {
+			return fmt.Errorf("failed to save: %w", err)
+		}
+	}
+
+	// Update counts
+	if doc.IsJournal {
+		imp.result.JournalsImported++
+	} else {
+		imp.result.PagesImported++
+	}
+
+	return nil
+}
+
+// Import is a convenience function that creates an Importer and runs the import.
+func Import(opts ImportOptions) (*ImportResult, error) {
+	importer := NewImporter(opts)
+	return importer.Import()
+}
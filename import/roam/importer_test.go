@@ -0,0 +1,71 @@
+package roam
+
+import (
+	"glog/db"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestImport(t *testing.T) {
+	tmpDir := t.TempDir()
+	exportPath := filepath.Join(tmpDir, "graph.json")
+	dbPath := filepath.Join(tmpDir, "glog.db")
+	export := `[
+  {"title": "October 17th, 2026", "children": [{"string": "{{[[DONE]]}} Review [[Roadmap]]", "uid": "r1r1r1r1r"}]},
+  {"title": "Roadmap", "create-time": 1700000000000, "edit-time": 1800000000000, "children": [{"string": "Q4", "uid": "q4q4q4q4q", "children": [{"string": "ref ((r1r1r1r1r)) and ((unknown99))", "create-time": 1750000000000, "edit-time": 1760000000000}]}]}
+]`
+	if err := os.WriteFile(exportPath, []byte(export), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Import(ImportOptions{ExportPath: exportPath, DBPath: dbPath})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("Import() errors = %v", result.Errors)
+	}
+	if result.JournalsImported != 1 || result.PagesImported != 1 {
+		t.Errorf("imported %d journals and %d pages, want 1 and 1", result.JournalsImported, result.PagesImported)
+	}
+
+	store, err := db.NewDocumentStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open DocumentStore: %v", err)
+	}
+	defer store.Close()
+
+	journal, err := store.LoadDocumentByTitle("Saturday, October 17, 2026")
+	if err != nil {
+		t.Fatalf("journal not imported: %v", err)
+	}
+	if !journal.IsJournal || !journal.Date.Equal(time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected a journal dated from its title, got %v (journal %v)", journal.Date, journal.IsJournal)
+	}
+	if len(journal.Blocks) != 1 || journal.Blocks[0].Content != "Review [[Roadmap]] /DONE" {
+		t.Fatalf("unexpected journal blocks: %+v", journal.Blocks)
+	}
+
+	page, err := store.LoadDocumentByTitle("Roadmap")
+	if err != nil {
+		t.Fatalf("page not imported: %v", err)
+	}
+	if !page.Date.Equal(time.UnixMilli(1700000000000)) {
+		t.Errorf("Expected the page dated by its create time, got %v", page.Date)
+	}
+	if len(page.Blocks) != 3 || page.Blocks[2].Indent != 1 {
+		t.Fatalf("unexpected page blocks: %+v", page.Blocks)
+	}
+
+	// Create and edit times are kept as properties
+	props := db.PageProperties(page)
+	if props["created"] != "2023-11-14T22:13:20Z" || props["updated"] != "2027-01-15T08:00:00Z" {
+		t.Errorf("page times not kept as properties: %v", props)
+	}
+	want := "ref ((" + journal.Blocks[0].ID.String() + ")) and ((unknown99))\ncreated:: 2025-06-15T15:06:40Z\nupdated:: 2025-10-09T08:53:20Z"
+	if page.Blocks[2].Content != want {
+		t.Errorf("block reference or times not kept: %q, want %q", page.Blocks[2].Content, want)
+	}
+}
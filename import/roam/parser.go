@@ -0,0 +1,254 @@
+// Package roam imports Roam Research JSON exports into glog.
+//
+// A Roam export is a list of pages, each holding a tree of blocks in nested
+// "children" arrays. Pages are flattened into glog documents whose blocks
+// carry the tree depth in Block.Indent. Daily pages ("October 17th, 2026")
+// become journals. Glog stores a single date per document, so a page's
+// create time (or edit time, if the create time is missing) becomes the
+// document date; journals use the date from their title.
+//
+// The create and edit times of pages and blocks are kept as created:: and
+// updated:: properties: a page gets a first block holding its properties,
+// and a block gets property lines after its content.
+package roam
+
+import (
+	"encoding/json"
+	"glog/domain"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Page is a page in a Roam JSON export.
+type Page struct {
+	Title      string  `json:"title"`
+	UID        string  `json:"uid"`
+	CreateTime int64   `json:"create-time"` // Unix milliseconds
+	EditTime   int64   `json:"edit-time"`   // Unix milliseconds
+	Children   []Block `json:"children"`
+}
+
+// Block is a block in a Roam JSON export.
+type Block struct {
+	String     string  `json:"string"`
+	UID        string  `json:"uid"`
+	Heading    int     `json:"heading"`
+	CreateTime int64   `json:"create-time"` // Unix milliseconds
+	EditTime   int64   `json:"edit-time"`   // Unix milliseconds
+	Children   []Block `json:"children"`
+}
+
+var (
+	// blockRefRegex matches Roam block references: ((uid))
+	blockRefRegex = regexp.MustCompile(`\(\(([A-Za-z0-9_-]{6,})\)\)`)
+
+	// todoRegex and doneRegex match Roam task markers: {{[[TODO]]}} / {{TODO}}
+	todoRegex = regexp.MustCompile(`\{\{\s*(?:\[\[)?TODO(?:\]\])?\s*\}\}\s*`)
+	doneRegex = regexp.MustCompile(`\{\{\s*(?:\[\[)?DONE(?:\]\])?\s*\}\}\s*`)
+
+	// ordinalRegex matches the ordinal suffix of a day in Roam daily page titles
+	ordinalRegex = regexp.MustCompile(`^(\w+ \d{1,2})(?:st|nd|rd|th), (\d{4})$`)
+
+	// pageRefRegex matches page references: [[Title]]
+	pageRefRegex = regexp.MustCompile(`\[\[([^\[\]]+)\]\]`)
+)
+
+// ReadExport reads and decodes a Roam JSON export file.
+func ReadExport(path string) ([]Page, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []Page
+	if err := json.Unmarshal(data, &pages); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// ParseDailyTitle parses a Roam daily page title such as "October 17th, 2026".
+func ParseDailyTitle(title string) (time.Time, error) {
+	match := ordinalRegex.FindStringSubmatch(strings.TrimSpace(title))
+	if match == nil {
+		return time.Parse("January 2, 2006", title) // returns the parse error
+	}
+	return time.Parse("January 2, 2006", match[1]+", "+match[2])
+}
+
+// JournalTitleFromDate formats a date into glog's journal title format.
+// Example: "Monday, January 2, 2006"
+func JournalTitleFromDate(date time.Time) string {
+	return date.Format("Monday, January 2, 2006")
+}
+
+// ConvertTaskMarkers converts Roam task markers to glog task markers.
+// Input:  "{{[[TODO]]}} Write report"
+// Output: "Write report /TODO"
+func ConvertTaskMarkers(content string) string {
+	switch {
+	case doneRegex.MatchString(content):
+		return strings.TrimSpace(doneRegex.ReplaceAllString(content, "")) + " /DONE"
+	case todoRegex.MatchString(content):
+		return strings.TrimSpace(todoRegex.ReplaceAllString(content, "")) + " /TODO"
+	}
+	return content
+}
+
+// ConvertDailyLinks rewrites links to Roam daily pages ("[[October 17th, 2026]]")
+// to glog journal titles ("[[Saturday, October 17, 2026]]").
+func ConvertDailyLinks(content string) string {
+	return pageRefRegex.ReplaceAllStringFunc(content, func(match string) string {
+		date, err := ParseDailyTitle(match[2 : len(match)-2])
+		if err != nil {
+			return match
+		}
+		return "[[" + JournalTitleFromDate(date) + "]]"
+	})
+}
+
+// RewriteBlockRefs replaces Roam block references with references to the
+// glog block IDs assigned during import: ((uid)) -> ((block-uuid)).
+// References to blocks outside the export are left unchanged.
+func RewriteBlockRefs(content string, blockIDs map[string]domain.BlockID) string {
+	return blockRefRegex.ReplaceAllStringFunc(content, func(match string) string {
+		id, ok := blockIDs[match[2:len(match)-2]]
+		if !ok {
+			return match
+		}
+		return "((" + id.String() + "))"
+	})
+}
+
+// AssignBlockIDs creates a glog block ID for every block in the export,
+// keyed by Roam uid, so block references can be resolved across pages.
+func AssignBlockIDs(pages []Page) map[string]domain.BlockID {
+	ids := make(map[string]domain.BlockID)
+	var walk func(blocks []Block)
+	walk = func(blocks []Block) {
+		for _, block := range blocks {
+			if block.UID != "" {
+				ids[block.UID] = domain.BlockID(uuid.New())
+			}
+			walk(block.Children)
+		}
+	}
+	for _, page := range pages {
+		walk(page.Children)
+	}
+	return ids
+}
+
+// FlattenBlocks walks the nested children tree depth-first and returns the
+// blocks as a flat glog outline with Indent set to the tree depth. Block
+// create and edit times follow the content as TimeProperties lines.
+func FlattenBlocks(children []Block, blockIDs map[string]domain.BlockID) []*domain.Block {
+	var blocks []*domain.Block
+	var walk func(children []Block, depth int)
+	walk = func(children []Block, depth int) {
+		for _, child := range children {
+			id, ok := blockIDs[child.UID]
+			if !ok {
+				id = domain.BlockID(uuid.New())
+			}
+
+			content := ConvertTaskMarkers(child.String)
+			content = ConvertDailyLinks(content)
+			content = RewriteBlockRefs(content, blockIDs)
+			if child.Heading > 0 && child.Heading <= 6 {
+				content = strings.Repeat("#", child.Heading) + " " + content
+			}
+			if props := TimeProperties(child.CreateTime, child.EditTime); props != "" {
+				content += "\n" + props
+			}
+
+			blocks = append(blocks, &domain.Block{
+				ID:      id,
+				Content: content,
+				Indent:  depth,
+			})
+			walk(child.Children, depth+1)
+		}
+	}
+	walk(children, 0)
+
+	// If no blocks were created, create an empty one
+	if len(blocks) == 0 {
+		blocks = append(blocks, &domain.Block{
+			ID:      domain.BlockID(uuid.New()),
+			Content: "",
+			Indent:  0,
+		})
+	}
+
+	return blocks
+}
+
+// TimeProperties renders Roam create and edit times (Unix milliseconds, 0
+// when missing) as "created:: " and "updated:: " property lines in RFC 3339
+// format. It returns an empty string if both are missing.
+func TimeProperties(createTime int64, editTime int64) string {
+	var lines []string
+	if createTime > 0 {
+		lines = append(lines, "created:: "+time.UnixMilli(createTime).UTC().Format(time.RFC3339))
+	}
+	if editTime > 0 {
+		lines = append(lines, "updated:: "+time.UnixMilli(editTime).UTC().Format(time.RFC3339))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ConvertPage converts a Roam page to a glog document.
+func ConvertPage(page Page, blockIDs map[string]domain.BlockID) *domain.Document {
+	doc := &domain.Document{
+		ID:     domain.DocumentID(uuid.New()),
+		Title:  page.Title,
+		Blocks: FlattenBlocks(page.Children, blockIDs),
+	}
+	if props := TimeProperties(page.CreateTime, page.EditTime); props != "" {
+		propsBlock := &domain.Block{ID: domain.BlockID(uuid.New()), Content: props}
+		doc.Blocks = append([]*domain.Block{propsBlock}, doc.Blocks...)
+	}
+
+	if date, err := ParseDailyTitle(page.Title); err == nil {
+		doc.IsJournal = true
+		doc.Title = JournalTitleFromDate(date)
+		doc.Date = date
+		return doc
+	}
+
+	doc.Date = pageDate(page)
+	return doc
+}
+
+// pageDate returns the page's create time, falling back to its edit time and
+// then to the earliest create time of its blocks.
+func pageDate(page Page) time.Time {
+	switch {
+	case page.CreateTime > 0:
+		return time.UnixMilli(page.CreateTime)
+	case page.EditTime > 0:
+		return time.UnixMilli(page.EditTime)
+	}
+
+	var earliest int64
+	var walk func(blocks []Block)
+	walk = func(blocks []Block) {
+		for _, block := range blocks {
+			if block.CreateTime > 0 && (earliest == 0 || block.CreateTime < earliest) {
+				earliest = block.CreateTime
+			}
+			walk(block.Children)
+		}
+	}
+	walk(page.Children)
+
+	if earliest > 0 {
+		return time.UnixMilli(earliest)
+	}
+	return time.Now()
+}
@@ -0,0 +1,174 @@
+package roam
+
+import (
+	"glog/domain"
+	"testing"
+	"time"
+)
+
+func TestParseDailyTitle(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    string
+		wantDate time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "th suffix",
+			title:    "October 17th, 2026",
+			wantDate: time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "st suffix",
+			title:    "January 1st, 2024",
+			wantDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "nd suffix",
+			title:    "March 22nd, 2025",
+			wantDate: time.Date(2025, 3, 22, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "regular page",
+			title:   "Project Alpha",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDailyTitle(tt.title)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseDailyTitle() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !got.Equal(tt.wantDate) {
+				t.Errorf("ParseDailyTitle() = %v, want %v", got, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestConvertTaskMarkers(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"{{[[TODO]]}} Write report", "Write report /TODO"},
+		{"{{[[DONE]]}} Write report", "Write report /DONE"},
+		{"{{TODO}} Short form", "Short form /TODO"},
+		{"No task here", "No task here"},
+	}
+
+	for _, tt := range tests {
+		if got := ConvertTaskMarkers(tt.input); got != tt.want {
+			t.Errorf("ConvertTaskMarkers(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestConvertDailyLinks(t *testing.T) {
+	got := ConvertDailyLinks("Follow up on [[October 17th, 2026]] with [[Project]]")
+	want := "Follow up on [[Saturday, October 17, 2026]] with [[Project]]"
+	if got != want {
+		t.Errorf("ConvertDailyLinks() = %q, want %q", got, want)
+	}
+}
+
+func TestFlattenBlocks(t *testing.T) {
+	pages := []Page{
+		{
+			Title: "Project",
+			Children: []Block{
+				{String: "Goals", UID: "aaaaaaaaa", Heading: 2, Children: []Block{
+					{String: "{{[[TODO]]}} Ship it", UID: "bbbbbbbbb", Children: []Block{
+						{String: "See ((aaaaaaaaa)) and ((unknown123))", UID: "ccccccccc"},
+					}},
+				}},
+				{String: "Notes", UID: "ddddddddd", CreateTime: 1700000000000, EditTime: 1800000000000},
+			},
+		},
+	}
+
+	ids := AssignBlockIDs(pages)
+	blocks := FlattenBlocks(pages[0].Children, ids)
+
+	want := []struct {
+		content string
+		indent  int
+	}{
+		{"## Goals", 0},
+		{"Ship it /TODO", 1},
+		{"See ((" + ids["aaaaaaaaa"].String() + ")) and ((unknown123))", 2},
+		{"Notes\ncreated:: 2023-11-14T22:13:20Z\nupdated:: 2027-01-15T08:00:00Z", 0},
+	}
+
+	if len(blocks) != len(want) {
+		t.Fatalf("got %d blocks, want %d", len(blocks), len(want))
+	}
+	for i, w := range want {
+		if blocks[i].Content != w.content {
+			t.Errorf("block %d content = %q, want %q", i, blocks[i].Content, w.content)
+		}
+		if blocks[i].Indent != w.indent {
+			t.Errorf("block %d indent = %d, want %d", i, blocks[i].Indent, w.indent)
+		}
+	}
+	if blocks[0].ID != ids["aaaaaaaaa"] {
+		t.Error("Expected block to keep the ID assigned to its Roam uid")
+	}
+}
+
+func TestTimeProperties(t *testing.T) {
+	tests := []struct {
+		create, edit int64
+		want         string
+	}{
+		{1700000000000, 1800000000000, "created:: 2023-11-14T22:13:20Z\nupdated:: 2027-01-15T08:00:00Z"},
+		{0, 1800000000000, "updated:: 2027-01-15T08:00:00Z"},
+		{0, 0, ""},
+	}
+	for _, tt := range tests {
+		if got := TimeProperties(tt.create, tt.edit); got != tt.want {
+			t.Errorf("TimeProperties(%d, %d) = %q, want %q", tt.create, tt.edit, got, tt.want)
+		}
+	}
+}
+
+func TestConvertPage(t *testing.T) {
+	t.Run("daily page becomes journal", func(t *testing.T) {
+		doc := ConvertPage(Page{Title: "October 17th, 2026", CreateTime: 1}, map[string]domain.BlockID{})
+		if !doc.IsJournal {
+			t.Error("Expected IsJournal to be true")
+		}
+		if doc.Title != "Saturday, October 17, 2026" {
+			t.Errorf("Title = %q", doc.Title)
+		}
+		if !doc.Date.Equal(time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("Date = %v", doc.Date)
+		}
+		if len(doc.Blocks) != 2 || doc.Blocks[0].Content != "created:: 1970-01-01T00:00:00Z" || doc.Blocks[1].Content != "" {
+			t.Errorf("Expected a properties block and an empty block, got %+v", doc.Blocks)
+		}
+	})
+
+	t.Run("page uses create time", func(t *testing.T) {
+		doc := ConvertPage(Page{Title: "Page", CreateTime: 1700000000000, EditTime: 1800000000000}, map[string]domain.BlockID{})
+		if !doc.Date.Equal(time.UnixMilli(1700000000000)) {
+			t.Errorf("Date = %v", doc.Date)
+		}
+		if doc.Blocks[0].Content != "created:: 2023-11-14T22:13:20Z\nupdated:: 2027-01-15T08:00:00Z" {
+			t.Errorf("Expected the times as page properties, got %q", doc.Blocks[0].Content)
+		}
+	})
+
+	t.Run("page falls back to block create time", func(t *testing.T) {
+		page := Page{Title: "Page", Children: []Block{
+			{String: "a", CreateTime: 1750000000000, Children: []Block{{String: "b", CreateTime: 1650000000000}}},
+		}}
+		doc := ConvertPage(page, map[string]domain.BlockID{})
+		if !doc.Date.Equal(time.UnixMilli(1650000000000)) {
+			t.Errorf("Date = %v", doc.Date)
+		}
+	})
+}
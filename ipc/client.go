@@ -0,0 +1,331 @@
+package ipc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"glog/capture"
+	"glog/db"
+	"glog/domain"
+	"io"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// dialTimeout bounds how long connecting to the app may take.
+const dialTimeout = time.Second
+
+// Client calls the DocumentStore of the app serving a socket. Its methods
+// mirror the DocumentStore methods of the same name.
+type Client struct {
+	path string
+}
+
+// Dial connects to the app serving the socket at path.
+func Dial(path string) (*Client, error) {
+	c := &Client{path: path}
+	if err := c.call("ping", nil, nil); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close releases the client. Connections are per call, so there is
+// nothing to release; it exists to match DocumentStore.
+func (c *Client) Close() error {
+	return nil
+}
+
+func (c *Client) call(method string, params interface{}, result interface{}) error {
+	conn, err := net.DialTimeout("unix", c.path, dialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	req := Request{Method: method}
+	if params != nil {
+		if req.Params, err = json.Marshal(params); err != nil {
+			return err
+		}
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return err
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("ipc: invalid response to %s: %w", method, err)
+	}
+	if err := resp.err(); err != nil {
+		return err
+	}
+
+	if result != nil {
+		return json.Unmarshal(resp.Result, result)
+	}
+	return nil
+}
+
+// stream calls method like call and copies the chunks of its streamed
+// result to w as they arrive.
+func (c *Client) stream(method string, params interface{}, w io.Writer) error {
+	conn, err := net.DialTimeout("unix", c.path, dialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	req := Request{Method: method}
+	if params != nil {
+		if req.Params, err = json.Marshal(params); err != nil {
+			return err
+		}
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(conn)
+	for {
+		var resp Response
+		if err := dec.Decode(&resp); err != nil {
+			return fmt.Errorf("ipc: invalid response to %s: %w", method, err)
+		}
+		if resp.Chunk == "" {
+			return resp.err()
+		}
+		if _, err := io.WriteString(w, resp.Chunk); err != nil {
+			return err
+		}
+	}
+}
+
+func (resp Response) err() error {
+	if resp.Error == "" {
+		return nil
+	}
+	if target, ok := errorCodes[resp.Code]; ok {
+		return fmt.Errorf("%w (from the glog app)", target)
+	}
+	return errors.New(resp.Error)
+}
+
+func (c *Client) Search(query string) ([]domain.DocumentID, error) {
+	var ids []string
+	if err := c.call("search", queryParams{Query: query}, &ids); err != nil {
+		return nil, err
+	}
+	return parseIDs(ids)
+}
+
+func (c *Client) GetDocument(id domain.DocumentID) (*domain.Document, error) {
+	var doc db.DumpDocument
+	if err := c.call("get_document", idParams{ID: id.String()}, &doc); err != nil {
+		return nil, err
+	}
+	return doc.ToDomain()
+}
+
+func (c *Client) GetDocumentByTitle(title string) (*domain.Document, error) {
+	var doc db.DumpDocument
+	if err := c.call("get_document_by_title", titleParams{Title: title}, &doc); err != nil {
+		return nil, err
+	}
+	return doc.ToDomain()
+}
+
+func (c *Client) ForEachDocument(fn func(doc *domain.Document) error) error {
+	var docs []db.DumpDocument
+	if err := c.call("documents", nil, &docs); err != nil {
+		return err
+	}
+	for _, d := range docs {
+		doc, err := d.ToDomain()
+		if err != nil {
+			return err
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) LoadJournals(from time.Time, to time.Time) ([]*domain.Document, error) {
+	var docs []db.DumpDocument
+	params := journalsParams{From: from.Format(time.RFC3339), To: to.Format(time.RFC3339)}
+	if err := c.call("load_journals", params, &docs); err != nil {
+		return nil, err
+	}
+	result := make([]*domain.Document, len(docs))
+	for i, d := range docs {
+		doc, err := d.ToDomain()
+		if err != nil {
+			return nil, err
+		}
+		result[i] = doc
+	}
+	return result, nil
+}
+
+func (c *Client) GetScheduledTasks(date time.Time, days int) ([]domain.ScheduleTask, error) {
+	var tasks []scheduledTask
+	if err := c.call("scheduled_tasks", tasksParams{Date: date.Format(time.RFC3339), Days: days}, &tasks); err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.ScheduleTask, len(tasks))
+	for i, task := range tasks {
+		id, err := uuid.Parse(task.ID)
+		if err != nil {
+			return nil, err
+		}
+		docID, err := uuid.Parse(task.DocID)
+		if err != nil {
+			return nil, err
+		}
+		blockID, err := uuid.Parse(task.BlockID)
+		if err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339, task.Time)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = domain.ScheduleTask{
+			ID:      id,
+			DocID:   domain.DocumentID(docID),
+			BlockID: domain.BlockID(blockID),
+			Time:    t,
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) GetReferences(title string) ([]domain.DocumentID, error) {
+	var ids []string
+	if err := c.call("references", titleParams{Title: title}, &ids); err != nil {
+		return nil, err
+	}
+	return parseIDs(ids)
+}
+
+// GetIndexHealth reports an unhealthy index when the app cannot be reached.
+func (c *Client) GetIndexHealth() db.IndexHealth {
+	var health db.IndexHealth
+	if err := c.call("index_health", nil, &health); err != nil {
+		return db.IndexHealth{
+			LastHealthCheck:    time.Now(),
+			HealthCheckMessage: fmt.Sprintf("failed to reach the glog app: %v", err),
+		}
+	}
+	return health
+}
+
+func (c *Client) ReindexSearch() error {
+	return c.call("reindex", nil, nil)
+}
+
+func (c *Client) VerifySearchIndex() (*db.IndexVerification, error) {
+	var result db.IndexVerification
+	if err := c.call("verify_index", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) GetSearchAnalysis() (db.SearchAnalysis, error) {
+	var analysis db.SearchAnalysis
+	err := c.call("search_analysis", nil, &analysis)
+	return analysis, err
+}
+
+func (c *Client) SetSearchAnalysis(analysis db.SearchAnalysis) error {
+	return c.call("set_search_analysis", analysis, nil)
+}
+
+func (c *Client) Save(doc *domain.Document) error {
+	return c.call("save", db.NewDumpDocument(doc), nil)
+}
+
+func (c *Client) Delete(id uuid.UUID) error {
+	return c.call("delete", idParams{ID: id.String()}, nil)
+}
+
+// Capture appends text to the journal of now, like capture.Journal.
+func (c *Client) Capture(now time.Time, text string, opts capture.Options) (*domain.Document, error) {
+	params := captureParams{
+		Now:    now.Format(time.RFC3339),
+		Text:   text,
+		Indent: opts.Indent,
+		Under:  opts.Under,
+	}
+	if !opts.Scheduled.IsZero() {
+		params.Scheduled = opts.Scheduled.Format("2006-01-02")
+	}
+
+	var doc db.DumpDocument
+	if err := c.call("capture", params, &doc); err != nil {
+		return nil, err
+	}
+	return doc.ToDomain()
+}
+
+func (c *Client) Dump(w io.Writer) error {
+	return c.stream("dump", nil, w)
+}
+
+func parseIDs(ids []string) ([]domain.DocumentID, error) {
+	result := make([]domain.DocumentID, len(ids))
+	for i, s := range ids {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = domain.DocumentID(id)
+	}
+	return result, nil
+}
+
+// Store is the set of DocumentStore operations available over the socket.
+// It is implemented by both *db.DocumentStore and *Client.
+type Store interface {
+	Search(query string) ([]domain.DocumentID, error)
+	GetDocument(id domain.DocumentID) (*domain.Document, error)
+	GetDocumentByTitle(title string) (*domain.Document, error)
+	ForEachDocument(fn func(doc *domain.Document) error) error
+	LoadJournals(from time.Time, to time.Time) ([]*domain.Document, error)
+	GetScheduledTasks(date time.Time, days int) ([]domain.ScheduleTask, error)
+	GetReferences(title string) ([]domain.DocumentID, error)
+	GetIndexHealth() db.IndexHealth
+	ReindexSearch() error
+	VerifySearchIndex() (*db.IndexVerification, error)
+	GetSearchAnalysis() (db.SearchAnalysis, error)
+	SetSearchAnalysis(analysis db.SearchAnalysis) error
+	Save(doc *domain.Document) error
+	Delete(id uuid.UUID) error
+	Dump(w io.Writer) error
+	Close() error
+}
+
+var (
+	_ Store = (*db.DocumentStore)(nil)
+	_ Store = (*Client)(nil)
+)
+
+// Open connects to the app when it serves the database at path and opens
+// the database directly with opts otherwise.
+func Open(path string, opts db.Options) (Store, error) {
+	if client, err := Dial(SocketPath(path)); err == nil {
+		return client, nil
+	}
+
+	store, err := db.NewDocumentStoreWithOptions(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
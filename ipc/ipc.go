@@ -0,0 +1,98 @@
+// Package ipc lets command-line tools work on a database that is held
+// open by the running desktop app.
+//
+// Bolt allows a single writer per database file, so while the app runs it
+// listens on a unix socket next to the database (see SocketPath) and other
+// processes send their reads and writes to the app's DocumentStore instead
+// of waiting for the file lock. Each connection carries one JSON request
+// and one JSON response, except for dumps, whose lines are streamed as a
+// series of chunk responses ended by a final one without a chunk. The
+// socket is only accessible to the current user.
+package ipc
+
+import (
+	"encoding/json"
+	"errors"
+	"glog/capture"
+	"glog/db"
+)
+
+var ErrAlreadyRunning = errors.New("another process is already serving this database")
+
+// SocketPath returns the socket of the database at dbPath.
+func SocketPath(dbPath string) string {
+	return dbPath + ".sock"
+}
+
+// Request is a call of a DocumentStore operation.
+type Request struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Response carries the result of a Request, or an error. Code is set for
+// errors the client maps back to the db package errors. Streamed results
+// come in several responses with a Chunk each; the last one has no Chunk
+// and carries the error, if any.
+type Response struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Chunk  string          `json:"chunk,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Code   string          `json:"code,omitempty"`
+}
+
+// chunkSize bounds the data a streamed response holds in memory.
+const chunkSize = 64 * 1024
+
+const (
+	codeNotFound       = "not_found"
+	codeDuplicateTitle = "duplicate_title"
+	codeReadOnly       = "read_only"
+	codeNothing        = "nothing_to_capture"
+	codeReindex        = "reindex_required"
+)
+
+var errorCodes = map[string]error{
+	codeNotFound:       db.ErrDocumentNotFound,
+	codeDuplicateTitle: db.ErrDuplicateTitle,
+	codeReadOnly:       db.ErrReadOnly,
+	codeNothing:        capture.ErrNothingToCapture,
+	codeReindex:        db.ErrReindexRequired,
+}
+
+type idParams struct {
+	ID string `json:"id"`
+}
+
+type titleParams struct {
+	Title string `json:"title"`
+}
+
+type queryParams struct {
+	Query string `json:"query"`
+}
+
+type journalsParams struct {
+	From string `json:"from"` // RFC 3339 format
+	To   string `json:"to"`   // RFC 3339 format
+}
+
+type tasksParams struct {
+	Date string `json:"date"` // RFC 3339 format
+	Days int    `json:"days"`
+}
+
+type captureParams struct {
+	Now       string `json:"now"` // RFC 3339 format
+	Text      string `json:"text"`
+	Indent    int    `json:"indent"`
+	Under     string `json:"under,omitempty"`
+	Scheduled string `json:"scheduled,omitempty"` // YYYY-MM-DD
+}
+
+type scheduledTask struct {
+	ID      string `json:"id"`
+	DocID   string `json:"doc_id"`
+	BlockID string `json:"block_id"`
+	Time    string `json:"time"` // RFC 3339 format
+}
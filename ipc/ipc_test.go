@@ -0,0 +1,164 @@
+package ipc
+
+import (
+	"bytes"
+	"errors"
+	"glog/capture"
+	"glog/db"
+	"glog/domain"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestClientServer(t *testing.T) {
+	store, err := db.NewDocumentStore("./testipc.db")
+	if err != nil {
+		t.Fatalf("Failed to create DocumentStore: %v", err)
+	}
+	defer func() {
+		err := store.Close()
+		if err != nil {
+			t.Errorf("Failed to close DocumentStore: %v", err)
+		}
+
+		_ = os.Remove("./testipc.db")
+		_ = os.RemoveAll("./testipc.db.bleve")
+	}()
+
+	server, err := Listen(store, SocketPath("./testipc.db"))
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer server.Close()
+
+	if _, err := Listen(store, SocketPath("./testipc.db")); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("Expected ErrAlreadyRunning for a second server, got %v", err)
+	}
+
+	client, err := Dial(SocketPath("./testipc.db"))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+
+	page := &domain.Document{
+		ID:    domain.DocumentID(uuid.New()),
+		Title: "Project",
+		Date:  time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		Blocks: []*domain.Block{
+			{ID: domain.BlockID(uuid.New()), Content: "Kickoff meeting"},
+		},
+	}
+	if err := client.Save(page); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Writes through the client are visible to the app's store
+	if doc, err := store.GetDocumentByTitle("project"); err != nil || doc.ID != page.ID {
+		t.Errorf("Expected the saved page in the store, got %v (err %v)", doc, err)
+	}
+
+	duplicate := &domain.Document{ID: domain.DocumentID(uuid.New()), Title: "Project", Date: time.Now()}
+	if err := client.Save(duplicate); !errors.Is(err, db.ErrDuplicateTitle) {
+		t.Errorf("Expected ErrDuplicateTitle, got %v", err)
+	}
+	if _, err := client.GetDocumentByTitle("Missing"); !errors.Is(err, db.ErrDocumentNotFound) {
+		t.Errorf("Expected ErrDocumentNotFound, got %v", err)
+	}
+
+	now := time.Now()
+	journal, err := client.Capture(now, "- Call [[Project]] team", capture.Options{Scheduled: now.AddDate(0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	if !journal.IsJournal || len(journal.Blocks) != 1 || !strings.HasPrefix(journal.Blocks[0].Content, "Call [[Project]] team /scheduled") {
+		t.Errorf("Unexpected captured journal: %+v", journal)
+	}
+	if _, err := client.Capture(now, "  \n", capture.Options{}); !errors.Is(err, capture.ErrNothingToCapture) {
+		t.Errorf("Expected ErrNothingToCapture, got %v", err)
+	}
+
+	refs, err := client.GetReferences("Project")
+	if err != nil || len(refs) != 1 || refs[0] != journal.ID {
+		t.Errorf("Expected the journal to reference Project, got %v (err %v)", refs, err)
+	}
+	ids, err := client.Search("kickoff")
+	if err != nil || len(ids) != 1 || ids[0] != page.ID {
+		t.Errorf("Expected one search hit, got %v (err %v)", ids, err)
+	}
+	tasks, err := client.GetScheduledTasks(now, 2)
+	if err != nil || len(tasks) != 1 || tasks[0].DocID != journal.ID {
+		t.Errorf("Expected the captured task, got %v (err %v)", tasks, err)
+	}
+
+	count := 0
+	if err := client.ForEachDocument(func(doc *domain.Document) error { count++; return nil }); err != nil || count != 2 {
+		t.Errorf("Expected 2 documents, got %d (err %v)", count, err)
+	}
+
+	// A dump larger than a chunk arrives whole and as the store writes it
+	large := &domain.Document{
+		ID:     domain.DocumentID(uuid.New()),
+		Title:  "Large",
+		Date:   time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC),
+		Blocks: []*domain.Block{{ID: domain.BlockID(uuid.New()), Content: strings.Repeat("notes ", 2*chunkSize/6)}},
+	}
+	if err := client.Save(large); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	var dump, direct bytes.Buffer
+	if err := client.Dump(&dump); err != nil || !strings.Contains(dump.String(), "Kickoff meeting") {
+		t.Errorf("Expected a dump with the page, got err %v", err)
+	}
+	if err := store.Dump(&direct); err != nil || dump.String() != direct.String() {
+		t.Errorf("Expected the streamed dump to match the store's (%d vs %d bytes, err %v)", dump.Len(), direct.Len(), err)
+	}
+	if err := client.Delete(uuid.UUID(large.ID)); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if health := client.GetIndexHealth(); !health.IsHealthy {
+		t.Errorf("Expected a healthy index, got %+v", health)
+	}
+
+	if result, err := client.VerifySearchIndex(); err != nil || !result.Consistent() || result.Documents != 2 {
+		t.Errorf("Expected a consistent index of 2 documents, got %+v (err %v)", result, err)
+	}
+
+	if err := client.SetSearchAnalysis(db.SearchAnalysis{Languages: []string{"en"}}); err != nil {
+		t.Fatalf("SetSearchAnalysis failed: %v", err)
+	}
+	if analysis, err := client.GetSearchAnalysis(); err != nil || len(analysis.Languages) != 1 {
+		t.Errorf("Expected the stored analysis, got %+v (err %v)", analysis, err)
+	}
+}
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+	path := "./teststale.sock"
+	defer os.Remove(path)
+
+	// A socket file nobody listens on, as left by a crash
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to create socket: %v", err)
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = listener.Close()
+
+	server, err := Listen(nil, path)
+	if err != nil {
+		t.Fatalf("Expected the stale socket to be replaced, got %v", err)
+	}
+	if _, err := Dial(path); err != nil {
+		t.Errorf("Dial failed: %v", err)
+	}
+	_ = server.Close()
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected Close to remove the socket")
+	}
+}
@@ -0,0 +1,325 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"glog/capture"
+	"glog/db"
+	"glog/domain"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+)
+
+// requestTimeout bounds how long a client may take to send its request.
+const requestTimeout = 10 * time.Second
+
+// Server serves a DocumentStore on a unix socket.
+type Server struct {
+	store    *db.DocumentStore
+	path     string
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+// Listen starts serving store on the socket at path. A socket left behind
+// by a crashed process is replaced; a live one gives ErrAlreadyRunning.
+func Listen(store *db.DocumentStore, path string) (*Server, error) {
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			_ = conn.Close()
+			return nil, ErrAlreadyRunning
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+
+	s := &Server{store: store, path: path, listener: listener}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// Close stops accepting connections, waits for in-flight requests and
+// removes the socket.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	_ = os.Remove(s.path)
+	return err
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Errorf("ipc: accept failed: %v", err)
+			}
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serve(conn)
+		}()
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(requestTimeout))
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		log.Warnf("ipc: invalid request: %v", err)
+		return
+	}
+
+	enc := json.NewEncoder(conn)
+	var resp Response
+	var err error
+	if req.Method == "dump" {
+		// Dumps can be larger than memory, so they are written to the
+		// connection as they are produced instead of as one result
+		w := bufio.NewWriterSize(chunkWriter{enc}, chunkSize)
+		if err = s.store.Dump(w); err == nil {
+			err = w.Flush()
+		}
+	} else {
+		var result interface{}
+		result, err = s.handle(req)
+		if err == nil {
+			resp.Result, err = json.Marshal(result)
+		}
+	}
+	if err != nil {
+		resp.Error = err.Error()
+		for code, target := range errorCodes {
+			if errors.Is(err, target) {
+				resp.Code = code
+				break
+			}
+		}
+	}
+
+	if err := enc.Encode(resp); err != nil {
+		log.Warnf("ipc: failed to write response to %s: %v", req.Method, err)
+	}
+}
+
+// chunkWriter sends everything written to it as chunk responses.
+type chunkWriter struct {
+	enc *json.Encoder
+}
+
+func (w chunkWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := w.enc.Encode(Response{Chunk: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *Server) handle(req Request) (interface{}, error) {
+	switch req.Method {
+	case "ping":
+		return "pong", nil
+
+	case "search":
+		var p queryParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		ids, err := s.store.Search(p.Query)
+		if err != nil {
+			return nil, err
+		}
+		return idStrings(ids), nil
+
+	case "get_document":
+		var p idParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		id, err := uuid.Parse(p.ID)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := s.store.GetDocument(domain.DocumentID(id))
+		if err != nil {
+			return nil, err
+		}
+		return db.NewDumpDocument(doc), nil
+
+	case "get_document_by_title":
+		var p titleParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		doc, err := s.store.GetDocumentByTitle(p.Title)
+		if err != nil {
+			return nil, err
+		}
+		return db.NewDumpDocument(doc), nil
+
+	case "documents":
+		docs := []*db.DumpDocument{}
+		err := s.store.ForEachDocument(func(doc *domain.Document) error {
+			docs = append(docs, db.NewDumpDocument(doc))
+			return nil
+		})
+		return docs, err
+
+	case "load_journals":
+		var p journalsParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		from, err := time.Parse(time.RFC3339, p.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := time.Parse(time.RFC3339, p.To)
+		if err != nil {
+			return nil, err
+		}
+		docs, err := s.store.LoadJournals(from, to)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]*db.DumpDocument, len(docs))
+		for i, doc := range docs {
+			result[i] = db.NewDumpDocument(doc)
+		}
+		return result, nil
+
+	case "scheduled_tasks":
+		var p tasksParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		date, err := time.Parse(time.RFC3339, p.Date)
+		if err != nil {
+			return nil, err
+		}
+		tasks, err := s.store.GetScheduledTasks(date, p.Days)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]scheduledTask, len(tasks))
+		for i, task := range tasks {
+			result[i] = scheduledTask{
+				ID:      task.ID.String(),
+				DocID:   task.DocID.String(),
+				BlockID: task.BlockID.String(),
+				Time:    task.Time.Format(time.RFC3339),
+			}
+		}
+		return result, nil
+
+	case "references":
+		var p titleParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		ids, err := s.store.GetReferences(p.Title)
+		if err != nil {
+			return nil, err
+		}
+		return idStrings(ids), nil
+
+	case "index_health":
+		return s.store.GetIndexHealth(), nil
+
+	case "reindex":
+		return nil, s.store.ReindexSearch()
+
+	case "verify_index":
+		return s.store.VerifySearchIndex()
+
+	case "search_analysis":
+		return s.store.GetSearchAnalysis()
+
+	case "set_search_analysis":
+		var analysis db.SearchAnalysis
+		if err := json.Unmarshal(req.Params, &analysis); err != nil {
+			return nil, err
+		}
+		return nil, s.store.SetSearchAnalysis(analysis)
+
+	case "save":
+		var d db.DumpDocument
+		if err := json.Unmarshal(req.Params, &d); err != nil {
+			return nil, err
+		}
+		doc, err := d.ToDomain()
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.store.Save(doc)
+
+	case "delete":
+		var p idParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		id, err := uuid.Parse(p.ID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.store.Delete(id)
+
+	case "capture":
+		var p captureParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		now, err := time.Parse(time.RFC3339, p.Now)
+		if err != nil {
+			return nil, err
+		}
+		opts := capture.Options{Indent: p.Indent, Under: p.Under}
+		if p.Scheduled != "" {
+			if opts.Scheduled, err = time.Parse("2006-01-02", p.Scheduled); err != nil {
+				return nil, err
+			}
+		}
+		doc, err := capture.Journal(s.store, now, p.Text, opts)
+		if err != nil {
+			return nil, err
+		}
+		return db.NewDumpDocument(doc), nil
+
+	}
+
+	return nil, fmt.Errorf("unknown method %q", req.Method)
+}
+
+func idStrings(ids []domain.DocumentID) []string {
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = id.String()
+	}
+	return result
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"embed"
 	"glog/db"
+	"glog/hooks"
+	"glog/ipc"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -56,8 +58,37 @@ func main() {
 
 	defer dbStore.Close()
 
+	// Let the glog CLI and importers work while the app holds the database
+	ipcServer, err := ipc.Listen(dbStore, ipc.SocketPath("glog.db"))
+	if err != nil {
+		log.Warnf("Command-line access disabled: %v", err)
+	} else {
+		defer ipcServer.Close()
+	}
+
 	app := NewApp(dbStore)
 
+	// Run the hooks configured in glog.db.hooks.json after saves and deletes
+	hookList, err := hooks.LoadConfig(hooks.ConfigPath("glog.db"))
+	if err != nil {
+		log.Warnf("Hooks disabled: %v", err)
+	} else if len(hookList) > 0 {
+		app.hooks = hooks.Start(dbStore, hookList)
+	}
+
+	// GLOG_API_ADDR and GLOG_API_TOKEN serve the HTTP API from startup, see 'glog serve'
+	if addr := os.Getenv("GLOG_API_ADDR"); addr != "" {
+		token := os.Getenv("GLOG_API_TOKEN")
+		if token == "" {
+			log.Warn("HTTP API disabled: GLOG_API_TOKEN is not set")
+		} else if server, err := app.StartAPIServer(addr, token); err != nil {
+			log.Warnf("HTTP API disabled: %v", err)
+		} else {
+			log.Infof("HTTP API listening on %s", server.Url)
+			defer app.StopAPIServer()
+		}
+	}
+
 	// Create application with options
 	err = wails.Run(&options.App{
 		Title:     "glog",
@@ -70,6 +101,7 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup:        app.startup,
+		OnShutdown:       app.shutdown,
 		Bind: []interface{}{
 			app,
 		},